@@ -0,0 +1,258 @@
+package agent
+
+import (
+	"bufio"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/portainer/portainer"
+	"golang.org/x/net/http2"
+)
+
+const (
+	// TunnelPath is the path of the Portainer API used by the agents to open their tunnel.
+	TunnelPath = "/api/agents/tunnel"
+	// TunnelProtocol is the protocol requested by the agents in the Upgrade header.
+	TunnelProtocol = "portainer-agent"
+	// KeyHeader is the header containing the key of an agent.
+	KeyHeader = "X-PortainerAgent-Key"
+	// VersionHeader is the header containing the version of an agent.
+	VersionHeader = "X-PortainerAgent-Version"
+
+	dialTimeout = 10 * time.Second
+	// tunnelReadTimeout is the duration without any data received from Portainer after which the tunnel
+	// is considered lost. Portainer pings the agents when a tunnel is idle.
+	tunnelReadTimeout = 90 * time.Second
+	minReconnectDelay = time.Second
+	maxReconnectDelay = 30 * time.Second
+)
+
+const (
+	// ErrInvalidAgentKey defines an error raised when Portainer rejects the key of the agent
+	ErrInvalidAgentKey = portainer.Error("Invalid agent key")
+	// ErrInvalidServerURL defines an error raised when the URL of the Portainer instance is not valid
+	ErrInvalidServerURL = portainer.Error("Invalid Portainer URL")
+	// ErrInvalidDockerHost defines an error raised when the Docker endpoint is not a unix:// or tcp:// endpoint
+	ErrInvalidDockerHost = portainer.Error("Invalid Docker endpoint: the agent only supports unix:// or tcp://")
+)
+
+// Agent opens a reverse tunnel to a Portainer instance and forwards the streams opened by Portainer
+// to the local Docker API. The tunnel is opened again when it is lost.
+type Agent struct {
+	serverURL     *url.URL
+	key           string
+	dockerNetwork string
+	dockerAddress string
+	tlsConfig     *tls.Config
+	logger        *log.Logger
+}
+
+type (
+	// bufferedConn is a connection whose reads go through the buffered reader used to parse the
+	// upgrade response, as it may already contain the beginning of the HTTP/2 connection.
+	bufferedConn struct {
+		net.Conn
+		reader *bufio.Reader
+	}
+
+	// idleConn is a connection closed when no data is received during the read timeout.
+	idleConn struct {
+		net.Conn
+		timeout time.Duration
+	}
+
+	flushWriter struct {
+		w http.ResponseWriter
+		f http.Flusher
+	}
+)
+
+// NewAgent initializes a new agent connecting to a Portainer instance with an agent key
+// and forwarding the streams to a Docker endpoint.
+func NewAgent(serverURL, key, dockerHost string, tlsSkipVerify bool) (*Agent, error) {
+	u, err := url.Parse(serverURL)
+	if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+		return nil, ErrInvalidServerURL
+	}
+
+	agent := &Agent{
+		serverURL: u,
+		key:       key,
+		logger:    log.New(os.Stderr, "", log.LstdFlags),
+	}
+
+	if strings.HasPrefix(dockerHost, "unix://") {
+		agent.dockerNetwork = "unix"
+		agent.dockerAddress = strings.TrimPrefix(dockerHost, "unix://")
+	} else if strings.HasPrefix(dockerHost, "tcp://") {
+		agent.dockerNetwork = "tcp"
+		agent.dockerAddress = strings.TrimPrefix(dockerHost, "tcp://")
+	} else {
+		return nil, ErrInvalidDockerHost
+	}
+
+	if u.Scheme == "https" {
+		agent.tlsConfig = &tls.Config{
+			ServerName:         u.Hostname(),
+			InsecureSkipVerify: tlsSkipVerify,
+		}
+	}
+	return agent, nil
+}
+
+// Run opens the tunnel and serves the streams until the tunnel is lost, then opens it again.
+// The delay between two attempts grows exponentially and is reset once a tunnel is opened.
+// Run only returns when the key of the agent is rejected.
+func (agent *Agent) Run() error {
+	delay := minReconnectDelay
+	for {
+		conn, err := agent.connect()
+		if err == ErrInvalidAgentKey {
+			return err
+		} else if err != nil {
+			agent.logger.Printf("Unable to open the tunnel to %s: %s (retrying in %s)", agent.serverURL.Host, err, delay)
+			time.Sleep(delay)
+			delay *= 2
+			if delay > maxReconnectDelay {
+				delay = maxReconnectDelay
+			}
+			continue
+		}
+
+		delay = minReconnectDelay
+		agent.logger.Printf("Tunnel opened to %s", agent.serverURL.Host)
+
+		server := &http2.Server{}
+		server.ServeConn(conn, &http2.ServeConnOpts{Handler: agent})
+		conn.Close()
+
+		agent.logger.Printf("Tunnel to %s lost, reconnecting", agent.serverURL.Host)
+	}
+}
+
+// connect opens a connection to Portainer and upgrades it to a tunnel.
+func (agent *Agent) connect() (net.Conn, error) {
+	dialer := &net.Dialer{
+		Timeout:   dialTimeout,
+		KeepAlive: 30 * time.Second,
+	}
+
+	var conn net.Conn
+	var err error
+	if agent.tlsConfig != nil {
+		conn, err = tls.DialWithDialer(dialer, "tcp", hostWithPort(agent.serverURL), agent.tlsConfig)
+	} else {
+		conn, err = dialer.Dial("tcp", hostWithPort(agent.serverURL))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest(http.MethodGet, strings.TrimRight(agent.serverURL.String(), "/")+TunnelPath, nil)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", TunnelProtocol)
+	req.Header.Set(KeyHeader, agent.key)
+	req.Header.Set(VersionHeader, portainer.APIVersion)
+
+	conn.SetDeadline(time.Now().Add(dialTimeout))
+	err = req.Write(conn)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, req)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	resp.Body.Close()
+	conn.SetDeadline(time.Time{})
+
+	if resp.StatusCode == http.StatusUnauthorized {
+		conn.Close()
+		return nil, ErrInvalidAgentKey
+	} else if resp.StatusCode != http.StatusSwitchingProtocols {
+		conn.Close()
+		return nil, fmt.Errorf("Unexpected response status: %d", resp.StatusCode)
+	}
+
+	return &idleConn{
+		Conn:    &bufferedConn{Conn: conn, reader: reader},
+		timeout: tunnelReadTimeout,
+	}, nil
+}
+
+// ServeHTTP forwards a stream opened by Portainer to the Docker API. Each stream is a CONNECT request,
+// the request body is sent to Docker and the output of Docker is sent in the response body.
+func (agent *Agent) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodConnect {
+		http.Error(w, "Only CONNECT requests are supported", http.StatusMethodNotAllowed)
+		return
+	}
+
+	dockerConn, err := net.DialTimeout(agent.dockerNetwork, agent.dockerAddress, dialTimeout)
+	if err != nil {
+		agent.logger.Printf("Unable to connect to the Docker API: %s", err)
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	defer dockerConn.Close()
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	go func() {
+		io.Copy(dockerConn, r.Body)
+		if c, ok := dockerConn.(interface {
+			CloseWrite() error
+		}); ok {
+			c.CloseWrite()
+		}
+	}()
+
+	io.Copy(&flushWriter{w: w, f: flusher}, dockerConn)
+}
+
+func hostWithPort(u *url.URL) string {
+	if u.Port() != "" {
+		return u.Host
+	}
+	if u.Scheme == "https" {
+		return net.JoinHostPort(u.Hostname(), "443")
+	}
+	return net.JoinHostPort(u.Hostname(), "80")
+}
+
+func (conn *bufferedConn) Read(p []byte) (int, error) {
+	return conn.reader.Read(p)
+}
+
+func (conn *idleConn) Read(p []byte) (int, error) {
+	conn.Conn.SetReadDeadline(time.Now().Add(conn.timeout))
+	return conn.Conn.Read(p)
+}
+
+func (fw *flushWriter) Write(p []byte) (int, error) {
+	n, err := fw.w.Write(p)
+	fw.f.Flush()
+	return n, err
+}
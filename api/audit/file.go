@@ -0,0 +1,169 @@
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/portainer/portainer"
+)
+
+const (
+	// defaultFileMaxSize is the size in megabytes after which a file is rotated when no size is specified.
+	defaultFileMaxSize = 10
+	// defaultFileMaxBackups is the number of rotated files kept when no number is specified.
+	defaultFileMaxBackups = 5
+)
+
+// fileSink writes the audit logs to a local file in the JSON lines format. When the file exceeds
+// the maximum size, it is renamed with the .1 suffix and the previous rotated files are shifted.
+// The file and the rotated files are never symbolic links so that the sink cannot be used to
+// overwrite or remove files outside of its directory.
+type fileSink struct {
+	path       string
+	maxSize    int64
+	maxBackups int
+	file       *os.File
+	size       int64
+}
+
+// newFileSink returns a sink writing to a file of directory, the path of the sink must be a file name.
+func newFileSink(settings *portainer.AuditSink, directory string) (*fileSink, error) {
+	if !isValidFileName(settings.Path) || directory == "" || settings.MaxSize < 0 || settings.MaxBackups < 0 {
+		return nil, portainer.ErrInvalidAuditSink
+	}
+
+	s := &fileSink{
+		path:       filepath.Join(directory, settings.Path),
+		maxSize:    int64(settings.MaxSize) * 1024 * 1024,
+		maxBackups: settings.MaxBackups,
+	}
+	if s.maxSize == 0 {
+		s.maxSize = defaultFileMaxSize * 1024 * 1024
+	}
+	if s.maxBackups == 0 {
+		s.maxBackups = defaultFileMaxBackups
+	}
+	return s, nil
+}
+
+func (s *fileSink) write(auditLogs []portainer.AuditLog) error {
+	for _, auditLog := range auditLogs {
+		line, err := json.Marshal(auditLog)
+		if err != nil {
+			return err
+		}
+		line = append(line, '\n')
+
+		if s.file != nil && s.size > 0 && s.size+int64(len(line)) > s.maxSize {
+			err = s.rotate()
+			if err != nil {
+				return err
+			}
+		}
+
+		if s.file == nil {
+			err = s.open()
+			if err != nil {
+				return err
+			}
+		}
+
+		n, err := s.file.Write(line)
+		s.size += int64(n)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *fileSink) close() error {
+	if s.file == nil {
+		return nil
+	}
+	err := s.file.Close()
+	s.file = nil
+	return err
+}
+
+func (s *fileSink) open() error {
+	err := checkNotSymlink(s.path)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(s.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	s.file = file
+	s.size = info.Size()
+	return nil
+}
+
+// rotate closes the current file and shifts the rotated files, the oldest one is removed.
+func (s *fileSink) rotate() error {
+	err := s.close()
+	if err != nil {
+		return err
+	}
+
+	err = checkNotSymlink(s.path)
+	if err != nil {
+		return err
+	}
+	for idx := 1; idx <= s.maxBackups; idx++ {
+		err = checkNotSymlink(backupFilePath(s.path, idx))
+		if err != nil {
+			return err
+		}
+	}
+
+	err = os.Remove(backupFilePath(s.path, s.maxBackups))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	for idx := s.maxBackups - 1; idx >= 1; idx-- {
+		err = os.Rename(backupFilePath(s.path, idx), backupFilePath(s.path, idx+1))
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	return os.Rename(s.path, backupFilePath(s.path, 1))
+}
+
+func backupFilePath(path string, idx int) string {
+	return fmt.Sprintf("%s.%d", path, idx)
+}
+
+// isValidFileName returns true when name is the name of a file, without any directory.
+func isValidFileName(name string) bool {
+	return name != "" && name != "." && name != ".." && filepath.Base(name) == name && !strings.ContainsAny(name, `/\`)
+}
+
+// checkNotSymlink returns an error when path is a symbolic link, a missing file is not an error.
+func checkNotSymlink(path string) error {
+	info, err := os.Lstat(path)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	if info.Mode()&os.ModeSymlink != 0 {
+		return portainer.ErrAuditFileIsSymlink
+	}
+	return nil
+}
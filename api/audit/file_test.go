@@ -0,0 +1,72 @@
+package audit
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/portainer/portainer"
+)
+
+func TestFileSinkPath(t *testing.T) {
+	for _, path := range []string{"", ".", "..", "/var/log/audit.log", "../audit.log", "logs/audit.log"} {
+		_, err := newFileSink(&portainer.AuditSink{Type: portainer.FileAuditSink, Path: path}, "/data/audit")
+		if err != portainer.ErrInvalidAuditSink {
+			t.Errorf("Unexpected error for path %q: %v", path, err)
+		}
+	}
+
+	s, err := newFileSink(&portainer.AuditSink{Type: portainer.FileAuditSink, Path: "audit.log"}, "/data/audit")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if s.path != "/data/audit/audit.log" {
+		t.Errorf("Unexpected path: %s", s.path)
+	}
+}
+
+func TestFileSinkRejectsSymlinks(t *testing.T) {
+	directory, err := ioutil.TempDir("", "portainer-audit")
+	if err != nil {
+		t.Fatalf("Unable to create the directory: %s", err)
+	}
+	defer os.RemoveAll(directory)
+
+	target := filepath.Join(directory, "target")
+	err = ioutil.WriteFile(target, []byte("content"), 0600)
+	if err != nil {
+		t.Fatalf("Unable to create the target file: %s", err)
+	}
+
+	auditLogs := []portainer.AuditLog{{Username: "admin"}}
+
+	err = os.Symlink(target, filepath.Join(directory, "audit.log"))
+	if err != nil {
+		t.Fatalf("Unable to create the symbolic link: %s", err)
+	}
+	s, _ := newFileSink(&portainer.AuditSink{Type: portainer.FileAuditSink, Path: "audit.log"}, directory)
+	if err := s.write(auditLogs); err != portainer.ErrAuditFileIsSymlink {
+		t.Errorf("Unexpected error for a symbolic link: %v", err)
+	}
+
+	// A symbolic link among the rotated files prevents the rotation.
+	err = os.Symlink(target, filepath.Join(directory, "other.log.2"))
+	if err != nil {
+		t.Fatalf("Unable to create the symbolic link: %s", err)
+	}
+	s, _ = newFileSink(&portainer.AuditSink{Type: portainer.FileAuditSink, Path: "other.log", MaxBackups: 2}, directory)
+	s.maxSize = 1
+	if err := s.write(auditLogs); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if err := s.write(auditLogs); err != portainer.ErrAuditFileIsSymlink {
+		t.Errorf("Unexpected error for a rotated symbolic link: %v", err)
+	}
+	s.close()
+
+	content, err := ioutil.ReadFile(target)
+	if err != nil || string(content) != "content" {
+		t.Errorf("The target file has been modified: %q %v", content, err)
+	}
+}
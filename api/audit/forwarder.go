@@ -0,0 +1,182 @@
+package audit
+
+import (
+	"log"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/portainer/portainer"
+)
+
+const (
+	// sinkQueueSize is the number of audit logs buffered for each sink, audit logs are dropped when the buffer is full.
+	sinkQueueSize = 1000
+	// maxBatchSize is the maximum number of audit logs sent to a sink at once.
+	maxBatchSize = 100
+	// maxSendAttempts is the number of attempts made to send a batch before it is dropped.
+	maxSendAttempts = 5
+	// initialRetryDelay is the delay before the first retry, it is doubled after each attempt.
+	initialRetryDelay = time.Second
+)
+
+type (
+	// Forwarder forwards the audit logs to the sinks defined in the settings.
+	// It implements the portainer.AuditForwarder interface.
+	Forwarder struct {
+		logger        *log.Logger
+		fileDirectory string
+		mu            sync.RWMutex
+		workers       []*sinkWorker
+	}
+
+	// sink represents a destination of the audit logs.
+	sink interface {
+		write(auditLogs []portainer.AuditLog) error
+		close() error
+	}
+
+	// sinkWorker sends the audit logs queued for a sink in the background so that
+	// a slow or unavailable sink never delays the requests being audited.
+	sinkWorker struct {
+		sink    sink
+		name    string
+		logger  *log.Logger
+		queue   chan portainer.AuditLog
+		done    chan struct{}
+		dropped int64
+	}
+)
+
+// NewForwarder initializes a new Forwarder without any sink. File sinks can only write to fileDirectory.
+func NewForwarder(fileDirectory string) *Forwarder {
+	return &Forwarder{
+		logger:        log.New(os.Stderr, "", log.LstdFlags),
+		fileDirectory: fileDirectory,
+		workers:       make([]*sinkWorker, 0),
+	}
+}
+
+// Configure replaces the current sinks. The current sinks are kept when a sink configuration is invalid.
+// Audit logs still queued for the previous sinks are discarded.
+func (forwarder *Forwarder) Configure(sinks []portainer.AuditSink) error {
+	workers := make([]*sinkWorker, 0)
+	for _, settings := range sinks {
+		s, err := newSink(&settings, forwarder.fileDirectory)
+		if err != nil {
+			for _, worker := range workers {
+				worker.sink.close()
+			}
+			return err
+		}
+
+		workers = append(workers, &sinkWorker{
+			sink:   s,
+			name:   sinkName(&settings),
+			logger: forwarder.logger,
+			queue:  make(chan portainer.AuditLog, sinkQueueSize),
+			done:   make(chan struct{}),
+		})
+	}
+
+	forwarder.mu.Lock()
+	previousWorkers := forwarder.workers
+	forwarder.workers = workers
+	forwarder.mu.Unlock()
+
+	for _, worker := range previousWorkers {
+		close(worker.done)
+	}
+	for _, worker := range workers {
+		go worker.run()
+	}
+	return nil
+}
+
+// Forward queues an audit log for each sink. It never blocks, the audit log is dropped
+// for the sinks whose buffer is full.
+func (forwarder *Forwarder) Forward(auditLog *portainer.AuditLog) {
+	forwarder.mu.RLock()
+	defer forwarder.mu.RUnlock()
+
+	for _, worker := range forwarder.workers {
+		select {
+		case worker.queue <- *auditLog:
+		default:
+			atomic.AddInt64(&worker.dropped, 1)
+		}
+	}
+}
+
+// run sends the queued audit logs by batches until the worker is stopped.
+func (worker *sinkWorker) run() {
+	defer worker.sink.close()
+
+	for {
+		select {
+		case <-worker.done:
+			return
+		case auditLog := <-worker.queue:
+			batch := []portainer.AuditLog{auditLog}
+		drain:
+			for len(batch) < maxBatchSize {
+				select {
+				case auditLog := <-worker.queue:
+					batch = append(batch, auditLog)
+				default:
+					break drain
+				}
+			}
+
+			worker.send(batch)
+
+			if dropped := atomic.SwapInt64(&worker.dropped, 0); dropped > 0 {
+				worker.logger.Printf("Audit sink %s is too slow, %d audit logs have been dropped", worker.name, dropped)
+			}
+		}
+	}
+}
+
+// send writes a batch to the sink, retrying with an exponential backoff.
+func (worker *sinkWorker) send(batch []portainer.AuditLog) {
+	delay := initialRetryDelay
+	for attempt := 1; ; attempt++ {
+		err := worker.sink.write(batch)
+		if err == nil {
+			return
+		}
+
+		if attempt == maxSendAttempts {
+			worker.logger.Printf("Unable to forward %d audit logs to sink %s: %s", len(batch), worker.name, err)
+			return
+		}
+
+		select {
+		case <-worker.done:
+			return
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
+
+// newSink creates the sink associated to a sink configuration.
+func newSink(settings *portainer.AuditSink, fileDirectory string) (sink, error) {
+	switch settings.Type {
+	case portainer.SyslogAuditSink:
+		return newSyslogSink(settings)
+	case portainer.WebhookAuditSink:
+		return newWebhookSink(settings)
+	case portainer.FileAuditSink:
+		return newFileSink(settings, fileDirectory)
+	}
+	return nil, portainer.ErrInvalidAuditSink
+}
+
+func sinkName(settings *portainer.AuditSink) string {
+	if settings.Type == portainer.FileAuditSink {
+		return settings.Path
+	}
+	return settings.URL
+}
@@ -0,0 +1,133 @@
+package audit
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/portainer/portainer"
+)
+
+const (
+	// syslogPriority is the priority of the syslog messages: log audit facility (13) and informational severity (6).
+	syslogPriority = 13*8 + 6
+	syslogAppName  = "portainer"
+	syslogMsgID    = "audit"
+	syslogTimeout  = 10 * time.Second
+)
+
+// syslogSink sends the audit logs to a syslog server using the RFC 5424 format. Messages sent over TCP
+// or TLS are framed using the octet counting method described in RFC 6587.
+type syslogSink struct {
+	network   string
+	address   string
+	tlsConfig *tls.Config
+	hostname  string
+	conn      net.Conn
+}
+
+func newSyslogSink(settings *portainer.AuditSink) (*syslogSink, error) {
+	sinkURL, err := url.Parse(settings.URL)
+	if err != nil || sinkURL.Host == "" {
+		return nil, portainer.ErrInvalidAuditSink
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil || hostname == "" {
+		hostname = "-"
+	}
+
+	s := &syslogSink{
+		network:  sinkURL.Scheme,
+		address:  sinkURL.Host,
+		hostname: hostname,
+	}
+
+	switch sinkURL.Scheme {
+	case "udp", "tcp":
+	case "tls":
+		s.network = "tcp"
+		s.tlsConfig = &tls.Config{
+			ServerName:         sinkURL.Hostname(),
+			InsecureSkipVerify: settings.TLSSkipVerify,
+		}
+	default:
+		return nil, portainer.ErrInvalidAuditSink
+	}
+	return s, nil
+}
+
+func (s *syslogSink) write(auditLogs []portainer.AuditLog) error {
+	if s.conn == nil {
+		err := s.connect()
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, auditLog := range auditLogs {
+		message, err := s.formatMessage(&auditLog)
+		if err != nil {
+			return err
+		}
+
+		if s.network != "udp" {
+			message = append([]byte(strconv.Itoa(len(message))+" "), message...)
+		}
+
+		s.conn.SetWriteDeadline(time.Now().Add(syslogTimeout))
+		_, err = s.conn.Write(message)
+		if err != nil {
+			// The connection is created again on the next attempt.
+			s.close()
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *syslogSink) close() error {
+	if s.conn == nil {
+		return nil
+	}
+	err := s.conn.Close()
+	s.conn = nil
+	return err
+}
+
+func (s *syslogSink) connect() error {
+	dialer := &net.Dialer{Timeout: syslogTimeout}
+
+	// The connection is only kept when the dial succeeds: a failed TLS dial returns a nil *tls.Conn
+	// which would not be detected as a missing connection once stored in s.conn.
+	var conn net.Conn
+	var err error
+	if s.tlsConfig != nil {
+		conn, err = tls.DialWithDialer(dialer, s.network, s.address, s.tlsConfig)
+	} else {
+		conn, err = dialer.Dial(s.network, s.address)
+	}
+	if err != nil {
+		return err
+	}
+
+	s.conn = conn
+	return nil
+}
+
+// formatMessage formats an audit log as a RFC 5424 message, the audit log is sent in the JSON format as the message content.
+func (s *syslogSink) formatMessage(auditLog *portainer.AuditLog) ([]byte, error) {
+	content, err := json.Marshal(auditLog)
+	if err != nil {
+		return nil, err
+	}
+
+	timestamp := time.Unix(auditLog.Timestamp, 0).UTC().Format(time.RFC3339)
+	header := fmt.Sprintf("<%d>1 %s %s %s %d %s - ", syslogPriority, timestamp, s.hostname, syslogAppName, os.Getpid(), syslogMsgID)
+	return append([]byte(header), content...), nil
+}
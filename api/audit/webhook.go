@@ -0,0 +1,80 @@
+package audit
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/portainer/portainer"
+)
+
+const (
+	webhookTimeout = 10 * time.Second
+)
+
+// webhookSink posts the audit logs to an HTTP endpoint as newline-delimited JSON.
+type webhookSink struct {
+	url    string
+	token  string
+	client *http.Client
+}
+
+func newWebhookSink(settings *portainer.AuditSink) (*webhookSink, error) {
+	sinkURL, err := url.Parse(settings.URL)
+	if err != nil || sinkURL.Host == "" || (sinkURL.Scheme != "http" && sinkURL.Scheme != "https") {
+		return nil, portainer.ErrInvalidAuditSink
+	}
+
+	transport := &http.Transport{
+		Proxy:           http.ProxyFromEnvironment,
+		TLSClientConfig: &tls.Config{InsecureSkipVerify: settings.TLSSkipVerify},
+	}
+
+	return &webhookSink{
+		url:   settings.URL,
+		token: settings.Token,
+		client: &http.Client{
+			Transport: transport,
+			Timeout:   webhookTimeout,
+		},
+	}, nil
+}
+
+func (s *webhookSink) write(auditLogs []portainer.AuditLog) error {
+	var body bytes.Buffer
+	encoder := json.NewEncoder(&body)
+	for _, auditLog := range auditLogs {
+		err := encoder.Encode(auditLog)
+		if err != nil {
+			return err
+		}
+	}
+
+	req, err := http.NewRequest(http.MethodPost, s.url, &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	if s.token != "" {
+		req.Header.Set("Authorization", "Bearer "+s.token)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("Unexpected response status: %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func (s *webhookSink) close() error {
+	return nil
+}
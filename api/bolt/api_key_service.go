@@ -0,0 +1,171 @@
+package bolt
+
+import (
+	"github.com/portainer/portainer"
+	"github.com/portainer/portainer/bolt/internal"
+
+	"github.com/boltdb/bolt"
+)
+
+// APIKeyService represents a service for managing API keys.
+type APIKeyService struct {
+	store *Store
+}
+
+// APIKey returns an API key by ID.
+func (service *APIKeyService) APIKey(ID portainer.APIKeyID) (*portainer.APIKey, error) {
+	var data []byte
+	err := service.store.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(apiKeyBucketName))
+		value := bucket.Get(internal.Itob(int(ID)))
+		if value == nil {
+			return portainer.ErrAPIKeyNotFound
+		}
+
+		data = make([]byte, len(value))
+		copy(data, value)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var apiKey portainer.APIKey
+	err = internal.UnmarshalAPIKey(data, &apiKey)
+	if err != nil {
+		return nil, err
+	}
+	return &apiKey, nil
+}
+
+// APIKeyByDigest returns the API key matching a digest.
+func (service *APIKeyService) APIKeyByDigest(digest string) (*portainer.APIKey, error) {
+	var apiKey *portainer.APIKey
+
+	err := service.store.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(apiKeyBucketName))
+		cursor := bucket.Cursor()
+		for k, v := cursor.First(); k != nil; k, v = cursor.Next() {
+			var key portainer.APIKey
+			err := internal.UnmarshalAPIKey(v, &key)
+			if err != nil {
+				return err
+			}
+			if key.Digest == digest {
+				apiKey = &key
+				break
+			}
+		}
+
+		if apiKey == nil {
+			return portainer.ErrAPIKeyNotFound
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return apiKey, nil
+}
+
+// APIKeysByUserID return an array containing all the API keys owned by a user.
+func (service *APIKeyService) APIKeysByUserID(userID portainer.UserID) ([]portainer.APIKey, error) {
+	var apiKeys = make([]portainer.APIKey, 0)
+	err := service.store.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(apiKeyBucketName))
+
+		cursor := bucket.Cursor()
+		for k, v := cursor.First(); k != nil; k, v = cursor.Next() {
+			var apiKey portainer.APIKey
+			err := internal.UnmarshalAPIKey(v, &apiKey)
+			if err != nil {
+				return err
+			}
+			if apiKey.UserID == userID {
+				apiKeys = append(apiKeys, apiKey)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return apiKeys, nil
+}
+
+// UpdateAPIKey saves an API key.
+func (service *APIKeyService) UpdateAPIKey(ID portainer.APIKeyID, apiKey *portainer.APIKey) error {
+	data, err := internal.MarshalAPIKey(apiKey)
+	if err != nil {
+		return err
+	}
+
+	return service.store.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(apiKeyBucketName))
+		err = bucket.Put(internal.Itob(int(ID)), data)
+
+		if err != nil {
+			return err
+		}
+		return nil
+	})
+}
+
+// CreateAPIKey creates a new API key.
+func (service *APIKeyService) CreateAPIKey(apiKey *portainer.APIKey) error {
+	return service.store.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(apiKeyBucketName))
+
+		id, _ := bucket.NextSequence()
+		apiKey.ID = portainer.APIKeyID(id)
+
+		data, err := internal.MarshalAPIKey(apiKey)
+		if err != nil {
+			return err
+		}
+
+		err = bucket.Put(internal.Itob(int(apiKey.ID)), data)
+		if err != nil {
+			return err
+		}
+		return nil
+	})
+}
+
+// DeleteAPIKey deletes an API key.
+func (service *APIKeyService) DeleteAPIKey(ID portainer.APIKeyID) error {
+	return service.store.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(apiKeyBucketName))
+		err := bucket.Delete(internal.Itob(int(ID)))
+		if err != nil {
+			return err
+		}
+		return nil
+	})
+}
+
+// DeleteAPIKeysByUserID deletes all the API keys owned by a user.
+func (service *APIKeyService) DeleteAPIKeysByUserID(userID portainer.UserID) error {
+	return service.store.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(apiKeyBucketName))
+
+		cursor := bucket.Cursor()
+		for k, v := cursor.First(); k != nil; k, v = cursor.Next() {
+			var apiKey portainer.APIKey
+			err := internal.UnmarshalAPIKey(v, &apiKey)
+			if err != nil {
+				return err
+			}
+			if apiKey.UserID == userID {
+				err := bucket.Delete(internal.Itob(int(apiKey.ID)))
+				if err != nil {
+					return err
+				}
+			}
+		}
+
+		return nil
+	})
+}
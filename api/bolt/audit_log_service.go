@@ -0,0 +1,83 @@
+package bolt
+
+import (
+	"github.com/portainer/portainer"
+	"github.com/portainer/portainer/bolt/internal"
+
+	"github.com/boltdb/bolt"
+)
+
+// AuditLogService represents a service for managing audit logs.
+type AuditLogService struct {
+	store *Store
+}
+
+// AuditLogs returns the audit logs matching a query, latest first, along with the total number of matching audit logs.
+func (service *AuditLogService) AuditLogs(query *portainer.AuditLogQuery) ([]portainer.AuditLog, int, error) {
+	var auditLogs = make([]portainer.AuditLog, 0)
+	count := 0
+
+	err := service.store.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(auditLogBucketName))
+
+		// Audit logs are created in chronological order, the search can stop
+		// as soon as an audit log older than the query is found.
+		cursor := bucket.Cursor()
+		for k, v := cursor.Last(); k != nil; k, v = cursor.Prev() {
+			var auditLog portainer.AuditLog
+			err := internal.UnmarshalAuditLog(v, &auditLog)
+			if err != nil {
+				return err
+			}
+
+			if query.Since != 0 && auditLog.Timestamp < query.Since {
+				break
+			}
+			if !matchAuditLogQuery(&auditLog, query) {
+				continue
+			}
+
+			if count >= query.Start && (query.Limit == 0 || len(auditLogs) < query.Limit) {
+				auditLogs = append(auditLogs, auditLog)
+			}
+			count++
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return auditLogs, count, nil
+}
+
+// CreateAuditLog creates a new audit log.
+func (service *AuditLogService) CreateAuditLog(auditLog *portainer.AuditLog) error {
+	return service.store.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(auditLogBucketName))
+
+		id, _ := bucket.NextSequence()
+		auditLog.ID = portainer.AuditLogID(id)
+
+		data, err := internal.MarshalAuditLog(auditLog)
+		if err != nil {
+			return err
+		}
+
+		err = bucket.Put(internal.Itob(int(auditLog.ID)), data)
+		if err != nil {
+			return err
+		}
+		return nil
+	})
+}
+
+func matchAuditLogQuery(auditLog *portainer.AuditLog, query *portainer.AuditLogQuery) bool {
+	return (query.UserID == 0 || auditLog.UserID == query.UserID) &&
+		(query.EndpointID == 0 || auditLog.EndpointID == query.EndpointID) &&
+		(query.Method == "" || auditLog.Method == query.Method) &&
+		(query.ResourceID == "" || auditLog.ResourceID == query.ResourceID) &&
+		(query.Status == 0 || auditLog.Status == query.Status) &&
+		(query.Until == 0 || auditLog.Timestamp <= query.Until)
+}
@@ -17,55 +17,81 @@ type Store struct {
 
 	// Services
 	UserService            *UserService
+	APIKeyService          *APIKeyService
 	TeamService            *TeamService
 	TeamMembershipService  *TeamMembershipService
 	EndpointService        *EndpointService
+	EndpointGroupService   *EndpointGroupService
 	ResourceControlService *ResourceControlService
 	VersionService         *VersionService
 	SettingsService        *SettingsService
 	RegistryService        *RegistryService
 	DockerHubService       *DockerHubService
+	JWTSecretService       *JWTSecretService
+	TokenRevocationService *TokenRevocationService
+	AuditLogService        *AuditLogService
 
 	db                    *bolt.DB
 	checkForDataMigration bool
+	fileService           portainer.FileService
+	encryptionService     portainer.EncryptionService
 }
 
 const (
 	databaseFileName          = "portainer.db"
 	versionBucketName         = "version"
 	userBucketName            = "users"
+	apiKeyBucketName          = "api_keys"
 	teamBucketName            = "teams"
 	teamMembershipBucketName  = "team_membership"
 	endpointBucketName        = "endpoints"
+	endpointGroupBucketName   = "endpoint_groups"
 	resourceControlBucketName = "resource_control"
 	settingsBucketName        = "settings"
 	registryBucketName        = "registries"
 	dockerhubBucketName       = "dockerhub"
+	jwtBucketName             = "jwt"
+	revokedTokenBucketName    = "revoked_tokens"
+	revokedUserBucketName     = "revoked_user_tokens"
+	auditLogBucketName        = "audit_logs"
 )
 
-// NewStore initializes a new Store and the associated services
-func NewStore(storePath string) (*Store, error) {
+// NewStore initializes a new Store and the associated services. The secrets are encrypted
+// when an encryption service is specified.
+func NewStore(storePath string, fileService portainer.FileService, encryptionService portainer.EncryptionService) (*Store, error) {
 	store := &Store{
 		Path:                   storePath,
+		fileService:            fileService,
+		encryptionService:      encryptionService,
 		UserService:            &UserService{},
+		APIKeyService:          &APIKeyService{},
 		TeamService:            &TeamService{},
 		TeamMembershipService:  &TeamMembershipService{},
 		EndpointService:        &EndpointService{},
+		EndpointGroupService:   &EndpointGroupService{},
 		ResourceControlService: &ResourceControlService{},
 		VersionService:         &VersionService{},
 		SettingsService:        &SettingsService{},
 		RegistryService:        &RegistryService{},
 		DockerHubService:       &DockerHubService{},
+		JWTSecretService:       &JWTSecretService{},
+		TokenRevocationService: &TokenRevocationService{},
+		AuditLogService:        &AuditLogService{},
 	}
 	store.UserService.store = store
+	store.APIKeyService.store = store
 	store.TeamService.store = store
 	store.TeamMembershipService.store = store
 	store.EndpointService.store = store
+	store.EndpointGroupService.store = store
 	store.ResourceControlService.store = store
 	store.VersionService.store = store
 	store.SettingsService.store = store
 	store.RegistryService.store = store
 	store.DockerHubService.store = store
+	store.JWTSecretService.store = store
+	store.TokenRevocationService.store = store
+	store.AuditLogService.store = store
 
 	_, err := os.Stat(storePath + "/" + databaseFileName)
 	if err != nil && os.IsNotExist(err) {
@@ -91,7 +117,8 @@ func (store *Store) Open() error {
 
 	bucketsToCreate := []string{versionBucketName, userBucketName, teamBucketName, endpointBucketName,
 		resourceControlBucketName, teamMembershipBucketName, settingsBucketName,
-		registryBucketName, dockerhubBucketName}
+		registryBucketName, dockerhubBucketName, apiKeyBucketName, jwtBucketName,
+		revokedTokenBucketName, revokedUserBucketName, auditLogBucketName, endpointGroupBucketName}
 
 	return db.Update(func(tx *bolt.Tx) error {
 
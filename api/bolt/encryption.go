@@ -0,0 +1,89 @@
+package bolt
+
+import (
+	"github.com/boltdb/bolt"
+	"github.com/portainer/portainer"
+	"github.com/portainer/portainer/crypto"
+)
+
+// encryptedBucketNames are the buckets containing secrets, their records are encrypted when a master key is configured.
+// The users bucket contains the TOTP secrets of the users.
+var encryptedBucketNames = []string{registryBucketName, dockerhubBucketName, settingsBucketName, jwtBucketName, userBucketName}
+
+// EncryptSecrets encrypts the records containing secrets and the key files stored in plain text when a master
+// key is configured. It is run at startup so that the secrets written without a master key are encrypted
+// as soon as one is configured.
+func (store *Store) EncryptSecrets() error {
+	if store.encryptionService == nil {
+		return nil
+	}
+
+	err := store.RotateEncryptionKey(store.encryptionService)
+	if err != nil {
+		return err
+	}
+
+	return store.fileService.EncryptKeyFiles(store.encryptionService)
+}
+
+// RotateEncryptionKey encrypts every record containing secrets with an encryption service, the encryption
+// service is used by the store afterwards. The records are decrypted with the current encryption service,
+// records stored in plain text are encrypted. Records already encrypted with the new encryption service
+// are left unchanged so that an interrupted rotation can be run again.
+func (store *Store) RotateEncryptionKey(encryptionService portainer.EncryptionService) error {
+	err := store.db.Update(func(tx *bolt.Tx) error {
+		for _, bucketName := range encryptedBucketNames {
+			bucket := tx.Bucket([]byte(bucketName))
+
+			records := make(map[string][]byte)
+			cursor := bucket.Cursor()
+			for k, v := cursor.First(); k != nil; k, v = cursor.Next() {
+				if crypto.IsEncrypted(v) {
+					if _, err := encryptionService.Decrypt(v); err == nil {
+						continue
+					}
+				}
+
+				data, err := store.decryptRecord(v, encryptionService)
+				if err != nil {
+					return err
+				}
+
+				records[string(k)], err = encryptionService.Encrypt(data)
+				if err != nil {
+					return err
+				}
+			}
+
+			for key, value := range records {
+				err := bucket.Put([]byte(key), value)
+				if err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	store.encryptionService = encryptionService
+	return nil
+}
+
+// decryptRecord decrypts a record using the current encryption service or, when the record
+// has been encrypted with another master key, using the new encryption service.
+func (store *Store) decryptRecord(data []byte, encryptionService portainer.EncryptionService) ([]byte, error) {
+	if !crypto.IsEncrypted(data) {
+		return data, nil
+	}
+
+	if store.encryptionService != nil {
+		decrypted, err := store.encryptionService.Decrypt(data)
+		if err != portainer.ErrEncryptionKeyMismatch {
+			return decrypted, err
+		}
+	}
+	return encryptionService.Decrypt(data)
+}
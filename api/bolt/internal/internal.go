@@ -2,19 +2,48 @@ package internal
 
 import (
 	"github.com/portainer/portainer"
+	"github.com/portainer/portainer/crypto"
 
 	"encoding/binary"
 	"encoding/json"
 )
 
-// MarshalUser encodes a user to binary format.
-func MarshalUser(user *portainer.User) ([]byte, error) {
-	return json.Marshal(user)
+// MarshalUser encodes a user to binary format, the result is encrypted when an encryption service is specified.
+func MarshalUser(user *portainer.User, encryptionService portainer.EncryptionService) ([]byte, error) {
+	data, err := json.Marshal(user)
+	if err != nil {
+		return nil, err
+	}
+	return encrypt(data, encryptionService)
+}
+
+// UnmarshalUser decodes a user from a binary data, the data is decrypted when it has been encrypted.
+func UnmarshalUser(data []byte, user *portainer.User, encryptionService portainer.EncryptionService) error {
+	data, err := decrypt(data, encryptionService)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, user)
 }
 
-// UnmarshalUser decodes a user from a binary data.
-func UnmarshalUser(data []byte, user *portainer.User) error {
-	return json.Unmarshal(data, user)
+// MarshalAPIKey encodes an API key to binary format.
+func MarshalAPIKey(apiKey *portainer.APIKey) ([]byte, error) {
+	return json.Marshal(apiKey)
+}
+
+// UnmarshalAPIKey decodes an API key from a binary data.
+func UnmarshalAPIKey(data []byte, apiKey *portainer.APIKey) error {
+	return json.Unmarshal(data, apiKey)
+}
+
+// MarshalAuditLog encodes an audit log to binary format.
+func MarshalAuditLog(auditLog *portainer.AuditLog) ([]byte, error) {
+	return json.Marshal(auditLog)
+}
+
+// UnmarshalAuditLog decodes an audit log from a binary data.
+func UnmarshalAuditLog(data []byte, auditLog *portainer.AuditLog) error {
+	return json.Unmarshal(data, auditLog)
 }
 
 // MarshalTeam encodes a team to binary format.
@@ -47,13 +76,31 @@ func UnmarshalEndpoint(data []byte, endpoint *portainer.Endpoint) error {
 	return json.Unmarshal(data, endpoint)
 }
 
-// MarshalRegistry encodes a registry to binary format.
-func MarshalRegistry(registry *portainer.Registry) ([]byte, error) {
-	return json.Marshal(registry)
+// MarshalEndpointGroup encodes an endpoint group to binary format.
+func MarshalEndpointGroup(group *portainer.EndpointGroup) ([]byte, error) {
+	return json.Marshal(group)
+}
+
+// UnmarshalEndpointGroup decodes an endpoint group from a binary data.
+func UnmarshalEndpointGroup(data []byte, group *portainer.EndpointGroup) error {
+	return json.Unmarshal(data, group)
 }
 
-// UnmarshalRegistry decodes a registry from a binary data.
-func UnmarshalRegistry(data []byte, registry *portainer.Registry) error {
+// MarshalRegistry encodes a registry to binary format, the result is encrypted when an encryption service is specified.
+func MarshalRegistry(registry *portainer.Registry, encryptionService portainer.EncryptionService) ([]byte, error) {
+	data, err := json.Marshal(registry)
+	if err != nil {
+		return nil, err
+	}
+	return encrypt(data, encryptionService)
+}
+
+// UnmarshalRegistry decodes a registry from a binary data, the data is decrypted when it has been encrypted.
+func UnmarshalRegistry(data []byte, registry *portainer.Registry, encryptionService portainer.EncryptionService) error {
+	data, err := decrypt(data, encryptionService)
+	if err != nil {
+		return err
+	}
 	return json.Unmarshal(data, registry)
 }
 
@@ -67,26 +114,62 @@ func UnmarshalResourceControl(data []byte, rc *portainer.ResourceControl) error
 	return json.Unmarshal(data, rc)
 }
 
-// MarshalSettings encodes a settings object to binary format.
-func MarshalSettings(settings *portainer.Settings) ([]byte, error) {
-	return json.Marshal(settings)
+// MarshalSettings encodes a settings object to binary format, the result is encrypted when an encryption service is specified.
+func MarshalSettings(settings *portainer.Settings, encryptionService portainer.EncryptionService) ([]byte, error) {
+	data, err := json.Marshal(settings)
+	if err != nil {
+		return nil, err
+	}
+	return encrypt(data, encryptionService)
 }
 
-// UnmarshalSettings decodes a settings object from a binary data.
-func UnmarshalSettings(data []byte, settings *portainer.Settings) error {
+// UnmarshalSettings decodes a settings object from a binary data, the data is decrypted when it has been encrypted.
+func UnmarshalSettings(data []byte, settings *portainer.Settings, encryptionService portainer.EncryptionService) error {
+	data, err := decrypt(data, encryptionService)
+	if err != nil {
+		return err
+	}
 	return json.Unmarshal(data, settings)
 }
 
-// MarshalDockerHub encodes a Dockerhub object to binary format.
-func MarshalDockerHub(settings *portainer.DockerHub) ([]byte, error) {
-	return json.Marshal(settings)
+// MarshalDockerHub encodes a Dockerhub object to binary format, the result is encrypted when an encryption service is specified.
+func MarshalDockerHub(settings *portainer.DockerHub, encryptionService portainer.EncryptionService) ([]byte, error) {
+	data, err := json.Marshal(settings)
+	if err != nil {
+		return nil, err
+	}
+	return encrypt(data, encryptionService)
 }
 
-// UnmarshalDockerHub decodes a Dockerhub object from a binary data.
-func UnmarshalDockerHub(data []byte, settings *portainer.DockerHub) error {
+// UnmarshalDockerHub decodes a Dockerhub object from a binary data, the data is decrypted when it has been encrypted.
+func UnmarshalDockerHub(data []byte, settings *portainer.DockerHub, encryptionService portainer.EncryptionService) error {
+	data, err := decrypt(data, encryptionService)
+	if err != nil {
+		return err
+	}
 	return json.Unmarshal(data, settings)
 }
 
+// MarshalTokenRevocation encodes a token revocation to binary format.
+func MarshalTokenRevocation(revocation *portainer.TokenRevocation) ([]byte, error) {
+	return json.Marshal(revocation)
+}
+
+// UnmarshalTokenRevocation decodes a token revocation from a binary data.
+func UnmarshalTokenRevocation(data []byte, revocation *portainer.TokenRevocation) error {
+	return json.Unmarshal(data, revocation)
+}
+
+// MarshalUserTokenRevocation encodes a user token revocation to binary format.
+func MarshalUserTokenRevocation(revocation *portainer.UserTokenRevocation) ([]byte, error) {
+	return json.Marshal(revocation)
+}
+
+// UnmarshalUserTokenRevocation decodes a user token revocation from a binary data.
+func UnmarshalUserTokenRevocation(data []byte, revocation *portainer.UserTokenRevocation) error {
+	return json.Unmarshal(data, revocation)
+}
+
 // Itob returns an 8-byte big endian representation of v.
 // This function is typically used for encoding integer IDs to byte slices
 // so that they can be used as BoltDB keys.
@@ -95,3 +178,32 @@ func Itob(v int) []byte {
 	binary.BigEndian.PutUint64(b, uint64(v))
 	return b
 }
+
+// MarshalJWTSecret returns the JWT secret to store, the result is encrypted when an encryption service is specified.
+func MarshalJWTSecret(secret []byte, encryptionService portainer.EncryptionService) ([]byte, error) {
+	return encrypt(secret, encryptionService)
+}
+
+// UnmarshalJWTSecret returns the stored JWT secret, the secret is decrypted when it has been encrypted.
+func UnmarshalJWTSecret(data []byte, encryptionService portainer.EncryptionService) ([]byte, error) {
+	return decrypt(data, encryptionService)
+}
+
+// encrypt encrypts data when an encryption service is specified.
+func encrypt(data []byte, encryptionService portainer.EncryptionService) ([]byte, error) {
+	if encryptionService == nil {
+		return data, nil
+	}
+	return encryptionService.Encrypt(data)
+}
+
+// decrypt decrypts data which has been encrypted, data stored in plain text is returned unchanged.
+func decrypt(data []byte, encryptionService portainer.EncryptionService) ([]byte, error) {
+	if !crypto.IsEncrypted(data) {
+		return data, nil
+	}
+	if encryptionService == nil {
+		return nil, portainer.ErrEncryptionKeyRequired
+	}
+	return encryptionService.Decrypt(data)
+}
@@ -0,0 +1,56 @@
+package bolt
+
+import (
+	"github.com/portainer/portainer"
+	"github.com/portainer/portainer/bolt/internal"
+
+	"github.com/boltdb/bolt"
+)
+
+// JWTSecretService represents a service to manage the secret used to sign JWT tokens.
+type JWTSecretService struct {
+	store *Store
+}
+
+const (
+	jwtSecretKey = "SECRET"
+)
+
+// Secret retrieves the JWT secret, the secret is decrypted when it has been encrypted.
+func (service *JWTSecretService) Secret() ([]byte, error) {
+	var data []byte
+	err := service.store.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(jwtBucketName))
+		value := bucket.Get([]byte(jwtSecretKey))
+		if value == nil {
+			return portainer.ErrSecretNotFound
+		}
+
+		data = make([]byte, len(value))
+		copy(data, value)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return internal.UnmarshalJWTSecret(data, service.store.encryptionService)
+}
+
+// StoreSecret persists the JWT secret, the secret is encrypted when an encryption service is configured.
+func (service *JWTSecretService) StoreSecret(secret []byte) error {
+	data, err := internal.MarshalJWTSecret(secret, service.store.encryptionService)
+	if err != nil {
+		return err
+	}
+
+	return service.store.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(jwtBucketName))
+
+		err := bucket.Put([]byte(jwtSecretKey), data)
+		if err != nil {
+			return err
+		}
+		return nil
+	})
+}
@@ -0,0 +1,23 @@
+package bolt
+
+import "github.com/portainer/portainer"
+
+func (m *Migrator) updateSettingsToDBVersion3() error {
+	// The settings are created with their default values after the migration when they do not exist.
+	legacySettings, err := m.SettingsService.Settings()
+	if err == portainer.ErrSettingsNotFound {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	legacySettings.AuthenticationMethod = portainer.AuthenticationInternal
+	legacySettings.LDAPSettings = portainer.LDAPSettings{
+		TLSConfig: portainer.LDAPTLSConfiguration{},
+		SearchSettings: []portainer.LDAPSearchSettings{
+			portainer.LDAPSearchSettings{},
+		},
+	}
+
+	return m.SettingsService.StoreSettings(legacySettings)
+}
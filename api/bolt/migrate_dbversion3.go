@@ -0,0 +1,23 @@
+package bolt
+
+import "github.com/portainer/portainer"
+
+func (m *Migrator) updateSettingsToDBVersion4() error {
+	// The settings are created with their default values after the migration when they do not exist.
+	legacySettings, err := m.SettingsService.Settings()
+	if err == portainer.ErrSettingsNotFound {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	legacySettings.LoginRateLimitSettings = portainer.LoginRateLimitSettings{
+		MaxFailedAttemptsPerUser: portainer.DefaultMaxFailedAttemptsPerUser,
+		MaxFailedAttemptsPerIP:   portainer.DefaultMaxFailedAttemptsPerIP,
+		LockoutDuration:          portainer.DefaultLoginLockoutDuration,
+		BackoffBaseDelay:         portainer.DefaultLoginBackoffBaseDelay,
+		BackoffMaxDelay:          portainer.DefaultLoginBackoffMaxDelay,
+	}
+
+	return m.SettingsService.StoreSettings(legacySettings)
+}
@@ -0,0 +1,153 @@
+package bolt
+
+import (
+	"github.com/portainer/portainer"
+	"github.com/portainer/portainer/bolt/internal"
+
+	"github.com/boltdb/bolt"
+)
+
+// TokenRevocationService represents a service for managing revoked JWT tokens.
+type TokenRevocationService struct {
+	store *Store
+}
+
+// TokenRevocation returns the revocation associated to a token ID.
+func (service *TokenRevocationService) TokenRevocation(tokenID string) (*portainer.TokenRevocation, error) {
+	var data []byte
+	err := service.store.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(revokedTokenBucketName))
+		value := bucket.Get([]byte(tokenID))
+		if value == nil {
+			return portainer.ErrTokenRevocationNotFound
+		}
+
+		data = make([]byte, len(value))
+		copy(data, value)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var revocation portainer.TokenRevocation
+	err = internal.UnmarshalTokenRevocation(data, &revocation)
+	if err != nil {
+		return nil, err
+	}
+	return &revocation, nil
+}
+
+// UserTokenRevocation returns the revocation of the tokens issued to a user.
+func (service *TokenRevocationService) UserTokenRevocation(userID portainer.UserID) (*portainer.UserTokenRevocation, error) {
+	var data []byte
+	err := service.store.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(revokedUserBucketName))
+		value := bucket.Get(internal.Itob(int(userID)))
+		if value == nil {
+			return portainer.ErrTokenRevocationNotFound
+		}
+
+		data = make([]byte, len(value))
+		copy(data, value)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var revocation portainer.UserTokenRevocation
+	err = internal.UnmarshalUserTokenRevocation(data, &revocation)
+	if err != nil {
+		return nil, err
+	}
+	return &revocation, nil
+}
+
+// RevokeToken saves a token revocation.
+func (service *TokenRevocationService) RevokeToken(revocation *portainer.TokenRevocation) error {
+	data, err := internal.MarshalTokenRevocation(revocation)
+	if err != nil {
+		return err
+	}
+
+	return service.store.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(revokedTokenBucketName))
+		err = bucket.Put([]byte(revocation.TokenID), data)
+
+		if err != nil {
+			return err
+		}
+		return nil
+	})
+}
+
+// RevokeUserTokens saves a user token revocation, replacing any previous revocation for this user.
+func (service *TokenRevocationService) RevokeUserTokens(revocation *portainer.UserTokenRevocation) error {
+	data, err := internal.MarshalUserTokenRevocation(revocation)
+	if err != nil {
+		return err
+	}
+
+	return service.store.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(revokedUserBucketName))
+		err = bucket.Put(internal.Itob(int(revocation.UserID)), data)
+
+		if err != nil {
+			return err
+		}
+		return nil
+	})
+}
+
+// DeleteExpiredRevocations removes the revocations that expired before now (unix time in seconds).
+// Once expired, the revoked tokens are rejected because of their own expiry.
+func (service *TokenRevocationService) DeleteExpiredRevocations(now int64) error {
+	return service.store.db.Update(func(tx *bolt.Tx) error {
+		tokenBucket := tx.Bucket([]byte(revokedTokenBucketName))
+
+		expiredKeys := make([][]byte, 0)
+		cursor := tokenBucket.Cursor()
+		for k, v := cursor.First(); k != nil; k, v = cursor.Next() {
+			var revocation portainer.TokenRevocation
+			err := internal.UnmarshalTokenRevocation(v, &revocation)
+			if err != nil {
+				return err
+			}
+			if revocation.ExpiresAt < now {
+				expiredKeys = append(expiredKeys, append([]byte{}, k...))
+			}
+		}
+
+		for _, key := range expiredKeys {
+			err := tokenBucket.Delete(key)
+			if err != nil {
+				return err
+			}
+		}
+
+		userBucket := tx.Bucket([]byte(revokedUserBucketName))
+
+		expiredKeys = make([][]byte, 0)
+		cursor = userBucket.Cursor()
+		for k, v := cursor.First(); k != nil; k, v = cursor.Next() {
+			var revocation portainer.UserTokenRevocation
+			err := internal.UnmarshalUserTokenRevocation(v, &revocation)
+			if err != nil {
+				return err
+			}
+			if revocation.ExpiresAt < now {
+				expiredKeys = append(expiredKeys, append([]byte{}, k...))
+			}
+		}
+
+		for _, key := range expiredKeys {
+			err := userBucket.Delete(key)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
@@ -20,8 +20,11 @@ const (
 	errSocketNotFound             = portainer.Error("Unable to locate Unix socket")
 	errEndpointsFileNotFound      = portainer.Error("Unable to locate external endpoints file")
 	errInvalidSyncInterval        = portainer.Error("Invalid synchronization interval")
+	errInvalidLDAPSyncInterval    = portainer.Error("Invalid LDAP synchronization interval")
+	errInvalidHealthCheckInterval = portainer.Error("Invalid endpoint health check interval")
 	errEndpointExcludeExternal    = portainer.Error("Cannot use the -H flag mutually with --external-endpoints")
 	errNoAuthExcludeAdminPassword = portainer.Error("Cannot use --no-auth with --admin-password")
+	errEncryptionKeyExcludeFile   = portainer.Error("Cannot use the PORTAINER_ENCRYPTION_KEY environment variable mutually with --encryption-key-file")
 )
 
 // ParseFlags parse the CLI flags and return a portainer.Flags struct
@@ -29,22 +32,27 @@ func (*Service) ParseFlags(version string) (*portainer.CLIFlags, error) {
 	kingpin.Version(version)
 
 	flags := &portainer.CLIFlags{
-		Endpoint:          kingpin.Flag("host", "Dockerd endpoint").Short('H').String(),
-		ExternalEndpoints: kingpin.Flag("external-endpoints", "Path to a file defining available endpoints").String(),
-		SyncInterval:      kingpin.Flag("sync-interval", "Duration between each synchronization via the external endpoints source").Default(defaultSyncInterval).String(),
-		Addr:              kingpin.Flag("bind", "Address and port to serve Portainer").Default(defaultBindAddress).Short('p').String(),
-		Assets:            kingpin.Flag("assets", "Path to the assets").Default(defaultAssetsDirectory).Short('a').String(),
-		Data:              kingpin.Flag("data", "Path to the folder where the data is stored").Default(defaultDataDirectory).Short('d').String(),
-		NoAuth:            kingpin.Flag("no-auth", "Disable authentication").Default(defaultNoAuth).Bool(),
-		NoAnalytics:       kingpin.Flag("no-analytics", "Disable Analytics in app").Default(defaultNoAuth).Bool(),
-		TLSVerify:         kingpin.Flag("tlsverify", "TLS support").Default(defaultTLSVerify).Bool(),
-		TLSCacert:         kingpin.Flag("tlscacert", "Path to the CA").Default(defaultTLSCACertPath).String(),
-		TLSCert:           kingpin.Flag("tlscert", "Path to the TLS certificate file").Default(defaultTLSCertPath).String(),
-		TLSKey:            kingpin.Flag("tlskey", "Path to the TLS key").Default(defaultTLSKeyPath).String(),
-		SSL:               kingpin.Flag("ssl", "Secure Portainer instance using SSL").Default(defaultSSL).Bool(),
-		SSLCert:           kingpin.Flag("sslcert", "Path to the SSL certificate used to secure the Portainer instance").Default(defaultSSLCertPath).String(),
-		SSLKey:            kingpin.Flag("sslkey", "Path to the SSL key used to secure the Portainer instance").Default(defaultSSLKeyPath).String(),
-		AdminPassword:     kingpin.Flag("admin-password", "Hashed admin password").String(),
+		Endpoint:            kingpin.Flag("host", "Dockerd endpoint").Short('H').String(),
+		ExternalEndpoints:   kingpin.Flag("external-endpoints", "Path to a file defining available endpoints").String(),
+		SyncInterval:        kingpin.Flag("sync-interval", "Duration between each synchronization via the external endpoints source").Default(defaultSyncInterval).String(),
+		Addr:                kingpin.Flag("bind", "Address and port to serve Portainer").Default(defaultBindAddress).Short('p').String(),
+		Assets:              kingpin.Flag("assets", "Path to the assets").Default(defaultAssetsDirectory).Short('a').String(),
+		Data:                kingpin.Flag("data", "Path to the folder where the data is stored").Default(defaultDataDirectory).Short('d').String(),
+		NoAuth:              kingpin.Flag("no-auth", "Disable authentication").Default(defaultNoAuth).Bool(),
+		NoAnalytics:         kingpin.Flag("no-analytics", "Disable Analytics in app").Default(defaultNoAuth).Bool(),
+		TLSVerify:           kingpin.Flag("tlsverify", "TLS support").Default(defaultTLSVerify).Bool(),
+		TLSCacert:           kingpin.Flag("tlscacert", "Path to the CA").Default(defaultTLSCACertPath).String(),
+		TLSCert:             kingpin.Flag("tlscert", "Path to the TLS certificate file").Default(defaultTLSCertPath).String(),
+		TLSKey:              kingpin.Flag("tlskey", "Path to the TLS key").Default(defaultTLSKeyPath).String(),
+		SSL:                 kingpin.Flag("ssl", "Secure Portainer instance using SSL").Default(defaultSSL).Bool(),
+		SSLCert:             kingpin.Flag("sslcert", "Path to the SSL certificate used to secure the Portainer instance").Default(defaultSSLCertPath).String(),
+		SSLKey:              kingpin.Flag("sslkey", "Path to the SSL key used to secure the Portainer instance").Default(defaultSSLKeyPath).String(),
+		AdminPassword:       kingpin.Flag("admin-password", "Hashed admin password").String(),
+		LDAPSyncInterval:    kingpin.Flag("ldap-sync-interval", "Duration between each synchronization of the team memberships with the LDAP groups").Default(defaultLDAPSyncInterval).String(),
+		HealthCheckInterval: kingpin.Flag("health-check-interval", "Duration between each health check of the endpoints").Default(defaultHealthCheckInterval).String(),
+		EncryptionKey:       kingpin.Flag("encryption-key", "Master key used to encrypt the stored secrets").Envar("PORTAINER_ENCRYPTION_KEY").Hidden().String(),
+		EncryptionKeyFile:   kingpin.Flag("encryption-key-file", "Path to a file containing the master key used to encrypt the stored secrets").String(),
+		RotateKeyFile:       kingpin.Flag("rotate-encryption-key-file", "Encrypt the stored secrets with the master key contained in this file and exit").String(),
 		// Deprecated flags
 		Labels:    pairs(kingpin.Flag("hide-label", "Hide containers with a specific label in the UI").Short('l')),
 		Logo:      kingpin.Flag("logo", "URL for the logo displayed in the UI").String(),
@@ -77,10 +85,24 @@ func (*Service) ValidateFlags(flags *portainer.CLIFlags) error {
 		return err
 	}
 
+	err = validateLDAPSyncInterval(*flags.LDAPSyncInterval)
+	if err != nil {
+		return err
+	}
+
+	err = validateHealthCheckInterval(*flags.HealthCheckInterval)
+	if err != nil {
+		return err
+	}
+
 	if *flags.NoAuth && (*flags.AdminPassword != "") {
 		return errNoAuthExcludeAdminPassword
 	}
 
+	if *flags.EncryptionKey != "" && *flags.EncryptionKeyFile != "" {
+		return errEncryptionKeyExcludeFile
+	}
+
 	displayDeprecationWarnings(*flags.Templates, *flags.Logo, *flags.Labels)
 
 	return nil
@@ -127,6 +149,26 @@ func validateSyncInterval(syncInterval string) error {
 	return nil
 }
 
+func validateLDAPSyncInterval(syncInterval string) error {
+	if syncInterval != defaultLDAPSyncInterval {
+		_, err := time.ParseDuration(syncInterval)
+		if err != nil {
+			return errInvalidLDAPSyncInterval
+		}
+	}
+	return nil
+}
+
+func validateHealthCheckInterval(healthCheckInterval string) error {
+	if healthCheckInterval != defaultHealthCheckInterval {
+		_, err := time.ParseDuration(healthCheckInterval)
+		if err != nil {
+			return errInvalidHealthCheckInterval
+		}
+	}
+	return nil
+}
+
 func displayDeprecationWarnings(templates, logo string, labels []portainer.Pair) {
 	if templates != "" {
 		log.Println("Warning: the --templates / -t flag is deprecated and will be removed in future versions.")
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"log"
+
+	"github.com/portainer/portainer"
+	"github.com/portainer/portainer/agent"
+
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+const (
+	defaultDockerHost = "unix:///var/run/docker.sock"
+)
+
+func main() {
+	kingpin.Version(portainer.APIVersion)
+
+	serverURL := kingpin.Flag("server", "URL of the Portainer instance").Required().String()
+	key := kingpin.Flag("key", "Agent key of the endpoint").Envar("PORTAINER_AGENT_KEY").Required().String()
+	dockerHost := kingpin.Flag("host", "Dockerd endpoint").Short('H').Default(defaultDockerHost).String()
+	tlsSkipVerify := kingpin.Flag("tls-skip-verify", "Disable the verification of the Portainer SSL certificate").Bool()
+	kingpin.Parse()
+
+	a, err := agent.NewAgent(*serverURL, *key, *dockerHost, *tlsSkipVerify)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	log.Printf("Starting Portainer agent %s, connecting to %s", portainer.APIVersion, *serverURL)
+	err = a.Run()
+	if err != nil {
+		log.Fatal(err)
+	}
+}
@@ -2,14 +2,22 @@ package main // import "github.com/portainer/portainer"
 
 import (
 	"github.com/portainer/portainer"
+	"github.com/portainer/portainer/audit"
 	"github.com/portainer/portainer/bolt"
 	"github.com/portainer/portainer/cli"
 	"github.com/portainer/portainer/cron"
 	"github.com/portainer/portainer/crypto"
 	"github.com/portainer/portainer/file"
 	"github.com/portainer/portainer/http"
+	"github.com/portainer/portainer/http/proxy"
 	"github.com/portainer/portainer/jwt"
+	"github.com/portainer/portainer/ldap"
+	"github.com/portainer/portainer/oauth"
+	"github.com/portainer/portainer/registry"
+	"github.com/portainer/portainer/ssh"
+	"github.com/portainer/portainer/tunnel"
 
+	"io/ioutil"
 	"log"
 )
 
@@ -27,16 +35,37 @@ func initCLI() *portainer.CLIFlags {
 	return flags
 }
 
-func initFileService(dataStorePath string) portainer.FileService {
-	fileService, err := file.NewService(dataStorePath, "")
+func initEncryptionService(flags *portainer.CLIFlags) portainer.EncryptionService {
+	masterKey := []byte(*flags.EncryptionKey)
+	if *flags.EncryptionKeyFile != "" {
+		var err error
+		masterKey, err = ioutil.ReadFile(*flags.EncryptionKeyFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	if len(masterKey) == 0 {
+		return nil
+	}
+
+	encryptionService, err := crypto.NewEncryptionService(masterKey)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return encryptionService
+}
+
+func initFileService(dataStorePath string, encryptionService portainer.EncryptionService) portainer.FileService {
+	fileService, err := file.NewService(dataStorePath, "", encryptionService)
 	if err != nil {
 		log.Fatal(err)
 	}
 	return fileService
 }
 
-func initStore(dataStorePath string) *bolt.Store {
-	store, err := bolt.NewStore(dataStorePath)
+func initStore(dataStorePath string, fileService portainer.FileService, encryptionService portainer.EncryptionService) *bolt.Store {
+	store, err := bolt.NewStore(dataStorePath, fileService, encryptionService)
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -50,12 +79,42 @@ func initStore(dataStorePath string) *bolt.Store {
 	if err != nil {
 		log.Fatal(err)
 	}
+
+	err = store.EncryptSecrets()
+	if err != nil {
+		log.Fatal(err)
+	}
 	return store
 }
 
-func initJWTService(authenticationEnabled bool) portainer.JWTService {
+// rotateEncryptionKey encrypts the stored secrets and private keys with the master key contained in a file.
+func rotateEncryptionKey(store *bolt.Store, fileService portainer.FileService, keyFilePath string) {
+	masterKey, err := ioutil.ReadFile(keyFilePath)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	encryptionService, err := crypto.NewEncryptionService(masterKey)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	err = store.RotateEncryptionKey(encryptionService)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	err = fileService.EncryptKeyFiles(encryptionService)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	log.Println("The stored secrets have been encrypted with the new master key. Portainer must now be started with this key.")
+}
+
+func initJWTService(authenticationEnabled bool, store *bolt.Store) portainer.JWTService {
 	if authenticationEnabled {
-		jwtService, err := jwt.NewService()
+		jwtService, err := jwt.NewService(store.JWTSecretService, store.TokenRevocationService)
 		if err != nil {
 			log.Fatal(err)
 		}
@@ -68,6 +127,18 @@ func initCryptoService() portainer.CryptoService {
 	return &crypto.Service{}
 }
 
+func initLDAPService() portainer.LDAPService {
+	return &ldap.Service{}
+}
+
+func initOAuthService() portainer.OAuthService {
+	return oauth.NewService()
+}
+
+func initRegistryBrowserService() portainer.RegistryBrowserService {
+	return registry.NewService()
+}
+
 func initEndpointWatcher(endpointService portainer.EndpointService, externalEnpointFile string, syncInterval string) bool {
 	authorizeEndpointMgmt := true
 	if externalEnpointFile != "" {
@@ -82,6 +153,65 @@ func initEndpointWatcher(endpointService portainer.EndpointService, externalEnpo
 	return authorizeEndpointMgmt
 }
 
+func initLDAPSyncJob(store *bolt.Store, ldapService portainer.LDAPService, syncInterval string) *cron.LDAPSyncJob {
+	ldapSyncJob := cron.NewLDAPSyncJob(store.UserService, store.TeamService, store.TeamMembershipService, store.SettingsService, ldapService)
+	ldapWatcher := cron.NewJobWatcher(syncInterval)
+	err := ldapWatcher.WatchLDAPGroups(ldapSyncJob)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return ldapSyncJob
+}
+
+func initExecRecordingRetentionJob(store *bolt.Store, fileService portainer.FileService) {
+	retentionJob := cron.NewExecRecordingRetentionJob(store.SettingsService, fileService)
+	retentionWatcher := cron.NewJobWatcher("")
+	err := retentionWatcher.WatchExecRecordings(retentionJob)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func initTunnelService(endpointService portainer.EndpointService) portainer.TunnelService {
+	tunnelService, err := tunnel.NewService(endpointService)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return tunnelService
+}
+
+func initSSHService(encryptionService portainer.EncryptionService) portainer.SSHService {
+	return ssh.NewService(encryptionService)
+}
+
+func initProxyManager(store *bolt.Store, encryptionService portainer.EncryptionService, tunnelService portainer.TunnelService, sshService portainer.SSHService) *proxy.Manager {
+	return proxy.NewManager(store.ResourceControlService, store.TeamMembershipService, store.SettingsService, store.RegistryService, store.DockerHubService, encryptionService, tunnelService, sshService)
+}
+
+func initEndpointHealthCheckJob(endpointService portainer.EndpointService, proxyManager *proxy.Manager, checkInterval string) {
+	healthCheckJob := cron.NewEndpointHealthCheckJob(endpointService, proxyManager)
+	healthCheckWatcher := cron.NewJobWatcher(checkInterval)
+	err := healthCheckWatcher.WatchEndpointHealth(healthCheckJob)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func initAuditForwarder(settingsService portainer.SettingsService, fileService portainer.FileService) *audit.Forwarder {
+	auditForwarder := audit.NewForwarder(fileService.GetAuditLogStorePath())
+
+	settings, err := settingsService.Settings()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	err = auditForwarder.Configure(settings.AuditSinks)
+	if err != nil {
+		log.Printf("Unable to configure the audit sinks: %s", err)
+	}
+	return auditForwarder
+}
+
 func initStatus(authorizeEndpointMgmt bool, flags *portainer.CLIFlags) *portainer.Status {
 	return &portainer.Status{
 		Analytics:          !*flags.NoAnalytics,
@@ -107,12 +237,43 @@ func initDockerHub(dockerHubService portainer.DockerHubService) error {
 	return nil
 }
 
+func initDefaultEndpointGroup(endpointGroupService portainer.EndpointGroupService) error {
+	_, err := endpointGroupService.EndpointGroup(portainer.DefaultEndpointGroupID)
+	if err == portainer.ErrEndpointGroupNotFound {
+		group := &portainer.EndpointGroup{
+			Name:            "Unassigned",
+			Description:     "Endpoints not assigned to any other group",
+			AuthorizedUsers: []portainer.UserID{},
+			AuthorizedTeams: []portainer.TeamID{},
+		}
+		return endpointGroupService.CreateEndpointGroup(group)
+	} else if err != nil {
+		return err
+	}
+
+	return nil
+}
+
 func initSettings(settingsService portainer.SettingsService, flags *portainer.CLIFlags) error {
 	_, err := settingsService.Settings()
 	if err == portainer.ErrSettingsNotFound {
 		settings := &portainer.Settings{
 			LogoURL:                     *flags.Logo,
 			DisplayExternalContributors: true,
+			AuthenticationMethod:        portainer.AuthenticationInternal,
+			LDAPSettings: portainer.LDAPSettings{
+				TLSConfig: portainer.LDAPTLSConfiguration{},
+				SearchSettings: []portainer.LDAPSearchSettings{
+					portainer.LDAPSearchSettings{},
+				},
+			},
+			LoginRateLimitSettings: portainer.LoginRateLimitSettings{
+				MaxFailedAttemptsPerUser: portainer.DefaultMaxFailedAttemptsPerUser,
+				MaxFailedAttemptsPerIP:   portainer.DefaultMaxFailedAttemptsPerIP,
+				LockoutDuration:          portainer.DefaultLoginLockoutDuration,
+				BackoffBaseDelay:         portainer.DefaultLoginBackoffBaseDelay,
+				BackoffMaxDelay:          portainer.DefaultLoginBackoffMaxDelay,
+			},
 		}
 
 		if *flags.Templates != "" {
@@ -146,15 +307,32 @@ func retrieveFirstEndpointFromDatabase(endpointService portainer.EndpointService
 func main() {
 	flags := initCLI()
 
-	fileService := initFileService(*flags.Data)
+	encryptionService := initEncryptionService(flags)
+
+	fileService := initFileService(*flags.Data, encryptionService)
 
-	store := initStore(*flags.Data)
+	store := initStore(*flags.Data, fileService, encryptionService)
 	defer store.Close()
 
-	jwtService := initJWTService(!*flags.NoAuth)
+	if *flags.RotateKeyFile != "" {
+		rotateEncryptionKey(store, fileService, *flags.RotateKeyFile)
+		return
+	}
+
+	jwtService := initJWTService(!*flags.NoAuth, store)
 
 	cryptoService := initCryptoService()
 
+	ldapService := initLDAPService()
+
+	oauthService := initOAuthService()
+
+	registryBrowserService := initRegistryBrowserService()
+
+	ldapSyncJob := initLDAPSyncJob(store, ldapService, *flags.LDAPSyncInterval)
+
+	initExecRecordingRetentionJob(store, fileService)
+
 	authorizeEndpointMgmt := initEndpointWatcher(store.EndpointService, *flags.ExternalEndpoints, *flags.SyncInterval)
 
 	err := initSettings(store.SettingsService, flags)
@@ -162,13 +340,26 @@ func main() {
 		log.Fatal(err)
 	}
 
+	auditForwarder := initAuditForwarder(store.SettingsService, fileService)
+
 	err = initDockerHub(store.DockerHubService)
 	if err != nil {
 		log.Fatal(err)
 	}
 
+	err = initDefaultEndpointGroup(store.EndpointGroupService)
+	if err != nil {
+		log.Fatal(err)
+	}
+
 	applicationStatus := initStatus(authorizeEndpointMgmt, flags)
 
+	tunnelService := initTunnelService(store.EndpointService)
+
+	sshService := initSSHService(encryptionService)
+
+	proxyManager := initProxyManager(store, encryptionService, tunnelService, sshService)
+
 	if *flags.Endpoint != "" {
 		var endpoints []portainer.Endpoint
 		endpoints, err := store.EndpointService.Endpoints()
@@ -177,14 +368,16 @@ func main() {
 		}
 		if len(endpoints) == 0 {
 			endpoint := &portainer.Endpoint{
-				Name:            "primary",
-				URL:             *flags.Endpoint,
-				TLS:             *flags.TLSVerify,
-				TLSCACertPath:   *flags.TLSCacert,
-				TLSCertPath:     *flags.TLSCert,
-				TLSKeyPath:      *flags.TLSKey,
-				AuthorizedUsers: []portainer.UserID{},
-				AuthorizedTeams: []portainer.TeamID{},
+				Name:                    "primary",
+				GroupID:                 portainer.DefaultEndpointGroupID,
+				URL:                     *flags.Endpoint,
+				TLS:                     *flags.TLSVerify,
+				TLSCACertPath:           *flags.TLSCacert,
+				TLSCertPath:             *flags.TLSCert,
+				TLSKeyPath:              *flags.TLSKey,
+				AuthorizedUsers:         []portainer.UserID{},
+				AuthorizedTeams:         []portainer.TeamID{},
+				ResourceOwnershipPolicy: portainer.PublicResourceOwnership,
 			}
 			err = store.EndpointService.CreateEndpoint(endpoint)
 			if err != nil {
@@ -208,6 +401,8 @@ func main() {
 		}
 	}
 
+	initEndpointHealthCheckJob(store.EndpointService, proxyManager, *flags.HealthCheckInterval)
+
 	var server portainer.Server = &http.Server{
 		Status:                 applicationStatus,
 		BindAddress:            *flags.Addr,
@@ -215,16 +410,29 @@ func main() {
 		AuthDisabled:           *flags.NoAuth,
 		EndpointManagement:     authorizeEndpointMgmt,
 		UserService:            store.UserService,
+		APIKeyService:          store.APIKeyService,
 		TeamService:            store.TeamService,
 		TeamMembershipService:  store.TeamMembershipService,
 		EndpointService:        store.EndpointService,
+		EndpointGroupService:   store.EndpointGroupService,
 		ResourceControlService: store.ResourceControlService,
 		SettingsService:        store.SettingsService,
 		RegistryService:        store.RegistryService,
 		DockerHubService:       store.DockerHubService,
 		CryptoService:          cryptoService,
+		EncryptionService:      encryptionService,
 		JWTService:             jwtService,
+		TokenRevocationService: store.TokenRevocationService,
+		AuditLogService:        store.AuditLogService,
+		AuditForwarder:         auditForwarder,
 		FileService:            fileService,
+		LDAPService:            ldapService,
+		LDAPSyncService:        ldapSyncJob,
+		OAuthService:           oauthService,
+		RegistryBrowserService: registryBrowserService,
+		ProxyManager:           proxyManager,
+		TunnelService:          tunnelService,
+		SSHService:             sshService,
 		SSL:                    *flags.SSL,
 		SSLCert:                *flags.SSLCert,
 		SSLKey:                 *flags.SSLKey,
@@ -0,0 +1,207 @@
+package cron
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/portainer/portainer"
+	"github.com/portainer/portainer/http/proxy"
+)
+
+const (
+	// endpointHealthCheckTimeout is the maximum duration of each request sent to the Docker API of an endpoint.
+	endpointHealthCheckTimeout = 10 * time.Second
+)
+
+// EndpointHealthCheckJob represents a job used to check the availability of the endpoints. The Docker API
+// of each endpoint is reached through the transport of its proxy and the status of the endpoint, along with
+// a snapshot of its Docker environment, is recorded on the endpoint.
+type EndpointHealthCheckJob struct {
+	logger          *log.Logger
+	endpointService portainer.EndpointService
+	proxyManager    *proxy.Manager
+	mu              sync.Mutex
+	running         bool
+}
+
+type (
+	dockerVersionResponse struct {
+		Version string
+	}
+	dockerInfoResponse struct {
+		Containers        int
+		ContainersRunning int
+		Images            int
+		Swarm             struct {
+			LocalNodeState string
+		}
+	}
+	dockerVolumesResponse struct {
+		Volumes []json.RawMessage
+	}
+)
+
+// NewEndpointHealthCheckJob initializes a new endpoint health check job.
+func NewEndpointHealthCheckJob(endpointService portainer.EndpointService, proxyManager *proxy.Manager) *EndpointHealthCheckJob {
+	return &EndpointHealthCheckJob{
+		logger:          log.New(os.Stderr, "", log.LstdFlags),
+		endpointService: endpointService,
+		proxyManager:    proxyManager,
+	}
+}
+
+// Run is used to implement the cron.Job interface. A run is skipped when the previous one is not over.
+func (job *EndpointHealthCheckJob) Run() {
+	job.mu.Lock()
+	if job.running {
+		job.mu.Unlock()
+		return
+	}
+	job.running = true
+	job.mu.Unlock()
+
+	defer func() {
+		job.mu.Lock()
+		job.running = false
+		job.mu.Unlock()
+	}()
+
+	err := job.CheckEndpoints()
+	if err != nil {
+		job.logger.Printf("Endpoint health check error: %s", err)
+	}
+}
+
+// CheckEndpoints checks all the endpoints concurrently and records their status.
+func (job *EndpointHealthCheckJob) CheckEndpoints() error {
+	endpoints, err := job.endpointService.Endpoints()
+	if err != nil {
+		return err
+	}
+
+	var wg sync.WaitGroup
+	for idx := range endpoints {
+		wg.Add(1)
+		go func(endpoint *portainer.Endpoint) {
+			defer wg.Done()
+			err := job.CheckEndpoint(endpoint)
+			if err != nil {
+				job.logger.Printf("Unable to record the status of endpoint %s: %s", endpoint.Name, err)
+			}
+		}(&endpoints[idx])
+	}
+	wg.Wait()
+
+	return nil
+}
+
+// CheckEndpoint checks the availability of an endpoint and records its status. The endpoint is retrieved
+// again before being updated so that the changes made during the check are preserved, the result is
+// discarded when the endpoint was deleted or its URL changed in the meantime.
+func (job *EndpointHealthCheckJob) CheckEndpoint(endpoint *portainer.Endpoint) error {
+	now := time.Now()
+	snapshot, latency, checkErr := job.snapshotEndpoint(endpoint)
+
+	current, err := job.endpointService.Endpoint(endpoint.ID)
+	if err == portainer.ErrEndpointNotFound {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	if current.URL != endpoint.URL {
+		return nil
+	}
+
+	current.LastCheck = now.Unix()
+	if checkErr != nil {
+		current.Status = portainer.EndpointStatusDown
+		current.StatusMessage = checkErr.Error()
+		current.Latency = 0
+	} else {
+		current.Status = portainer.EndpointStatusUp
+		current.StatusMessage = ""
+		current.Latency = latency
+		current.Snapshot = snapshot
+	}
+
+	return job.endpointService.UpdateEndpoint(current.ID, current)
+}
+
+// snapshotEndpoint pings the Docker API of an endpoint and returns a snapshot of its Docker environment,
+// along with the latency of the ping in milliseconds.
+func (job *EndpointHealthCheckJob) snapshotEndpoint(endpoint *portainer.Endpoint) (*portainer.EndpointSnapshot, int64, error) {
+	transport, baseURL, err := job.proxyManager.DockerTransport(endpoint)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	client := &http.Client{
+		Transport: transport,
+		Timeout:   endpointHealthCheckTimeout,
+	}
+
+	start := time.Now()
+	err = dockerRequest(client, baseURL, "/_ping", nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	latency := int64(time.Since(start) / time.Millisecond)
+
+	var version dockerVersionResponse
+	err = dockerRequest(client, baseURL, "/version", &version)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var info dockerInfoResponse
+	err = dockerRequest(client, baseURL, "/info", &info)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var volumes dockerVolumesResponse
+	err = dockerRequest(client, baseURL, "/volumes", &volumes)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	snapshot := &portainer.EndpointSnapshot{
+		Time:                  time.Now().Unix(),
+		DockerVersion:         version.Version,
+		Swarm:                 info.Swarm.LocalNodeState == "active",
+		RunningContainerCount: info.ContainersRunning,
+		StoppedContainerCount: info.Containers - info.ContainersRunning,
+		ImageCount:            info.Images,
+		VolumeCount:           len(volumes.Volumes),
+	}
+	return snapshot, latency, nil
+}
+
+// dockerRequest sends a GET request to the Docker API and decodes the response in result when it is not nil.
+func dockerRequest(client *http.Client, baseURL *url.URL, path string, result interface{}) error {
+	requestURL := *baseURL
+	requestURL.Path = strings.TrimRight(baseURL.Path, "/") + path
+
+	resp, err := client.Get(requestURL.String())
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("Unexpected response status on %s: %d", path, resp.StatusCode)
+	}
+
+	if result == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(result)
+}
@@ -84,9 +84,31 @@ func mergeEndpointIfRequired(original, updated *portainer.Endpoint) *portainer.E
 			endpoint.TLSKeyPath = ""
 		}
 	}
+	if !equalTags(original.Tags, updated.Tags) {
+		endpoint = original
+		endpoint.Tags = updated.Tags
+	}
 	return endpoint
 }
 
+// equalTags checks that two lists contain the same tags, regardless of their order.
+func equalTags(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	tags := make(map[string]int)
+	for _, tag := range a {
+		tags[tag]++
+	}
+	for _, tag := range b {
+		if tags[tag] == 0 {
+			return false
+		}
+		tags[tag]--
+	}
+	return true
+}
+
 func (sync synchronization) requireSync() bool {
 	if len(sync.endpointsToCreate) != 0 || len(sync.endpointsToUpdate) != 0 || len(sync.endpointsToDelete) != 0 {
 		return true
@@ -124,6 +146,7 @@ func (job endpointSyncJob) prepareSyncData(storedEndpoints, fileEndpoints []port
 		sidx := endpointExists(&fileEndpoints[idx], storedEndpoints)
 		if sidx == -1 {
 			job.logger.Printf("File endpoint not found in database, adding to database. [name: %v] [url: %v]", fileEndpoints[idx].Name, fileEndpoints[idx].URL)
+			fileEndpoints[idx].GroupID = portainer.DefaultEndpointGroupID
 			endpointsToCreate = append(endpointsToCreate, &fileEndpoints[idx])
 		}
 	}
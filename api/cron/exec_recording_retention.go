@@ -0,0 +1,80 @@
+package cron
+
+import (
+	"log"
+	"os"
+	"time"
+
+	"github.com/portainer/portainer"
+)
+
+const (
+	// execRecordingRetentionInterval is the interval at which the retention policy of the recordings is enforced.
+	execRecordingRetentionInterval = "1h"
+)
+
+// ExecRecordingRetentionJob represents a job used to delete the recordings of exec sessions
+// older than the retention period defined in the settings.
+type ExecRecordingRetentionJob struct {
+	logger          *log.Logger
+	settingsService portainer.SettingsService
+	fileService     portainer.FileService
+}
+
+// NewExecRecordingRetentionJob initializes a new exec recording retention job.
+func NewExecRecordingRetentionJob(settingsService portainer.SettingsService, fileService portainer.FileService) *ExecRecordingRetentionJob {
+	return &ExecRecordingRetentionJob{
+		logger:          log.New(os.Stderr, "", log.LstdFlags),
+		settingsService: settingsService,
+		fileService:     fileService,
+	}
+}
+
+// Run is used to implement the cron.Job interface.
+func (job *ExecRecordingRetentionJob) Run() {
+	deleted, err := job.DeleteExpiredRecordings(time.Now())
+	if err != nil {
+		job.logger.Printf("Exec recording retention error: %s", err)
+		return
+	}
+
+	if deleted > 0 {
+		job.logger.Printf("Exec recording retention ended. [deleted: %v]", deleted)
+	}
+}
+
+// DeleteExpiredRecordings deletes the recordings started before the retention period and returns
+// the number of deleted recordings. Nothing is deleted when no retention period is defined.
+func (job *ExecRecordingRetentionJob) DeleteExpiredRecordings(now time.Time) (int, error) {
+	settings, err := job.settingsService.Settings()
+	if err != nil {
+		return 0, err
+	}
+
+	retentionDays := settings.ExecRecordingSettings.RetentionDays
+	if retentionDays <= 0 {
+		return 0, nil
+	}
+
+	recordings, err := job.fileService.ExecRecordings()
+	if err != nil {
+		return 0, err
+	}
+
+	deleted := 0
+	limit := now.AddDate(0, 0, -retentionDays).Unix()
+	for _, recording := range recordings {
+		if recording.StartedAt >= limit {
+			continue
+		}
+
+		err = job.fileService.DeleteExecRecording(recording.ID)
+		if err != nil && err != portainer.ErrExecRecordingNotFound {
+			job.logger.Printf("Unable to delete exec recording %s: %s", recording.ID, err)
+			continue
+		}
+		deleted++
+	}
+
+	return deleted, nil
+}
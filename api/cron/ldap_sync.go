@@ -0,0 +1,197 @@
+package cron
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/portainer/portainer"
+)
+
+const (
+	// maxLDAPSyncReports is the number of synchronization reports kept in memory.
+	maxLDAPSyncReports = 20
+)
+
+// LDAPSyncJob represents a job used to synchronize the team memberships with the LDAP groups.
+// It implements the portainer.LDAPSyncService interface.
+type LDAPSyncJob struct {
+	logger                *log.Logger
+	userService           portainer.UserService
+	teamService           portainer.TeamService
+	teamMembershipService portainer.TeamMembershipService
+	settingsService       portainer.SettingsService
+	ldapService           portainer.LDAPService
+	mu                    sync.Mutex
+	reports               []portainer.LDAPSyncReport
+}
+
+// NewLDAPSyncJob initializes a new LDAP synchronization job.
+func NewLDAPSyncJob(userService portainer.UserService, teamService portainer.TeamService, teamMembershipService portainer.TeamMembershipService,
+	settingsService portainer.SettingsService, ldapService portainer.LDAPService) *LDAPSyncJob {
+	return &LDAPSyncJob{
+		logger:                log.New(os.Stderr, "", log.LstdFlags),
+		userService:           userService,
+		teamService:           teamService,
+		teamMembershipService: teamMembershipService,
+		settingsService:       settingsService,
+		ldapService:           ldapService,
+		reports:               make([]portainer.LDAPSyncReport, 0),
+	}
+}
+
+// Run is used to implement the cron.Job interface.
+func (job *LDAPSyncJob) Run() {
+	report, err := job.Synchronize()
+	if err == portainer.ErrLDAPSyncDisabled {
+		return
+	} else if err != nil {
+		job.logger.Printf("LDAP synchronization error: %s", err)
+		return
+	}
+
+	job.logger.Printf("LDAP synchronization ended. [created: %v] [deleted: %v] [errors: %v]",
+		len(report.CreatedMemberships), len(report.DeletedMemberships), len(report.Errors))
+}
+
+// Reports returns the most recent synchronization reports, latest first.
+func (job *LDAPSyncJob) Reports() []portainer.LDAPSyncReport {
+	job.mu.Lock()
+	defer job.mu.Unlock()
+
+	reports := make([]portainer.LDAPSyncReport, len(job.reports))
+	for idx, report := range job.reports {
+		reports[len(job.reports)-1-idx] = report
+	}
+	return reports
+}
+
+// Synchronize creates and removes the team memberships of the users found in the LDAP directory
+// so that they match the LDAP group to team mappings. Memberships of teams that are not associated
+// to any LDAP group are left untouched.
+func (job *LDAPSyncJob) Synchronize() (*portainer.LDAPSyncReport, error) {
+	job.mu.Lock()
+	defer job.mu.Unlock()
+
+	settings, err := job.settingsService.Settings()
+	if err != nil {
+		return nil, err
+	}
+
+	if settings.AuthenticationMethod != portainer.AuthenticationLDAP || len(settings.LDAPSettings.GroupTeamMappings) == 0 {
+		return nil, portainer.ErrLDAPSyncDisabled
+	}
+
+	report := &portainer.LDAPSyncReport{
+		StartedAt:          time.Now().Unix(),
+		CreatedMemberships: make([]portainer.TeamMembership, 0),
+		DeletedMemberships: make([]portainer.TeamMembership, 0),
+		Errors:             make([]string, 0),
+	}
+
+	mappings := job.validMappings(settings.LDAPSettings.GroupTeamMappings, report)
+
+	users, err := job.userService.Users()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, user := range users {
+		err := job.synchronizeUser(&user, mappings, &settings.LDAPSettings, report)
+		if err != nil {
+			job.logger.Printf("LDAP synchronization error for user %s: %s", user.Username, err)
+			report.Errors = append(report.Errors, fmt.Sprintf("%s: %s", user.Username, err))
+		}
+	}
+
+	report.EndedAt = time.Now().Unix()
+
+	job.reports = append(job.reports, *report)
+	if len(job.reports) > maxLDAPSyncReports {
+		job.reports = job.reports[len(job.reports)-maxLDAPSyncReports:]
+	}
+
+	return report, nil
+}
+
+// validMappings filters out the mappings referencing a team that does not exist.
+func (job *LDAPSyncJob) validMappings(mappings []portainer.LDAPGroupTeamMapping, report *portainer.LDAPSyncReport) []portainer.LDAPGroupTeamMapping {
+	validMappings := make([]portainer.LDAPGroupTeamMapping, 0)
+	for _, mapping := range mappings {
+		_, err := job.teamService.Team(mapping.TeamID)
+		if err != nil {
+			report.Errors = append(report.Errors, fmt.Sprintf("Invalid mapping for group %s: %s", mapping.GroupDN, err))
+			continue
+		}
+		validMappings = append(validMappings, mapping)
+	}
+	return validMappings
+}
+
+func (job *LDAPSyncJob) synchronizeUser(user *portainer.User, mappings []portainer.LDAPGroupTeamMapping, settings *portainer.LDAPSettings, report *portainer.LDAPSyncReport) error {
+	groups, err := job.ldapService.GetUserGroups(user.Username, settings)
+	if err == portainer.ErrLDAPUserNotFound {
+		// Local accounts are not managed by the directory.
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	mappedTeams := make(map[portainer.TeamID]bool)
+	expectedTeams := make(map[portainer.TeamID]bool)
+	for _, mapping := range mappings {
+		mappedTeams[mapping.TeamID] = true
+		if isMemberOfGroup(mapping.GroupDN, groups) {
+			expectedTeams[mapping.TeamID] = true
+		}
+	}
+
+	memberships, err := job.teamMembershipService.TeamMembershipsByUserID(user.ID)
+	if err != nil {
+		return err
+	}
+
+	currentTeams := make(map[portainer.TeamID]bool)
+	for _, membership := range memberships {
+		currentTeams[membership.TeamID] = true
+
+		if mappedTeams[membership.TeamID] && !expectedTeams[membership.TeamID] {
+			err = job.teamMembershipService.DeleteTeamMembership(membership.ID)
+			if err != nil {
+				return err
+			}
+			report.DeletedMemberships = append(report.DeletedMemberships, membership)
+		}
+	}
+
+	for teamID := range expectedTeams {
+		if currentTeams[teamID] {
+			continue
+		}
+
+		membership := &portainer.TeamMembership{
+			UserID: user.ID,
+			TeamID: teamID,
+			Role:   portainer.TeamMember,
+		}
+		err = job.teamMembershipService.CreateTeamMembership(membership)
+		if err != nil {
+			return err
+		}
+		report.CreatedMemberships = append(report.CreatedMemberships, *membership)
+	}
+
+	return nil
+}
+
+func isMemberOfGroup(groupDN string, groups []string) bool {
+	for _, group := range groups {
+		if strings.EqualFold(group, groupDN) {
+			return true
+		}
+	}
+	return false
+}
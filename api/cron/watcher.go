@@ -21,6 +21,15 @@ func NewWatcher(endpointService portainer.EndpointService, syncInterval string)
 	}
 }
 
+// NewJobWatcher initializes a new service used to run jobs which retrieve their data from their own services.
+// The interval is used by the jobs run at the sync interval.
+func NewJobWatcher(interval string) *Watcher {
+	return &Watcher{
+		Cron:         cron.New(),
+		syncInterval: interval,
+	}
+}
+
 // WatchEndpointFile starts a cron job to synchronize the endpoints from a file
 func (watcher *Watcher) WatchEndpointFile(endpointFilePath string) error {
 	job := newEndpointSyncJob(endpointFilePath, watcher.EndpointService)
@@ -38,3 +47,39 @@ func (watcher *Watcher) WatchEndpointFile(endpointFilePath string) error {
 	watcher.Cron.Start()
 	return nil
 }
+
+// WatchLDAPGroups starts a cron job to synchronize the team memberships with the LDAP groups.
+func (watcher *Watcher) WatchLDAPGroups(job *LDAPSyncJob) error {
+	err := watcher.Cron.AddJob("@every "+watcher.syncInterval, job)
+	if err != nil {
+		return err
+	}
+
+	watcher.Cron.Start()
+	return nil
+}
+
+// WatchExecRecordings starts a cron job to enforce the retention policy of the exec session recordings.
+func (watcher *Watcher) WatchExecRecordings(job *ExecRecordingRetentionJob) error {
+	err := watcher.Cron.AddJob("@every "+execRecordingRetentionInterval, job)
+	if err != nil {
+		return err
+	}
+
+	watcher.Cron.Start()
+	return nil
+}
+
+// WatchEndpointHealth starts a cron job to check the availability of the endpoints.
+// The endpoints are checked once in the background when the job is started.
+func (watcher *Watcher) WatchEndpointHealth(job *EndpointHealthCheckJob) error {
+	err := watcher.Cron.AddJob("@every "+watcher.syncInterval, job)
+	if err != nil {
+		return err
+	}
+
+	go job.Run()
+
+	watcher.Cron.Start()
+	return nil
+}
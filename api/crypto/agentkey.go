@@ -0,0 +1,28 @@
+package crypto
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+)
+
+const (
+	// AgentKeyPrefix is prepended to every generated agent key so that they can easily be identified.
+	AgentKeyPrefix = "ptra_"
+	agentKeySize   = 32
+)
+
+// GenerateAgentKey returns a new random key used by an agent to open the reverse tunnel of an endpoint.
+func GenerateAgentKey() (string, error) {
+	b := make([]byte, agentKeySize)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+	return AgentKeyPrefix + base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// HashAgentKey returns the digest of an agent key. Like API keys, agent keys have a high entropy
+// and a fast hash is used.
+func HashAgentKey(key string) string {
+	return HashAPIKey(key)
+}
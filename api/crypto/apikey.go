@@ -0,0 +1,31 @@
+package crypto
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+)
+
+const (
+	// APIKeyPrefix is prepended to every generated API key so that they can easily be identified.
+	APIKeyPrefix = "ptr_"
+	apiKeySize   = 32
+)
+
+// GenerateAPIKey returns a new random API key.
+func GenerateAPIKey() (string, error) {
+	b := make([]byte, apiKeySize)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+	return APIKeyPrefix + base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// HashAPIKey returns the digest of an API key. API keys are random values with a high entropy,
+// a fast hash is used so that they can be looked up on every request.
+func HashAPIKey(key string) string {
+	digest := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(digest[:])
+}
@@ -0,0 +1,147 @@
+package crypto
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+
+	"github.com/portainer/portainer"
+)
+
+const (
+	// MinimumMasterKeyLength is the minimum number of characters of a master key.
+	MinimumMasterKeyLength = 32
+	dataKeySize            = 32
+	keyIDSize              = 8
+	nonceSize              = 12
+	// wrappedDataKeySize is the size of a data key encrypted with the master key: nonce, key and GCM tag.
+	wrappedDataKeySize = nonceSize + dataKeySize + 16
+)
+
+// encryptedDataPrefix identifies the data encrypted by an EncryptionService. JSON documents and PEM files
+// cannot start with this prefix.
+var encryptedDataPrefix = []byte("penc1:")
+
+// EncryptionService implements portainer.EncryptionService using envelope encryption: every content is
+// encrypted with its own random data key using AES-256-GCM, the data key is encrypted with the master key
+// and stored with the content. The identifier of the master key is stored as well, so that decrypting a
+// content with another master key is reported as such.
+type EncryptionService struct {
+	masterKey []byte
+	keyID     []byte
+}
+
+// NewEncryptionService initializes a new service using a master key. Leading and trailing white spaces
+// of the master key are ignored and the AES key is derived from the master key using SHA-256.
+func NewEncryptionService(masterKey []byte) (*EncryptionService, error) {
+	masterKey = bytes.TrimSpace(masterKey)
+	if len(masterKey) < MinimumMasterKeyLength {
+		return nil, portainer.ErrEncryptionKeyTooShort
+	}
+
+	key := sha256.Sum256(masterKey)
+	keyID := sha256.Sum256(key[:])
+	return &EncryptionService{
+		masterKey: key[:],
+		keyID:     keyID[:keyIDSize],
+	}, nil
+}
+
+// IsEncrypted returns true when a content has been encrypted by an EncryptionService.
+func IsEncrypted(data []byte) bool {
+	return bytes.HasPrefix(data, encryptedDataPrefix)
+}
+
+// Encrypt encrypts a content with a new data key.
+func (service *EncryptionService) Encrypt(data []byte) ([]byte, error) {
+	dataKey := make([]byte, dataKeySize)
+	_, err := rand.Read(dataKey)
+	if err != nil {
+		return nil, err
+	}
+
+	wrappedDataKey, err := seal(service.masterKey, dataKey)
+	if err != nil {
+		return nil, err
+	}
+
+	ciphertext, err := seal(dataKey, data)
+	if err != nil {
+		return nil, err
+	}
+
+	encrypted := make([]byte, 0, len(encryptedDataPrefix)+keyIDSize+len(wrappedDataKey)+len(ciphertext))
+	encrypted = append(encrypted, encryptedDataPrefix...)
+	encrypted = append(encrypted, service.keyID...)
+	encrypted = append(encrypted, wrappedDataKey...)
+	return append(encrypted, ciphertext...), nil
+}
+
+// Decrypt decrypts a content encrypted with Encrypt. A content which is not encrypted is returned unchanged.
+func (service *EncryptionService) Decrypt(data []byte) ([]byte, error) {
+	if !IsEncrypted(data) {
+		return data, nil
+	}
+
+	data = data[len(encryptedDataPrefix):]
+	if len(data) < keyIDSize+wrappedDataKeySize+nonceSize {
+		return nil, portainer.ErrInvalidEncryptedData
+	}
+
+	if subtle.ConstantTimeCompare(data[:keyIDSize], service.keyID) != 1 {
+		return nil, portainer.ErrEncryptionKeyMismatch
+	}
+	data = data[keyIDSize:]
+
+	dataKey, err := open(service.masterKey, data[:wrappedDataKeySize])
+	if err != nil {
+		return nil, err
+	}
+
+	return open(dataKey, data[wrappedDataKeySize:])
+}
+
+// seal encrypts a content using AES-GCM with a random nonce, the nonce is prepended to the result.
+func seal(key, plaintext []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, nonceSize)
+	_, err = rand.Read(nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	return gcm.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+// open decrypts a content encrypted with seal.
+func open(key, ciphertext []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(ciphertext) < nonceSize {
+		return nil, portainer.ErrInvalidEncryptedData
+	}
+
+	plaintext, err := gcm.Open(nil, ciphertext[:nonceSize], ciphertext[nonceSize:], nil)
+	if err != nil {
+		return nil, portainer.ErrInvalidEncryptedData
+	}
+	return plaintext, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
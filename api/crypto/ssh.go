@@ -0,0 +1,58 @@
+package crypto
+
+import (
+	"io/ioutil"
+	"os"
+	"time"
+
+	"github.com/portainer/portainer"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+const (
+	sshHandshakeTimeout = 10 * time.Second
+)
+
+// CreateSSHClientConfiguration initializes an ssh.ClientConfig authenticating a user with a private key.
+// The host keys are pinned, only the keys listed in the known hosts file are accepted.
+// The private key is decrypted with the encryption service when it has been stored encrypted.
+func CreateSSHClientConfiguration(user, keyPath, knownHostsPath string, encryptionService portainer.EncryptionService) (*ssh.ClientConfig, error) {
+	keyPEM, err := ioutil.ReadFile(keyPath)
+	if os.IsNotExist(err) {
+		return nil, portainer.ErrSSHKeyNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	if IsEncrypted(keyPEM) {
+		if encryptionService == nil {
+			return nil, portainer.ErrEncryptionKeyRequired
+		}
+		keyPEM, err = encryptionService.Decrypt(keyPEM)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	signer, err := ssh.ParsePrivateKey(keyPEM)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = os.Stat(knownHostsPath)
+	if os.IsNotExist(err) {
+		return nil, portainer.ErrSSHKnownHostsNotFound
+	}
+	hostKeyCallback, err := knownhosts.New(knownHostsPath)
+	if err != nil {
+		return nil, err
+	}
+
+	config := &ssh.ClientConfig{
+		User:            user,
+		Auth:            []ssh.AuthMethod{ssh.PublicKeys(signer)},
+		HostKeyCallback: hostKeyCallback,
+		Timeout:         sshHandshakeTimeout,
+	}
+	return config, nil
+}
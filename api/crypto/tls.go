@@ -4,11 +4,31 @@ import (
 	"crypto/tls"
 	"crypto/x509"
 	"io/ioutil"
+
+	"github.com/portainer/portainer"
 )
 
-// CreateTLSConfiguration initializes a tls.Config using a CA certificate, a certificate and a key
-func CreateTLSConfiguration(caCertPath, certPath, keyPath string) (*tls.Config, error) {
-	cert, err := tls.LoadX509KeyPair(certPath, keyPath)
+// CreateTLSConfiguration initializes a tls.Config using a CA certificate, a certificate and a key.
+// The key is decrypted with the encryption service when it has been stored encrypted.
+func CreateTLSConfiguration(caCertPath, certPath, keyPath string, encryptionService portainer.EncryptionService) (*tls.Config, error) {
+	certPEM, err := ioutil.ReadFile(certPath)
+	if err != nil {
+		return nil, err
+	}
+	keyPEM, err := ioutil.ReadFile(keyPath)
+	if err != nil {
+		return nil, err
+	}
+	if IsEncrypted(keyPEM) {
+		if encryptionService == nil {
+			return nil, portainer.ErrEncryptionKeyRequired
+		}
+		keyPEM, err = encryptionService.Decrypt(keyPEM)
+		if err != nil {
+			return nil, err
+		}
+	}
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
 	if err != nil {
 		return nil, err
 	}
@@ -24,3 +44,23 @@ func CreateTLSConfiguration(caCertPath, certPath, keyPath string) (*tls.Config,
 	}
 	return config, nil
 }
+
+// CreateTLSConfigurationWithCA initializes a client tls.Config using an optional CA certificate.
+// Server certificate verification is disabled when skipVerify is set.
+func CreateTLSConfigurationWithCA(caCertPath string, skipVerify bool) (*tls.Config, error) {
+	config := &tls.Config{
+		InsecureSkipVerify: skipVerify,
+	}
+
+	if caCertPath != "" {
+		caCert, err := ioutil.ReadFile(caCertPath)
+		if err != nil {
+			return nil, err
+		}
+		caCertPool := x509.NewCertPool()
+		caCertPool.AppendCertsFromPEM(caCert)
+		config.RootCAs = caCertPool
+	}
+
+	return config, nil
+}
@@ -15,6 +15,20 @@ const (
 	ErrUserAlreadyExists       = Error("User already exists")
 	ErrInvalidUsername         = Error("Invalid username. White spaces are not allowed.")
 	ErrAdminAlreadyInitialized = Error("Admin user already initialized")
+	ErrTooManyLoginAttempts    = Error("Too many failed authentication attempts, try again later")
+)
+
+// Two-factor authentication errors.
+const (
+	ErrTwoFactorAlreadyEnabled = Error("Two-factor authentication is already enabled for this user")
+	ErrTwoFactorNotEnrolled    = Error("Two-factor authentication enrollment not started for this user")
+	ErrInvalidTwoFactorCode    = Error("Invalid two-factor authentication code")
+)
+
+// APIKey errors.
+const (
+	ErrAPIKeyNotFound = Error("API key not found")
+	ErrAPIKeyExpired  = Error("API key has expired")
 )
 
 // Team errors.
@@ -42,10 +56,34 @@ const (
 	ErrEndpointAccessDenied = Error("Access denied to endpoint")
 )
 
+// Agent errors.
+const (
+	ErrAgentTunnelNotConnected = Error("Agent tunnel is not connected")
+	ErrEndpointNotAgent        = Error("Endpoint is not an agent endpoint")
+)
+
+// SSH errors.
+const (
+	ErrSSHKeyNotFound        = Error("SSH private key not found")
+	ErrSSHKnownHostsNotFound = Error("SSH known hosts file not found")
+)
+
+// Endpoint group errors.
+const (
+	ErrEndpointGroupNotFound        = Error("Endpoint group not found")
+	ErrDefaultEndpointGroupDeletion = Error("Cannot remove the default endpoint group")
+)
+
 // Registry errors.
 const (
-	ErrRegistryNotFound      = Error("Registry not found")
-	ErrRegistryAlreadyExists = Error("A registry is already defined for this URL")
+	ErrRegistryNotFound             = Error("Registry not found")
+	ErrRegistryAlreadyExists        = Error("A registry is already defined for this URL")
+	ErrRegistryResourceNotFound     = Error("Repository or manifest not found in the registry")
+	ErrRegistryAuthenticationFailed = Error("Unable to authenticate against the registry")
+	ErrRegistryDeletionNotSupported = Error("The registry does not allow the deletion of manifests")
+	ErrRegistryUnexpectedResponse   = Error("Unexpected response from the registry")
+	ErrRegistryInvalidURL           = Error("Invalid registry URL")
+	ErrRegistryAPINotSupported      = Error("The URL does not serve the Docker Registry HTTP API v2")
 )
 
 // Version errors.
@@ -65,7 +103,11 @@ const (
 
 // Crypto errors.
 const (
-	ErrCryptoHashFailure = Error("Unable to hash data")
+	ErrCryptoHashFailure     = Error("Unable to hash data")
+	ErrEncryptionKeyTooShort = Error("The master key must contain at least 32 characters")
+	ErrEncryptionKeyRequired = Error("A master key is required to decrypt the data")
+	ErrEncryptionKeyMismatch = Error("The data has been encrypted with a different master key")
+	ErrInvalidEncryptedData  = Error("Unable to decrypt the data")
 )
 
 // JWT errors.
@@ -73,11 +115,39 @@ const (
 	ErrSecretGeneration   = Error("Unable to generate secret key")
 	ErrInvalidJWTToken    = Error("Invalid JWT token")
 	ErrMissingContextData = Error("Unable to find JWT data in request context")
+	ErrSecretNotFound     = Error("JWT secret not found")
+	ErrRevokedJWTToken    = Error("Revoked JWT token")
+)
+
+// Token revocation errors.
+const (
+	ErrTokenRevocationNotFound = Error("Token revocation not found")
+)
+
+// LDAP errors.
+const (
+	ErrLDAPUserNotFound = Error("User not found in LDAP directory")
+	ErrLDAPSyncDisabled = Error("LDAP group synchronization is not enabled")
+)
+
+// OAuth errors.
+const (
+	ErrOAuthInvalidState           = Error("Invalid or expired OAuth state")
+	ErrOAuthInvalidIDToken         = Error("Invalid OAuth ID token")
+	ErrOAuthUserIdentifierNotFound = Error("Unable to find the user identifier in the OAuth claims")
+)
+
+// Audit errors.
+const (
+	ErrInvalidAuditSink   = Error("Invalid audit sink configuration")
+	ErrAuditFileIsSymlink = Error("Audit log file is a symbolic link")
 )
 
 // File errors.
 const (
-	ErrUndefinedTLSFileType = Error("Undefined TLS file type")
+	ErrUndefinedTLSFileType  = Error("Undefined TLS file type")
+	ErrUndefinedSSHFileType  = Error("Undefined SSH file type")
+	ErrExecRecordingNotFound = Error("Exec recording not found")
 )
 
 // Error represents an application error.
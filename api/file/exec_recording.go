@@ -0,0 +1,250 @@
+package file
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/portainer/portainer"
+)
+
+const (
+	// ExecRecordingStorePath represents the subfolder where the exec session recordings are stored in the file store folder.
+	ExecRecordingStorePath = "recordings"
+	// execRecordingFileExtension represents the extension of the asciicast files.
+	execRecordingFileExtension = ".cast"
+	// asciicastVersion represents the version of the asciicast format used for the recordings.
+	asciicastVersion = 2
+	// Default terminal size, the actual size is recorded by the resize events.
+	asciicastDefaultWidth  = 80
+	asciicastDefaultHeight = 24
+)
+
+type (
+	// asciicastHeader represents the first line of an asciicast file, the recording metadata are stored in a custom field.
+	// Format reference: https://github.com/asciinema/asciinema/blob/develop/doc/asciicast-v2.md
+	asciicastHeader struct {
+		Version   int                      `json:"version"`
+		Width     int                      `json:"width"`
+		Height    int                      `json:"height"`
+		Timestamp int64                    `json:"timestamp"`
+		Title     string                   `json:"title"`
+		Recording *portainer.ExecRecording `json:"portainer"`
+	}
+
+	// execRecorder writes the events of an exec session to an asciicast file.
+	// It implements the portainer.ExecRecorder interface.
+	execRecorder struct {
+		mu            sync.Mutex
+		file          *os.File
+		encoder       *json.Encoder
+		startedAt     time.Time
+		pendingInput  []byte
+		pendingOutput []byte
+	}
+)
+
+// CreateExecRecording creates the asciicast file associated to a recording and returns a recorder writing to it.
+func (service *Service) CreateExecRecording(recording *portainer.ExecRecording) (portainer.ExecRecorder, error) {
+	if !isValidExecRecordingID(recording.ID) {
+		return nil, portainer.ErrExecRecordingNotFound
+	}
+
+	startedAt := time.Now()
+	recording.StartedAt = startedAt.Unix()
+
+	recordingPath := path.Join(service.fileStorePath, ExecRecordingStorePath, recording.ID+execRecordingFileExtension)
+	file, err := os.OpenFile(recordingPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return nil, err
+	}
+
+	recorder := &execRecorder{
+		file:      file,
+		encoder:   json.NewEncoder(file),
+		startedAt: startedAt,
+	}
+
+	header := &asciicastHeader{
+		Version:   asciicastVersion,
+		Width:     asciicastDefaultWidth,
+		Height:    asciicastDefaultHeight,
+		Timestamp: recording.StartedAt,
+		Title:     recording.Username + "@" + recording.ContainerID,
+		Recording: recording,
+	}
+
+	err = recorder.encoder.Encode(header)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return recorder, nil
+}
+
+// ExecRecordings returns the recordings available in the file store, latest first.
+func (service *Service) ExecRecordings() ([]portainer.ExecRecording, error) {
+	storePath := path.Join(service.fileStorePath, ExecRecordingStorePath)
+	files, err := ioutil.ReadDir(storePath)
+	if err != nil {
+		return nil, err
+	}
+
+	recordings := make([]portainer.ExecRecording, 0)
+	for _, info := range files {
+		if info.IsDir() || !strings.HasSuffix(info.Name(), execRecordingFileExtension) {
+			continue
+		}
+
+		recording, err := readExecRecording(path.Join(storePath, info.Name()))
+		if err != nil {
+			// Files which are not valid recordings are ignored.
+			continue
+		}
+		recording.ID = strings.TrimSuffix(info.Name(), execRecordingFileExtension)
+		recording.Size = info.Size()
+		recordings = append(recordings, *recording)
+	}
+
+	sort.Slice(recordings, func(i, j int) bool {
+		return recordings[i].StartedAt > recordings[j].StartedAt
+	})
+	return recordings, nil
+}
+
+// GetPathForExecRecording returns the absolute path to the asciicast file of a recording.
+func (service *Service) GetPathForExecRecording(ID string) (string, error) {
+	if !isValidExecRecordingID(ID) {
+		return "", portainer.ErrExecRecordingNotFound
+	}
+
+	recordingPath := path.Join(service.fileStorePath, ExecRecordingStorePath, ID+execRecordingFileExtension)
+	_, err := os.Stat(recordingPath)
+	if os.IsNotExist(err) {
+		return "", portainer.ErrExecRecordingNotFound
+	} else if err != nil {
+		return "", err
+	}
+	return recordingPath, nil
+}
+
+// DeleteExecRecording deletes the asciicast file of a recording.
+func (service *Service) DeleteExecRecording(ID string) error {
+	recordingPath, err := service.GetPathForExecRecording(ID)
+	if err != nil {
+		return err
+	}
+	return os.Remove(recordingPath)
+}
+
+// RecordInput records data sent to the exec session.
+func (recorder *execRecorder) RecordInput(data []byte) error {
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+	return recorder.recordData("i", &recorder.pendingInput, data)
+}
+
+// RecordOutput records data received from the exec session.
+func (recorder *execRecorder) RecordOutput(data []byte) error {
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+	return recorder.recordData("o", &recorder.pendingOutput, data)
+}
+
+// RecordResize records a change of the terminal size.
+func (recorder *execRecorder) RecordResize(width, height int) error {
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+	return recorder.writeEvent("r", fmt.Sprintf("%dx%d", width, height))
+}
+
+// Close flushes the incomplete characters left in the streams and closes the asciicast file.
+func (recorder *execRecorder) Close() error {
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+
+	if len(recorder.pendingInput) > 0 {
+		recorder.writeEvent("i", string(recorder.pendingInput))
+	}
+	if len(recorder.pendingOutput) > 0 {
+		recorder.writeEvent("o", string(recorder.pendingOutput))
+	}
+	return recorder.file.Close()
+}
+
+// recordData writes an event containing data. Event data must be valid UTF-8, an incomplete character
+// at the end of data is kept in pending until the rest of the character is received.
+func (recorder *execRecorder) recordData(eventType string, pending *[]byte, data []byte) error {
+	data = append(*pending, data...)
+	complete := len(data) - incompleteRuneLength(data)
+	*pending = append([]byte(nil), data[complete:]...)
+	if complete == 0 {
+		return nil
+	}
+	return recorder.writeEvent(eventType, string(data[:complete]))
+}
+
+// writeEvent writes an event line, the time of the event is expressed in seconds since the beginning of the session.
+func (recorder *execRecorder) writeEvent(eventType, data string) error {
+	elapsed := time.Since(recorder.startedAt).Seconds()
+	return recorder.encoder.Encode([]interface{}{elapsed, eventType, data})
+}
+
+// readExecRecording reads the recording metadata from the header of an asciicast file.
+func readExecRecording(recordingPath string) (*portainer.ExecRecording, error) {
+	file, err := os.Open(recordingPath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	line, err := bufio.NewReader(file).ReadBytes('\n')
+	if err != nil {
+		return nil, err
+	}
+
+	var header asciicastHeader
+	err = json.Unmarshal(line, &header)
+	if err != nil {
+		return nil, err
+	}
+	if header.Recording == nil {
+		return nil, portainer.ErrExecRecordingNotFound
+	}
+	return header.Recording, nil
+}
+
+// incompleteRuneLength returns the length of the incomplete UTF-8 encoded character at the end of data.
+func incompleteRuneLength(data []byte) int {
+	for i := 1; i <= utf8.UTFMax && i <= len(data); i++ {
+		if !utf8.RuneStart(data[len(data)-i]) {
+			continue
+		}
+		if utf8.FullRune(data[len(data)-i:]) {
+			return 0
+		}
+		return i
+	}
+	return 0
+}
+
+// isValidExecRecordingID returns true when the identifier can safely be used as a file name.
+func isValidExecRecordingID(ID string) bool {
+	if ID == "" {
+		return false
+	}
+	for _, c := range ID {
+		if (c < 'a' || c > 'z') && (c < 'A' || c > 'Z') && (c < '0' || c > '9') {
+			return false
+		}
+	}
+	return true
+}
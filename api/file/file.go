@@ -2,11 +2,14 @@ package file
 
 import (
 	"github.com/portainer/portainer"
+	"github.com/portainer/portainer/crypto"
 
+	"bytes"
 	"io"
+	"io/ioutil"
 	"os"
 	"path"
-	"strconv"
+	"path/filepath"
 )
 
 const (
@@ -18,20 +21,32 @@ const (
 	TLSCertFile = "cert.pem"
 	// TLSKeyFile represents the name on disk for a TLS key file.
 	TLSKeyFile = "key.pem"
+	// LDAPStorePath represents the subfolder of the TLSStorePath where the LDAP TLS files are stored.
+	LDAPStorePath = "ldap"
+	// SSHStorePath represents the subfolder where SSH files are stored in the file store folder.
+	SSHStorePath = "ssh"
+	// SSHKeyFile represents the name on disk for an SSH private key file.
+	SSHKeyFile = "id_key"
+	// SSHKnownHostsFile represents the name on disk for an SSH known hosts file.
+	SSHKnownHostsFile = "known_hosts"
+	// AuditLogStorePath represents the subfolder where the file audit sinks write in the file store folder.
+	AuditLogStorePath = "audit"
 )
 
 // Service represents a service for managing files and directories.
 type Service struct {
-	dataStorePath string
-	fileStorePath string
+	dataStorePath     string
+	fileStorePath     string
+	encryptionService portainer.EncryptionService
 }
 
 // NewService initializes a new service. It creates a data directory and a directory to store files
-// inside this directory if they don't exist.
-func NewService(dataStorePath, fileStorePath string) (*Service, error) {
+// inside this directory if they don't exist. The TLS and SSH keys are encrypted when an encryption service is specified.
+func NewService(dataStorePath, fileStorePath string, encryptionService portainer.EncryptionService) (*Service, error) {
 	service := &Service{
-		dataStorePath: dataStorePath,
-		fileStorePath: path.Join(dataStorePath, fileStorePath),
+		dataStorePath:     dataStorePath,
+		fileStorePath:     path.Join(dataStorePath, fileStorePath),
+		encryptionService: encryptionService,
 	}
 
 	// Checking if a mount directory exists is broken with Go on Windows.
@@ -47,14 +62,34 @@ func NewService(dataStorePath, fileStorePath string) (*Service, error) {
 		return nil, err
 	}
 
+	err = service.createDirectoryInStoreIfNotExist(SSHStorePath)
+	if err != nil {
+		return nil, err
+	}
+
+	err = service.createDirectoryInStoreIfNotExist(ExecRecordingStorePath)
+	if err != nil {
+		return nil, err
+	}
+
+	err = service.createDirectoryInStoreIfNotExist(AuditLogStorePath)
+	if err != nil {
+		return nil, err
+	}
+
 	return service, nil
 }
 
-// StoreTLSFile creates a subfolder in the TLSStorePath and stores a new file with the content from r.
-func (service *Service) StoreTLSFile(endpointID portainer.EndpointID, fileType portainer.TLSFileType, r io.Reader) error {
-	ID := strconv.Itoa(int(endpointID))
-	endpointStorePath := path.Join(TLSStorePath, ID)
-	err := service.createDirectoryInStoreIfNotExist(endpointStorePath)
+// GetAuditLogStorePath returns the absolute path to the folder where the file audit sinks write.
+func (service *Service) GetAuditLogStorePath() string {
+	return path.Join(service.fileStorePath, AuditLogStorePath)
+}
+
+// StoreTLSFile creates a folder in the TLSStorePath and stores a new file with the content from r.
+// A TLS key is encrypted when the service has an encryption service.
+func (service *Service) StoreTLSFile(folder string, fileType portainer.TLSFileType, r io.Reader) error {
+	storePath := path.Join(TLSStorePath, folder)
+	err := service.createDirectoryInStoreIfNotExist(storePath)
 	if err != nil {
 		return err
 	}
@@ -71,7 +106,14 @@ func (service *Service) StoreTLSFile(endpointID portainer.EndpointID, fileType p
 		return portainer.ErrUndefinedTLSFileType
 	}
 
-	tlsFilePath := path.Join(endpointStorePath, fileName)
+	if fileType == portainer.TLSFileKey {
+		r, err = service.encryptKey(r)
+		if err != nil {
+			return err
+		}
+	}
+
+	tlsFilePath := path.Join(storePath, fileName)
 	err = service.createFileInStore(tlsFilePath, r)
 	if err != nil {
 		return err
@@ -79,8 +121,8 @@ func (service *Service) StoreTLSFile(endpointID portainer.EndpointID, fileType p
 	return nil
 }
 
-// GetPathForTLSFile returns the absolute path to a specific TLS file for an endpoint.
-func (service *Service) GetPathForTLSFile(endpointID portainer.EndpointID, fileType portainer.TLSFileType) (string, error) {
+// GetPathForTLSFile returns the absolute path to a specific TLS file stored in a folder.
+func (service *Service) GetPathForTLSFile(folder string, fileType portainer.TLSFileType) (string, error) {
 	var fileName string
 	switch fileType {
 	case portainer.TLSFileCA:
@@ -92,21 +134,141 @@ func (service *Service) GetPathForTLSFile(endpointID portainer.EndpointID, fileT
 	default:
 		return "", portainer.ErrUndefinedTLSFileType
 	}
-	ID := strconv.Itoa(int(endpointID))
-	return path.Join(service.fileStorePath, TLSStorePath, ID, fileName), nil
+	return path.Join(service.fileStorePath, TLSStorePath, folder, fileName), nil
 }
 
-// DeleteTLSFiles deletes a folder containing the TLS files for an endpoint.
-func (service *Service) DeleteTLSFiles(endpointID portainer.EndpointID) error {
-	ID := strconv.Itoa(int(endpointID))
-	endpointPath := path.Join(service.fileStorePath, TLSStorePath, ID)
-	err := os.RemoveAll(endpointPath)
+// EncryptKeyFiles encrypts the TLS keys and the SSH private keys with an encryption service, the encryption
+// service is used to store the keys afterwards. The keys are decrypted with the current encryption service,
+// keys already encrypted with the new encryption service are left unchanged.
+func (service *Service) EncryptKeyFiles(encryptionService portainer.EncryptionService) error {
+	keyFilePaths, err := filepath.Glob(path.Join(service.fileStorePath, TLSStorePath, "*", TLSKeyFile))
+	if err != nil {
+		return err
+	}
+
+	sshKeyFilePaths, err := filepath.Glob(path.Join(service.fileStorePath, SSHStorePath, "*", SSHKeyFile))
 	if err != nil {
 		return err
 	}
+	keyFilePaths = append(keyFilePaths, sshKeyFilePaths...)
+
+	for _, keyFilePath := range keyFilePaths {
+		data, err := ioutil.ReadFile(keyFilePath)
+		if err != nil {
+			return err
+		}
+
+		if crypto.IsEncrypted(data) {
+			_, err = encryptionService.Decrypt(data)
+			if err == nil {
+				continue
+			}
+			if service.encryptionService == nil {
+				return portainer.ErrEncryptionKeyRequired
+			}
+
+			data, err = service.encryptionService.Decrypt(data)
+			if err != nil {
+				return err
+			}
+		}
+
+		encrypted, err := encryptionService.Encrypt(data)
+		if err != nil {
+			return err
+		}
+
+		// The key is written in a temporary file first so that it is never left truncated.
+		temporaryFilePath := keyFilePath + ".tmp"
+		err = ioutil.WriteFile(temporaryFilePath, encrypted, 0600)
+		if err != nil {
+			return err
+		}
+
+		err = os.Rename(temporaryFilePath, keyFilePath)
+		if err != nil {
+			return err
+		}
+	}
+
+	service.encryptionService = encryptionService
 	return nil
 }
 
+// DeleteTLSFiles deletes a folder containing TLS files.
+func (service *Service) DeleteTLSFiles(folder string) error {
+	storePath := path.Join(service.fileStorePath, TLSStorePath, folder)
+	err := os.RemoveAll(storePath)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// StoreSSHFile creates a folder in the SSHStorePath and stores a new file with the content from r.
+// An SSH private key is encrypted when the service has an encryption service.
+func (service *Service) StoreSSHFile(folder string, fileType portainer.SSHFileType, r io.Reader) error {
+	storePath := path.Join(SSHStorePath, folder)
+	err := service.createDirectoryInStoreIfNotExist(storePath)
+	if err != nil {
+		return err
+	}
+
+	var fileName string
+	switch fileType {
+	case portainer.SSHFileKey:
+		fileName = SSHKeyFile
+		r, err = service.encryptKey(r)
+		if err != nil {
+			return err
+		}
+	case portainer.SSHFileKnownHosts:
+		fileName = SSHKnownHostsFile
+	default:
+		return portainer.ErrUndefinedSSHFileType
+	}
+
+	return service.createFileInStore(path.Join(storePath, fileName), r)
+}
+
+// GetPathForSSHFile returns the absolute path to a specific SSH file stored in a folder.
+func (service *Service) GetPathForSSHFile(folder string, fileType portainer.SSHFileType) (string, error) {
+	var fileName string
+	switch fileType {
+	case portainer.SSHFileKey:
+		fileName = SSHKeyFile
+	case portainer.SSHFileKnownHosts:
+		fileName = SSHKnownHostsFile
+	default:
+		return "", portainer.ErrUndefinedSSHFileType
+	}
+	return path.Join(service.fileStorePath, SSHStorePath, folder, fileName), nil
+}
+
+// DeleteSSHFiles deletes a folder containing SSH files.
+func (service *Service) DeleteSSHFiles(folder string) error {
+	storePath := path.Join(service.fileStorePath, SSHStorePath, folder)
+	return os.RemoveAll(storePath)
+}
+
+// encryptKey returns a reader on the encrypted content of r when the service has an encryption service.
+func (service *Service) encryptKey(r io.Reader) (io.Reader, error) {
+	if service.encryptionService == nil {
+		return r, nil
+	}
+
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	encrypted, err := service.encryptionService.Encrypt(data)
+	if err != nil {
+		return nil, err
+	}
+	return bytes.NewReader(encrypted), nil
+}
+
 // createDirectoryInStoreIfNotExist creates a new directory in the file store if it doesn't exists on the file system.
 func (service *Service) createDirectoryInStoreIfNotExist(name string) error {
 	path := path.Join(service.fileStorePath, name)
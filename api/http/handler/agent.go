@@ -0,0 +1,116 @@
+package handler
+
+import (
+	"github.com/portainer/portainer"
+	"github.com/portainer/portainer/agent"
+	"github.com/portainer/portainer/crypto"
+	httperror "github.com/portainer/portainer/http/error"
+	"github.com/portainer/portainer/http/security"
+
+	"crypto/subtle"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// AgentHandler represents an HTTP API handler for the tunnels opened by the agents of the endpoints.
+type AgentHandler struct {
+	*mux.Router
+	Logger          *log.Logger
+	EndpointService portainer.EndpointService
+	TunnelService   portainer.TunnelService
+}
+
+const (
+	// ErrInvalidTunnelUpgrade defines an error raised when a tunnel request does not ask for the agent protocol
+	ErrInvalidTunnelUpgrade = portainer.Error("Invalid tunnel upgrade request")
+)
+
+// NewAgentHandler returns a new instance of AgentHandler.
+func NewAgentHandler(bouncer *security.RequestBouncer) *AgentHandler {
+	h := &AgentHandler{
+		Router: mux.NewRouter(),
+		Logger: log.New(os.Stderr, "", log.LstdFlags),
+	}
+	h.Handle("/agents/tunnel",
+		bouncer.PublicAccess(http.HandlerFunc(h.handleGetAgentTunnel))).Methods(http.MethodGet)
+
+	return h
+}
+
+// handleGetAgentTunnel handles GET requests on /agents/tunnel
+// Agents authenticate with the key of their endpoint, the connection is then upgraded and used as the tunnel
+// of the endpoint until it is closed.
+func (handler *AgentHandler) handleGetAgentTunnel(w http.ResponseWriter, r *http.Request) {
+	if !strings.EqualFold(r.Header.Get("Upgrade"), agent.TunnelProtocol) {
+		httperror.WriteErrorResponse(w, ErrInvalidTunnelUpgrade, http.StatusBadRequest, handler.Logger)
+		return
+	}
+
+	endpoint, err := handler.authenticateAgent(r.Header.Get(agent.KeyHeader))
+	if err == portainer.ErrUnauthorized {
+		httperror.WriteErrorResponse(w, err, http.StatusUnauthorized, handler.Logger)
+		return
+	} else if err != nil {
+		httperror.WriteErrorResponse(w, err, http.StatusInternalServerError, handler.Logger)
+		return
+	}
+
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		httperror.WriteErrorResponse(w, security.ErrHijackNotSupported, http.StatusInternalServerError, handler.Logger)
+		return
+	}
+
+	conn, rw, err := hijacker.Hijack()
+	if err != nil {
+		httperror.WriteErrorResponse(w, err, http.StatusInternalServerError, handler.Logger)
+		return
+	}
+
+	_, err = conn.Write([]byte("HTTP/1.1 101 Switching Protocols\r\nUpgrade: " + agent.TunnelProtocol + "\r\nConnection: Upgrade\r\n\r\n"))
+	if err != nil {
+		conn.Close()
+		return
+	}
+
+	// The agent may already have started the HTTP/2 connection, the bytes read by the server
+	// must not be lost.
+	var tunnelConn net.Conn = conn
+	if rw.Reader.Buffered() > 0 {
+		tunnelConn = &hijackedConn{Conn: conn, reader: rw.Reader}
+	}
+
+	err = handler.TunnelService.Serve(endpoint, tunnelConn, r.RemoteAddr, r.Header.Get(agent.VersionHeader))
+	if err != nil {
+		handler.Logger.Printf("Unable to open the tunnel of endpoint %s: %s", endpoint.Name, err)
+	}
+}
+
+// authenticateAgent returns the agent endpoint associated to an agent key.
+func (handler *AgentHandler) authenticateAgent(key string) (*portainer.Endpoint, error) {
+	if key == "" {
+		return nil, portainer.ErrUnauthorized
+	}
+
+	endpoints, err := handler.EndpointService.Endpoints()
+	if err != nil {
+		return nil, err
+	}
+
+	digest := crypto.HashAgentKey(key)
+	for idx := range endpoints {
+		endpoint := &endpoints[idx]
+		if endpoint.AgentKeyDigest == "" || !isAgentEndpointURL(endpoint.URL) {
+			continue
+		}
+		if subtle.ConstantTimeCompare([]byte(endpoint.AgentKeyDigest), []byte(digest)) == 1 {
+			return endpoint, nil
+		}
+	}
+	return nil, portainer.ErrUnauthorized
+}
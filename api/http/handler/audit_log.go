@@ -0,0 +1,191 @@
+package handler
+
+import (
+	"github.com/portainer/portainer"
+	httperror "github.com/portainer/portainer/http/error"
+	"github.com/portainer/portainer/http/security"
+
+	"encoding/csv"
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// AuditLogHandler represents an HTTP API handler for managing audit logs.
+type AuditLogHandler struct {
+	*mux.Router
+	Logger          *log.Logger
+	AuditLogService portainer.AuditLogService
+}
+
+const (
+	// ErrInvalidAuditLogExportFormat defines an error raised when the format of an audit log export is not supported
+	ErrInvalidAuditLogExportFormat = portainer.Error("Unsupported audit log export format")
+	// defaultAuditLogLimit is the number of audit logs returned when no limit is specified.
+	defaultAuditLogLimit = 100
+	// maxAuditLogLimit is the maximum number of audit logs returned in a single page.
+	maxAuditLogLimit = 1000
+)
+
+// NewAuditLogHandler returns a new instance of AuditLogHandler.
+func NewAuditLogHandler(bouncer *security.RequestBouncer) *AuditLogHandler {
+	h := &AuditLogHandler{
+		Router: mux.NewRouter(),
+		Logger: log.New(os.Stderr, "", log.LstdFlags),
+	}
+	h.Handle("/audit_logs",
+		bouncer.AdministratorAccess(http.HandlerFunc(h.handleGetAuditLogs))).Methods(http.MethodGet)
+	h.Handle("/audit_logs/export",
+		bouncer.AdministratorAccess(http.HandlerFunc(h.handleGetAuditLogsExport))).Methods(http.MethodGet)
+
+	return h
+}
+
+type getAuditLogsResponse struct {
+	AuditLogs  []portainer.AuditLog `json:"AuditLogs"`
+	TotalCount int                  `json:"TotalCount"`
+}
+
+// handleGetAuditLogs handles GET requests on /audit_logs?start=<start>&limit=<limit>
+// Audit logs can be filtered with the userId, endpointId, method, resourceId, status, since and until query parameters.
+func (handler *AuditLogHandler) handleGetAuditLogs(w http.ResponseWriter, r *http.Request) {
+	query, err := parseAuditLogQuery(r.URL.Query())
+	if err != nil {
+		httperror.WriteErrorResponse(w, ErrInvalidQueryFormat, http.StatusBadRequest, handler.Logger)
+		return
+	}
+
+	if query.Limit == 0 {
+		query.Limit = defaultAuditLogLimit
+	} else if query.Limit > maxAuditLogLimit {
+		query.Limit = maxAuditLogLimit
+	}
+
+	auditLogs, count, err := handler.AuditLogService.AuditLogs(query)
+	if err != nil {
+		httperror.WriteErrorResponse(w, err, http.StatusInternalServerError, handler.Logger)
+		return
+	}
+
+	encodeJSON(w, &getAuditLogsResponse{AuditLogs: auditLogs, TotalCount: count}, handler.Logger)
+}
+
+// handleGetAuditLogsExport handles GET requests on /audit_logs/export?format=<jsonl|csv>
+// Every audit log matching the filters is exported, pagination parameters are ignored.
+func (handler *AuditLogHandler) handleGetAuditLogsExport(w http.ResponseWriter, r *http.Request) {
+	query, err := parseAuditLogQuery(r.URL.Query())
+	if err != nil {
+		httperror.WriteErrorResponse(w, ErrInvalidQueryFormat, http.StatusBadRequest, handler.Logger)
+		return
+	}
+	query.Start = 0
+	query.Limit = 0
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "jsonl"
+	}
+	if format != "jsonl" && format != "csv" {
+		httperror.WriteErrorResponse(w, ErrInvalidAuditLogExportFormat, http.StatusBadRequest, handler.Logger)
+		return
+	}
+
+	auditLogs, _, err := handler.AuditLogService.AuditLogs(query)
+	if err != nil {
+		httperror.WriteErrorResponse(w, err, http.StatusInternalServerError, handler.Logger)
+		return
+	}
+
+	w.Header().Set("Content-Disposition", "attachment; filename=\"audit_logs."+format+"\"")
+	if format == "csv" {
+		w.Header().Set("Content-Type", "text/csv")
+		err = writeAuditLogsCSV(w, auditLogs)
+	} else {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		err = writeAuditLogsJSONLines(w, auditLogs)
+	}
+	if err != nil {
+		handler.Logger.Printf("Unable to export audit logs: %s", err)
+	}
+}
+
+// parseAuditLogQuery builds an audit log query from the request query parameters.
+func parseAuditLogQuery(values url.Values) (*portainer.AuditLogQuery, error) {
+	query := &portainer.AuditLogQuery{
+		Method:     strings.ToUpper(values.Get("method")),
+		ResourceID: values.Get("resourceId"),
+	}
+
+	parameters := map[string]func(value int64){
+		"userId":     func(value int64) { query.UserID = portainer.UserID(value) },
+		"endpointId": func(value int64) { query.EndpointID = portainer.EndpointID(value) },
+		"status":     func(value int64) { query.Status = int(value) },
+		"since":      func(value int64) { query.Since = value },
+		"until":      func(value int64) { query.Until = value },
+		"start":      func(value int64) { query.Start = int(value) },
+		"limit":      func(value int64) { query.Limit = int(value) },
+	}
+
+	for name, set := range parameters {
+		value := values.Get(name)
+		if value == "" {
+			continue
+		}
+
+		parsedValue, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || parsedValue < 0 {
+			return nil, ErrInvalidQueryFormat
+		}
+		set(parsedValue)
+	}
+
+	return query, nil
+}
+
+// writeAuditLogsJSONLines writes the audit logs in the JSON lines format, one JSON object per line.
+func writeAuditLogsJSONLines(w http.ResponseWriter, auditLogs []portainer.AuditLog) error {
+	encoder := json.NewEncoder(w)
+	for _, auditLog := range auditLogs {
+		err := encoder.Encode(auditLog)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeAuditLogsCSV writes the audit logs in the CSV format, preceded by a header line.
+func writeAuditLogsCSV(w http.ResponseWriter, auditLogs []portainer.AuditLog) error {
+	writer := csv.NewWriter(w)
+	err := writer.Write([]string{"Id", "Timestamp", "UserId", "Username", "EndpointId", "Method", "Path", "ResourceId", "Status", "RemoteAddr"})
+	if err != nil {
+		return err
+	}
+
+	for _, auditLog := range auditLogs {
+		err = writer.Write([]string{
+			strconv.Itoa(int(auditLog.ID)),
+			strconv.FormatInt(auditLog.Timestamp, 10),
+			strconv.Itoa(int(auditLog.UserID)),
+			auditLog.Username,
+			strconv.Itoa(int(auditLog.EndpointID)),
+			auditLog.Method,
+			auditLog.Path,
+			auditLog.ResourceID,
+			strconv.Itoa(auditLog.Status),
+			auditLog.RemoteAddr,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
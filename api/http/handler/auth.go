@@ -3,10 +3,19 @@ package handler
 import (
 	"github.com/portainer/portainer"
 
+	"crypto/rand"
+	"encoding/base64"
 	"encoding/json"
 	"log"
+	"math"
+	"net"
 	"net/http"
+	"net/url"
 	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/asaskevich/govalidator"
 	"github.com/gorilla/mux"
@@ -17,11 +26,19 @@ import (
 // AuthHandler represents an HTTP API handler for managing authentication.
 type AuthHandler struct {
 	*mux.Router
-	Logger        *log.Logger
-	authDisabled  bool
-	UserService   portainer.UserService
-	CryptoService portainer.CryptoService
-	JWTService    portainer.JWTService
+	Logger                *log.Logger
+	authDisabled          bool
+	UserService           portainer.UserService
+	CryptoService         portainer.CryptoService
+	JWTService            portainer.JWTService
+	LDAPService           portainer.LDAPService
+	OAuthService          portainer.OAuthService
+	SettingsService       portainer.SettingsService
+	TeamMembershipService portainer.TeamMembershipService
+	LoginLimiter          *security.LoginLimiter
+	oauthStates           *oauthStateStore
+	dummyHashOnce         sync.Once
+	dummyHash             string
 }
 
 const (
@@ -32,6 +49,20 @@ const (
 	// ErrAuthDisabled is an error raised when trying to access the authentication endpoints
 	// when the server has been started with the --no-auth flag
 	ErrAuthDisabled = portainer.Error("Authentication is disabled")
+	// ErrOAuthDisabled is an error raised when trying to use the OAuth login flow
+	// while OAuth is not the configured authentication method
+	ErrOAuthDisabled = portainer.Error("OAuth authentication is not enabled")
+)
+
+const (
+	// oauthStateCookieName is the name of the cookie binding an OAuth login flow to the browser that started it.
+	oauthStateCookieName = "portainer_oauth_state"
+	// oauthStateTimeout is the time allowed to the user to authenticate against the OAuth provider.
+	oauthStateTimeout = 10 * time.Minute
+	// oauthCallbackPath is the path of the OAuth callback, relative to the URL the application is served from.
+	oauthCallbackPath = "api/auth/oauth/callback"
+	// oauthLoginURI is the path starting an OAuth login flow, relative to the URL the application is served from.
+	oauthLoginURI = "api/auth/oauth/login"
 )
 
 // NewAuthHandler returns a new instance of AuthHandler.
@@ -40,9 +71,26 @@ func NewAuthHandler(bouncer *security.RequestBouncer, authDisabled bool) *AuthHa
 		Router:       mux.NewRouter(),
 		Logger:       log.New(os.Stderr, "", log.LstdFlags),
 		authDisabled: authDisabled,
+		oauthStates:  newOAuthStateStore(),
 	}
 	h.Handle("/auth",
 		bouncer.PublicAccess(http.HandlerFunc(h.handlePostAuth)))
+	h.Handle("/auth/2fa",
+		bouncer.PublicAccess(http.HandlerFunc(h.handlePostTwoFactorAuth))).Methods(http.MethodPost)
+	h.Handle("/auth/2fa/enroll",
+		bouncer.PublicAccess(http.HandlerFunc(h.handlePostTwoFactorEnroll))).Methods(http.MethodPost)
+	h.Handle("/auth/lockouts",
+		bouncer.AdministratorAccess(http.HandlerFunc(h.handleGetLockouts))).Methods(http.MethodGet)
+	h.Handle("/auth/lockouts/ip/{ip}",
+		bouncer.AdministratorAccess(http.HandlerFunc(h.handleDeleteIPLockout))).Methods(http.MethodDelete)
+	h.Handle("/auth/logout",
+		bouncer.AuthenticatedAccess(http.HandlerFunc(h.handlePostLogout))).Methods(http.MethodPost)
+	h.Handle("/auth/secret/rotate",
+		bouncer.AdministratorAccess(http.HandlerFunc(h.handlePostSecretRotate))).Methods(http.MethodPost)
+	h.Handle("/auth/oauth/login",
+		bouncer.PublicAccess(http.HandlerFunc(h.handleGetOAuthLogin))).Methods(http.MethodGet)
+	h.Handle("/auth/oauth/callback",
+		bouncer.PublicAccess(http.HandlerFunc(h.handleGetOAuthCallback))).Methods(http.MethodGet)
 
 	return h
 }
@@ -72,22 +120,390 @@ func (handler *AuthHandler) handlePostAuth(w http.ResponseWriter, r *http.Reques
 
 	var username = req.Username
 	var password = req.Password
+	var clientIP = requestClientIP(r)
+
+	// The audit log of the request records the username and the client IP of every authentication attempt.
+	security.SetAuditLogUsername(r, username)
+
+	settings, err := handler.SettingsService.Settings()
+	if err != nil {
+		httperror.WriteErrorResponse(w, err, http.StatusInternalServerError, handler.Logger)
+		return
+	}
+
+	if delay := handler.LoginLimiter.Delay(username, clientIP); delay > 0 {
+		writeTooManyLoginAttemptsResponse(w, delay, handler.Logger)
+		return
+	}
 
 	u, err := handler.UserService.UserByUsername(username)
+	if err != nil && err != portainer.ErrUserNotFound {
+		httperror.WriteErrorResponse(w, err, http.StatusInternalServerError, handler.Logger)
+		return
+	}
+
+	if u != nil && u.LockedUntil > time.Now().Unix() {
+		// A locked account is handled like an invalid password, after the same hash comparison,
+		// so that it cannot be distinguished from an unknown user.
+		handler.CryptoService.CompareHashAndData(handler.dummyPasswordHash(), password)
+		err = ErrInvalidCredentials
+	} else {
+		u, err = handler.authenticate(u, username, password, settings)
+	}
+
+	if err == ErrInvalidCredentials {
+		handler.Logger.Printf("Failed authentication attempt [username: %s] [ip: %s]", username, clientIP)
+
+		lockedUntil := handler.LoginLimiter.RecordFailure(username, clientIP, &settings.LoginRateLimitSettings)
+		if !lockedUntil.IsZero() {
+			handler.Logger.Printf("Authentication locked until %s [username: %s]", lockedUntil.Format(time.RFC3339), username)
+			err = handler.lockUser(username, lockedUntil)
+			if err != nil {
+				httperror.WriteErrorResponse(w, err, http.StatusInternalServerError, handler.Logger)
+				return
+			}
+		}
+
+		// Unknown users and invalid passwords must not be distinguishable.
+		httperror.WriteErrorResponse(w, ErrInvalidCredentials, http.StatusUnprocessableEntity, handler.Logger)
+		return
+	} else if err != nil {
+		httperror.WriteErrorResponse(w, err, http.StatusInternalServerError, handler.Logger)
+		return
+	}
+
+	// The failed attempts are only reset once the second factor is accepted, otherwise logging in
+	// with the password would reset the attempts used to guess the two-factor codes.
+	enrollmentRequired := !u.TOTPEnabled && settings.EnforceTwoFactorForAdmins && u.Role == portainer.AdministratorRole
+	if u.TOTPEnabled || enrollmentRequired {
+		handler.writeTwoFactorToken(w, u, enrollmentRequired)
+		return
+	}
+
+	err = handler.completeLogin(u)
+	if err != nil {
+		httperror.WriteErrorResponse(w, err, http.StatusInternalServerError, handler.Logger)
+		return
+	}
+
+	handler.writeToken(w, u)
+}
+
+// handlePostTwoFactorAuth handles POST requests on /auth/2fa.
+// It exchanges a two-factor token and a TOTP or recovery code against a JWT.
+// Users completing an enforced enrollment must use a TOTP code, which enables the two-factor authentication.
+func (handler *AuthHandler) handlePostTwoFactorAuth(w http.ResponseWriter, r *http.Request) {
+	if handler.authDisabled {
+		httperror.WriteErrorResponse(w, ErrAuthDisabled, http.StatusServiceUnavailable, handler.Logger)
+		return
+	}
+
+	var req postTwoFactorAuthRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httperror.WriteErrorResponse(w, ErrInvalidJSON, http.StatusBadRequest, handler.Logger)
+		return
+	}
+
+	_, err := govalidator.ValidateStruct(req)
+	if err != nil || (req.Code == "" && req.RecoveryCode == "") {
+		httperror.WriteErrorResponse(w, ErrInvalidCredentialsFormat, http.StatusBadRequest, handler.Logger)
+		return
+	}
+
+	tokenData, err := handler.JWTService.ParseAndVerifyTwoFactorToken(req.Token)
+	if err != nil {
+		httperror.WriteErrorResponse(w, err, http.StatusUnauthorized, handler.Logger)
+		return
+	}
+	security.SetAuditLogUsername(r, tokenData.Username)
+
+	settings, err := handler.SettingsService.Settings()
+	if err != nil {
+		httperror.WriteErrorResponse(w, err, http.StatusInternalServerError, handler.Logger)
+		return
+	}
+
+	clientIP := requestClientIP(r)
+	if delay := handler.LoginLimiter.Delay(tokenData.Username, clientIP); delay > 0 {
+		writeTooManyLoginAttemptsResponse(w, delay, handler.Logger)
+		return
+	}
+
+	u, err := handler.UserService.User(tokenData.ID)
 	if err == portainer.ErrUserNotFound {
-		httperror.WriteErrorResponse(w, ErrInvalidCredentials, http.StatusBadRequest, handler.Logger)
+		httperror.WriteErrorResponse(w, ErrInvalidCredentials, http.StatusUnauthorized, handler.Logger)
 		return
 	} else if err != nil {
 		httperror.WriteErrorResponse(w, err, http.StatusInternalServerError, handler.Logger)
 		return
 	}
 
-	err = handler.CryptoService.CompareHashAndData(u.Password, password)
+	if u.TOTPSecret == "" {
+		httperror.WriteErrorResponse(w, portainer.ErrTwoFactorNotEnrolled, http.StatusBadRequest, handler.Logger)
+		return
+	}
+
+	// A locked account is handled like an invalid code so that the codes cannot be guessed while it is locked.
+	if u.LockedUntil > time.Now().Unix() || !validateTwoFactorCode(u, req.Code, req.RecoveryCode, handler.CryptoService) {
+		handler.Logger.Printf("Failed two-factor authentication attempt [username: %s] [ip: %s]", u.Username, clientIP)
+
+		lockedUntil := handler.LoginLimiter.RecordFailure(u.Username, clientIP, &settings.LoginRateLimitSettings)
+		if !lockedUntil.IsZero() {
+			handler.Logger.Printf("Authentication locked until %s [username: %s]", lockedUntil.Format(time.RFC3339), u.Username)
+			err = handler.lockUser(u.Username, lockedUntil)
+			if err != nil {
+				httperror.WriteErrorResponse(w, err, http.StatusInternalServerError, handler.Logger)
+				return
+			}
+		}
+
+		httperror.WriteErrorResponse(w, portainer.ErrInvalidTwoFactorCode, http.StatusUnprocessableEntity, handler.Logger)
+		return
+	}
+
+	u.TOTPEnabled = true
+	err = handler.completeLogin(u)
 	if err != nil {
-		httperror.WriteErrorResponse(w, ErrInvalidCredentials, http.StatusUnprocessableEntity, handler.Logger)
+		httperror.WriteErrorResponse(w, err, http.StatusInternalServerError, handler.Logger)
+		return
+	}
+
+	handler.writeToken(w, u)
+}
+
+type postTwoFactorAuthRequest struct {
+	Token        string `valid:"required"`
+	Code         string `valid:"-"`
+	RecoveryCode string `valid:"-"`
+}
+
+// handlePostTwoFactorEnroll handles POST requests on /auth/2fa/enroll.
+// It starts the enrollment of a user that must use two-factor authentication before being able to log in.
+func (handler *AuthHandler) handlePostTwoFactorEnroll(w http.ResponseWriter, r *http.Request) {
+	if handler.authDisabled {
+		httperror.WriteErrorResponse(w, ErrAuthDisabled, http.StatusServiceUnavailable, handler.Logger)
+		return
+	}
+
+	var req postTwoFactorEnrollRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httperror.WriteErrorResponse(w, ErrInvalidJSON, http.StatusBadRequest, handler.Logger)
+		return
+	}
+
+	_, err := govalidator.ValidateStruct(req)
+	if err != nil {
+		httperror.WriteErrorResponse(w, ErrInvalidRequestFormat, http.StatusBadRequest, handler.Logger)
+		return
+	}
+
+	tokenData, err := handler.JWTService.ParseAndVerifyTwoFactorToken(req.Token)
+	if err != nil {
+		httperror.WriteErrorResponse(w, err, http.StatusUnauthorized, handler.Logger)
+		return
+	}
+
+	u, err := handler.UserService.User(tokenData.ID)
+	if err == portainer.ErrUserNotFound {
+		httperror.WriteErrorResponse(w, ErrInvalidCredentials, http.StatusUnauthorized, handler.Logger)
+		return
+	} else if err != nil {
+		httperror.WriteErrorResponse(w, err, http.StatusInternalServerError, handler.Logger)
+		return
+	}
+
+	if u.TOTPEnabled {
+		httperror.WriteErrorResponse(w, portainer.ErrTwoFactorAlreadyEnabled, http.StatusConflict, handler.Logger)
+		return
+	}
+
+	enrollment, err := startTwoFactorEnrollment(u, handler.UserService, handler.CryptoService)
+	if err != nil {
+		httperror.WriteErrorResponse(w, err, http.StatusInternalServerError, handler.Logger)
+		return
+	}
+
+	encodeJSON(w, enrollment, handler.Logger)
+}
+
+type postTwoFactorEnrollRequest struct {
+	Token string `valid:"required"`
+}
+
+// writeTwoFactorToken generates a token only allowing the user to complete the two-factor authentication.
+func (handler *AuthHandler) writeTwoFactorToken(w http.ResponseWriter, u *portainer.User, enrollmentRequired bool) {
+	tokenData := &portainer.TokenData{
+		ID:       u.ID,
+		Username: u.Username,
+		Role:     u.Role,
+	}
+	token, err := handler.JWTService.GenerateTwoFactorToken(tokenData)
+	if err != nil {
+		httperror.WriteErrorResponse(w, err, http.StatusInternalServerError, handler.Logger)
+		return
+	}
+
+	encodeJSON(w, &postAuthTwoFactorResponse{TwoFactorToken: token, EnrollmentRequired: enrollmentRequired}, handler.Logger)
+}
+
+// authenticate checks the credentials of a user against the configured authentication method
+// and returns the authenticated user. It returns ErrInvalidCredentials when the authentication fails.
+func (handler *AuthHandler) authenticate(u *portainer.User, username, password string, settings *portainer.Settings) (*portainer.User, error) {
+	// The initial administrator account always authenticates against the internal database
+	// so that access to the instance can be recovered when the LDAP server is unavailable.
+	if settings.AuthenticationMethod == portainer.AuthenticationLDAP && (u == nil || u.ID != 1) {
+		err := handler.LDAPService.AuthenticateUser(username, password, &settings.LDAPSettings)
+		if err != nil {
+			return nil, ErrInvalidCredentials
+		}
+
+		if u == nil {
+			if !settings.LDAPSettings.AutoCreateUsers {
+				return nil, ErrInvalidCredentials
+			}
+
+			u = &portainer.User{
+				Username: username,
+				Role:     portainer.StandardUserRole,
+			}
+			err = handler.UserService.CreateUser(u)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return u, nil
+	}
+
+	if u == nil {
+		// Compare against a dummy hash so that unknown users take as long as invalid passwords.
+		handler.CryptoService.CompareHashAndData(handler.dummyPasswordHash(), password)
+		return nil, ErrInvalidCredentials
+	}
+
+	err := handler.CryptoService.CompareHashAndData(u.Password, password)
+	if err != nil {
+		return nil, ErrInvalidCredentials
+	}
+	return u, nil
+}
+
+// lockUser persists the lockout of a user account, if it exists.
+func (handler *AuthHandler) lockUser(username string, lockedUntil time.Time) error {
+	u, err := handler.UserService.UserByUsername(username)
+	if err == portainer.ErrUserNotFound {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	u.LockedUntil = lockedUntil.Unix()
+	return handler.UserService.UpdateUser(u.ID, u)
+}
+
+// completeLogin resets the failed attempts and the expired lock of a user once all the authentication
+// factors have been accepted, the user is saved.
+func (handler *AuthHandler) completeLogin(u *portainer.User) error {
+	handler.LoginLimiter.RecordSuccess(u.Username)
+	u.LockedUntil = 0
+	return handler.UserService.UpdateUser(u.ID, u)
+}
+
+// dummyPasswordHash returns a hash that is computed once and used to authenticate unknown users.
+func (handler *AuthHandler) dummyPasswordHash() string {
+	handler.dummyHashOnce.Do(func() {
+		hash, err := handler.CryptoService.Hash("portainer")
+		if err == nil {
+			handler.dummyHash = hash
+		}
+	})
+	return handler.dummyHash
+}
+
+// handleGetLockouts handles GET requests on /auth/lockouts
+func (handler *AuthHandler) handleGetLockouts(w http.ResponseWriter, r *http.Request) {
+	encodeJSON(w, handler.LoginLimiter.Lockouts(), handler.Logger)
+}
+
+// handleDeleteIPLockout handles DELETE requests on /auth/lockouts/ip/:ip
+func (handler *AuthHandler) handleDeleteIPLockout(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	ip := vars["ip"]
+
+	if net.ParseIP(ip) == nil {
+		httperror.WriteErrorResponse(w, ErrInvalidQueryFormat, http.StatusBadRequest, handler.Logger)
+		return
+	}
+
+	handler.LoginLimiter.UnlockIP(ip)
+}
+
+// requestClientIP returns the IP address of the client connected to the server.
+// Forwarding headers are ignored as they can be set by the client.
+func requestClientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+func writeTooManyLoginAttemptsResponse(w http.ResponseWriter, delay time.Duration, logger *log.Logger) {
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(delay.Seconds()))))
+	httperror.WriteErrorResponse(w, portainer.ErrTooManyLoginAttempts, http.StatusTooManyRequests, logger)
+}
+
+// handlePostLogout handles POST requests on /auth/logout.
+// It revokes the token used to authenticate the request.
+func (handler *AuthHandler) handlePostLogout(w http.ResponseWriter, r *http.Request) {
+	if handler.authDisabled {
+		httperror.WriteErrorResponse(w, ErrAuthDisabled, http.StatusServiceUnavailable, handler.Logger)
+		return
+	}
+
+	tokenData, err := security.RetrieveTokenData(r)
+	if err != nil {
+		httperror.WriteErrorResponse(w, err, http.StatusInternalServerError, handler.Logger)
+		return
+	}
+
+	err = handler.JWTService.RevokeToken(tokenData)
+	if err != nil {
+		httperror.WriteErrorResponse(w, err, http.StatusInternalServerError, handler.Logger)
+		return
+	}
+}
+
+// handlePostSecretRotate handles POST requests on /auth/secret/rotate.
+// All the existing tokens are invalidated, a new token is returned to the administrator.
+func (handler *AuthHandler) handlePostSecretRotate(w http.ResponseWriter, r *http.Request) {
+	if handler.authDisabled {
+		httperror.WriteErrorResponse(w, ErrAuthDisabled, http.StatusServiceUnavailable, handler.Logger)
 		return
 	}
 
+	tokenData, err := security.RetrieveTokenData(r)
+	if err != nil {
+		httperror.WriteErrorResponse(w, err, http.StatusInternalServerError, handler.Logger)
+		return
+	}
+
+	u, err := handler.UserService.User(tokenData.ID)
+	if err != nil {
+		httperror.WriteErrorResponse(w, err, http.StatusInternalServerError, handler.Logger)
+		return
+	}
+
+	err = handler.JWTService.RotateSecret()
+	if err != nil {
+		httperror.WriteErrorResponse(w, err, http.StatusInternalServerError, handler.Logger)
+		return
+	}
+
+	handler.writeToken(w, u)
+}
+
+// writeToken generates a JWT for the user and writes it in the response.
+func (handler *AuthHandler) writeToken(w http.ResponseWriter, u *portainer.User) {
 	tokenData := &portainer.TokenData{
 		ID:       u.ID,
 		Username: u.Username,
@@ -110,3 +526,326 @@ type postAuthRequest struct {
 type postAuthResponse struct {
 	JWT string `json:"jwt"`
 }
+
+type postAuthTwoFactorResponse struct {
+	TwoFactorToken     string `json:"twoFactorToken"`
+	EnrollmentRequired bool   `json:"enrollmentRequired"`
+}
+
+// handleGetOAuthLogin handles GET requests on /auth/oauth/login.
+// It redirects the user to the authorization endpoint of the OAuth provider.
+func (handler *AuthHandler) handleGetOAuthLogin(w http.ResponseWriter, r *http.Request) {
+	if handler.authDisabled {
+		httperror.WriteErrorResponse(w, ErrAuthDisabled, http.StatusServiceUnavailable, handler.Logger)
+		return
+	}
+
+	settings, err := handler.SettingsService.Settings()
+	if err != nil {
+		httperror.WriteErrorResponse(w, err, http.StatusInternalServerError, handler.Logger)
+		return
+	}
+
+	if settings.AuthenticationMethod != portainer.AuthenticationOAuth {
+		httperror.WriteErrorResponse(w, ErrOAuthDisabled, http.StatusServiceUnavailable, handler.Logger)
+		return
+	}
+
+	state, err := generateOAuthToken()
+	if err != nil {
+		httperror.WriteErrorResponse(w, err, http.StatusInternalServerError, handler.Logger)
+		return
+	}
+
+	nonce, err := generateOAuthToken()
+	if err != nil {
+		httperror.WriteErrorResponse(w, err, http.StatusInternalServerError, handler.Logger)
+		return
+	}
+
+	authorizationURL, err := handler.OAuthService.GetAuthorizationURL(state, nonce, &settings.OAuthSettings)
+	if err != nil {
+		httperror.WriteErrorResponse(w, err, http.StatusInternalServerError, handler.Logger)
+		return
+	}
+
+	handler.oauthStates.add(state, nonce)
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     oauthStateCookieName,
+		Value:    state,
+		Path:     "/api/auth/oauth",
+		MaxAge:   int(oauthStateTimeout.Seconds()),
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+	})
+	http.Redirect(w, r, authorizationURL, http.StatusFound)
+}
+
+// handleGetOAuthCallback handles GET requests on /auth/oauth/callback.
+// It exchanges the authorization code returned by the OAuth provider and redirects the user to the
+// application with a JWT in the URL fragment, so that the token is never sent back to the server.
+// Users that must use two-factor authentication receive a two-factor token instead.
+// Authentication failures are reported to the application the same way.
+func (handler *AuthHandler) handleGetOAuthCallback(w http.ResponseWriter, r *http.Request) {
+	if handler.authDisabled {
+		httperror.WriteErrorResponse(w, ErrAuthDisabled, http.StatusServiceUnavailable, handler.Logger)
+		return
+	}
+
+	settings, err := handler.SettingsService.Settings()
+	if err != nil {
+		httperror.WriteErrorResponse(w, err, http.StatusInternalServerError, handler.Logger)
+		return
+	}
+
+	applicationURL := oauthApplicationURL(settings.OAuthSettings.RedirectURI)
+
+	if settings.AuthenticationMethod != portainer.AuthenticationOAuth {
+		handler.redirectOAuthError(w, r, applicationURL, ErrOAuthDisabled)
+		return
+	}
+
+	if providerError := r.FormValue("error"); providerError != "" {
+		handler.Logger.Printf("OAuth provider error: %s", providerError)
+		handler.redirectOAuthError(w, r, applicationURL, ErrInvalidCredentials)
+		return
+	}
+
+	code := r.FormValue("code")
+	state := r.FormValue("state")
+	if code == "" || state == "" {
+		handler.redirectOAuthError(w, r, applicationURL, ErrInvalidQueryFormat)
+		return
+	}
+
+	cookie, err := r.Cookie(oauthStateCookieName)
+	if err != nil || cookie.Value != state {
+		handler.redirectOAuthError(w, r, applicationURL, portainer.ErrOAuthInvalidState)
+		return
+	}
+
+	nonce, ok := handler.oauthStates.consume(state)
+	if !ok {
+		handler.redirectOAuthError(w, r, applicationURL, portainer.ErrOAuthInvalidState)
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:   oauthStateCookieName,
+		Path:   "/api/auth/oauth",
+		MaxAge: -1,
+	})
+
+	identity, err := handler.OAuthService.Authenticate(code, nonce, &settings.OAuthSettings)
+	if err != nil {
+		handler.Logger.Printf("OAuth authentication error: %s", err)
+		handler.redirectOAuthError(w, r, applicationURL, ErrInvalidCredentials)
+		return
+	}
+
+	u, err := handler.UserService.UserByUsername(identity.Username)
+	if err != nil && err != portainer.ErrUserNotFound {
+		handler.Logger.Printf("Unable to retrieve user %s: %s", identity.Username, err)
+		handler.redirectOAuthError(w, r, applicationURL, portainer.ErrUnauthorized)
+		return
+	}
+
+	// Only the accounts managed by the OAuth provider can be signed into, so that the provider cannot
+	// be used to take over a local account. The initial administrator account is never managed by the provider.
+	if u != nil && (u.ID == 1 || !u.External || u.LockedUntil > time.Now().Unix()) {
+		handler.Logger.Printf("OAuth authentication refused for user %s", u.Username)
+		handler.redirectOAuthError(w, r, applicationURL, ErrInvalidCredentials)
+		return
+	}
+
+	if u == nil {
+		if !settings.OAuthSettings.AutoCreateUsers {
+			handler.redirectOAuthError(w, r, applicationURL, ErrInvalidCredentials)
+			return
+		}
+
+		u = &portainer.User{
+			Username: identity.Username,
+			Role:     portainer.StandardUserRole,
+			External: true,
+		}
+		err = handler.UserService.CreateUser(u)
+		if err != nil {
+			handler.Logger.Printf("Unable to create user %s: %s", identity.Username, err)
+			handler.redirectOAuthError(w, r, applicationURL, portainer.ErrUnauthorized)
+			return
+		}
+	}
+
+	err = handler.synchronizeOAuthTeams(u.ID, identity.Groups, settings.OAuthSettings.GroupTeamMappings)
+	if err != nil {
+		handler.Logger.Printf("Unable to synchronize the teams of user %s: %s", u.Username, err)
+		handler.redirectOAuthError(w, r, applicationURL, portainer.ErrUnauthorized)
+		return
+	}
+
+	tokenData := &portainer.TokenData{
+		ID:       u.ID,
+		Username: u.Username,
+		Role:     u.Role,
+	}
+
+	// The second factor is required the same way as after a password authentication.
+	enrollmentRequired := !u.TOTPEnabled && settings.EnforceTwoFactorForAdmins && u.Role == portainer.AdministratorRole
+	if u.TOTPEnabled || enrollmentRequired {
+		token, err := handler.JWTService.GenerateTwoFactorToken(tokenData)
+		if err != nil {
+			handler.Logger.Printf("Unable to generate a two-factor token for user %s: %s", u.Username, err)
+			handler.redirectOAuthError(w, r, applicationURL, portainer.ErrUnauthorized)
+			return
+		}
+
+		query := url.Values{}
+		query.Set("twoFactorToken", token)
+		query.Set("enrollmentRequired", strconv.FormatBool(enrollmentRequired))
+		http.Redirect(w, r, applicationURL+"#/auth/oauth?"+query.Encode(), http.StatusFound)
+		return
+	}
+
+	token, err := handler.JWTService.GenerateToken(tokenData)
+	if err != nil {
+		handler.Logger.Printf("Unable to generate a token for user %s: %s", u.Username, err)
+		handler.redirectOAuthError(w, r, applicationURL, portainer.ErrUnauthorized)
+		return
+	}
+
+	http.Redirect(w, r, applicationURL+"#/auth/oauth?token="+url.QueryEscape(token), http.StatusFound)
+}
+
+// redirectOAuthError redirects the user to the application with the error that ended the OAuth login flow.
+func (handler *AuthHandler) redirectOAuthError(w http.ResponseWriter, r *http.Request, applicationURL string, err error) {
+	http.Redirect(w, r, applicationURL+"#/auth/oauth?error="+url.QueryEscape(err.Error()), http.StatusFound)
+}
+
+// oauthApplicationURL returns the URL the application is served from, based on the redirect URI
+// registered with the OAuth provider. The application may be served under a sub-path by a reverse proxy.
+func oauthApplicationURL(redirectURI string) string {
+	if strings.HasSuffix(redirectURI, oauthCallbackPath) {
+		return strings.TrimSuffix(redirectURI, oauthCallbackPath)
+	}
+	return "/"
+}
+
+// synchronizeOAuthTeams creates and removes the memberships of the user so that they match the
+// group to team mappings. Memberships of teams that are not associated to any group are left untouched.
+func (handler *AuthHandler) synchronizeOAuthTeams(userID portainer.UserID, groups []string, mappings []portainer.OAuthGroupTeamMapping) error {
+	if len(mappings) == 0 {
+		return nil
+	}
+
+	userGroups := make(map[string]bool)
+	for _, group := range groups {
+		userGroups[group] = true
+	}
+
+	mappedTeams := make(map[portainer.TeamID]bool)
+	expectedTeams := make(map[portainer.TeamID]bool)
+	for _, mapping := range mappings {
+		mappedTeams[mapping.TeamID] = true
+		if userGroups[mapping.Group] {
+			expectedTeams[mapping.TeamID] = true
+		}
+	}
+
+	memberships, err := handler.TeamMembershipService.TeamMembershipsByUserID(userID)
+	if err != nil {
+		return err
+	}
+
+	currentTeams := make(map[portainer.TeamID]bool)
+	for _, membership := range memberships {
+		currentTeams[membership.TeamID] = true
+
+		if mappedTeams[membership.TeamID] && !expectedTeams[membership.TeamID] {
+			err = handler.TeamMembershipService.DeleteTeamMembership(membership.ID)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	for teamID := range expectedTeams {
+		if currentTeams[teamID] {
+			continue
+		}
+
+		membership := &portainer.TeamMembership{
+			UserID: userID,
+			TeamID: teamID,
+			Role:   portainer.TeamMember,
+		}
+		err = handler.TeamMembershipService.CreateTeamMembership(membership)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// generateOAuthToken returns a random value suitable for the OAuth state and nonce parameters.
+func generateOAuthToken() (string, error) {
+	b := make([]byte, 32)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// oauthStateStore keeps track of the pending OAuth login flows and their nonce.
+type oauthStateStore struct {
+	mu     sync.Mutex
+	states map[string]oauthState
+}
+
+type oauthState struct {
+	nonce     string
+	expiresAt time.Time
+}
+
+func newOAuthStateStore() *oauthStateStore {
+	return &oauthStateStore{
+		states: make(map[string]oauthState),
+	}
+}
+
+func (store *oauthStateStore) add(state, nonce string) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	now := time.Now()
+	for key, value := range store.states {
+		if now.After(value.expiresAt) {
+			delete(store.states, key)
+		}
+	}
+
+	store.states[state] = oauthState{
+		nonce:     nonce,
+		expiresAt: now.Add(oauthStateTimeout),
+	}
+}
+
+// consume returns the nonce associated to a state and removes it, a state can only be used once.
+func (store *oauthStateStore) consume(state string) (string, bool) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	value, ok := store.states[state]
+	if !ok {
+		return "", false
+	}
+	delete(store.states, state)
+
+	if time.Now().After(value.expiresAt) {
+		return "", false
+	}
+	return value.nonce, true
+}
@@ -0,0 +1,210 @@
+package handler
+
+import (
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/portainer/portainer"
+)
+
+type stubSettingsService struct {
+	portainer.SettingsService
+	settings *portainer.Settings
+}
+
+func (service *stubSettingsService) Settings() (*portainer.Settings, error) {
+	settings := *service.settings
+	return &settings, nil
+}
+
+type stubOAuthService struct {
+	code     string
+	identity *portainer.OAuthIdentity
+}
+
+func (service *stubOAuthService) GetAuthorizationURL(state, nonce string, settings *portainer.OAuthSettings) (string, error) {
+	return settings.AuthorizationURI + "?state=" + state + "&nonce=" + nonce, nil
+}
+
+func (service *stubOAuthService) Authenticate(code, nonce string, settings *portainer.OAuthSettings) (*portainer.OAuthIdentity, error) {
+	if code != service.code {
+		return nil, portainer.ErrOAuthInvalidIDToken
+	}
+	return service.identity, nil
+}
+
+type stubUserService struct {
+	portainer.UserService
+	users []portainer.User
+}
+
+func (service *stubUserService) UserByUsername(username string) (*portainer.User, error) {
+	for idx := range service.users {
+		if service.users[idx].Username == username {
+			return &service.users[idx], nil
+		}
+	}
+	return nil, portainer.ErrUserNotFound
+}
+
+func (service *stubUserService) CreateUser(user *portainer.User) error {
+	user.ID = portainer.UserID(len(service.users) + 1)
+	service.users = append(service.users, *user)
+	return nil
+}
+
+type stubJWTService struct {
+	portainer.JWTService
+}
+
+func (service *stubJWTService) GenerateToken(data *portainer.TokenData) (string, error) {
+	return "token-" + data.Username, nil
+}
+
+func (service *stubJWTService) GenerateTwoFactorToken(data *portainer.TokenData) (string, error) {
+	return "2fa-token-" + data.Username, nil
+}
+
+func newOAuthTestHandler(autoCreateUsers bool, users ...portainer.User) *AuthHandler {
+	return &AuthHandler{
+		Logger: log.New(ioutil.Discard, "", 0),
+		SettingsService: &stubSettingsService{settings: &portainer.Settings{
+			AuthenticationMethod: portainer.AuthenticationOAuth,
+			OAuthSettings: portainer.OAuthSettings{
+				AuthorizationURI: "https://provider.example.com/authorize",
+				RedirectURI:      "https://portainer.example.com/portainer/api/auth/oauth/callback",
+				AutoCreateUsers:  autoCreateUsers,
+			},
+		}},
+		OAuthService: &stubOAuthService{
+			code:     "code",
+			identity: &portainer.OAuthIdentity{Username: "user@example.com"},
+		},
+		UserService: &stubUserService{users: append([]portainer.User{{ID: 1, Username: "admin"}}, users...)},
+		JWTService:  &stubJWTService{},
+		oauthStates: newOAuthStateStore(),
+	}
+}
+
+// startOAuthLogin starts a login flow and returns the state sent to the provider and the state cookie.
+func startOAuthLogin(t *testing.T, handler *AuthHandler) (string, *http.Cookie) {
+	w := httptest.NewRecorder()
+	handler.handleGetOAuthLogin(w, httptest.NewRequest(http.MethodGet, "/api/auth/oauth/login", nil))
+	if w.Code != http.StatusFound {
+		t.Fatalf("Unexpected login status: %d", w.Code)
+	}
+
+	location, err := url.Parse(w.Header().Get("Location"))
+	if err != nil {
+		t.Fatalf("Invalid login redirection: %s", err)
+	}
+
+	cookies := w.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != oauthStateCookieName {
+		t.Fatalf("Missing OAuth state cookie")
+	}
+	return location.Query().Get("state"), cookies[0]
+}
+
+func oauthCallback(handler *AuthHandler, code, state string, cookie *http.Cookie) *httptest.ResponseRecorder {
+	query := url.Values{}
+	query.Set("code", code)
+	query.Set("state", state)
+	r := httptest.NewRequest(http.MethodGet, "/api/auth/oauth/callback?"+query.Encode(), nil)
+	if cookie != nil {
+		r.AddCookie(cookie)
+	}
+
+	w := httptest.NewRecorder()
+	handler.handleGetOAuthCallback(w, r)
+	return w
+}
+
+func TestOAuthCallbackRedirectsWithToken(t *testing.T) {
+	handler := newOAuthTestHandler(true)
+	state, cookie := startOAuthLogin(t, handler)
+
+	w := oauthCallback(handler, "code", state, cookie)
+	if w.Code != http.StatusFound {
+		t.Fatalf("Unexpected callback status: %d", w.Code)
+	}
+
+	expected := "https://portainer.example.com/portainer/#/auth/oauth?token=token-user%40example.com"
+	if location := w.Header().Get("Location"); location != expected {
+		t.Errorf("Unexpected callback redirection: %s", location)
+	}
+
+	// The state can only be used once.
+	w = oauthCallback(handler, "code", state, cookie)
+	if location := w.Header().Get("Location"); !strings.Contains(location, "#/auth/oauth?error=") {
+		t.Errorf("Unexpected redirection for a replayed state: %s", location)
+	}
+}
+
+func TestOAuthCallbackRedirectsWithError(t *testing.T) {
+	cases := []struct {
+		name            string
+		code            string
+		useCookie       bool
+		autoCreateUsers bool
+	}{
+		{name: "missing state cookie", code: "code", useCookie: false, autoCreateUsers: true},
+		{name: "invalid code", code: "invalid", useCookie: true, autoCreateUsers: true},
+		{name: "unknown user", code: "code", useCookie: true, autoCreateUsers: false},
+	}
+
+	for _, c := range cases {
+		handler := newOAuthTestHandler(c.autoCreateUsers)
+		state, cookie := startOAuthLogin(t, handler)
+		if !c.useCookie {
+			cookie = nil
+		}
+
+		w := oauthCallback(handler, c.code, state, cookie)
+		location := w.Header().Get("Location")
+		if w.Code != http.StatusFound || !strings.HasPrefix(location, "https://portainer.example.com/portainer/#/auth/oauth?error=") {
+			t.Errorf("%s: unexpected callback response: %d %s", c.name, w.Code, location)
+		}
+		if strings.Contains(location, "token=") {
+			t.Errorf("%s: a token was issued", c.name)
+		}
+	}
+}
+
+func TestOAuthCallbackRefusesLocalAccounts(t *testing.T) {
+	cases := []struct {
+		name string
+		user portainer.User
+	}{
+		{name: "local account", user: portainer.User{ID: 2, Username: "user@example.com", Role: portainer.AdministratorRole}},
+		{name: "locked account", user: portainer.User{ID: 2, Username: "user@example.com", External: true, LockedUntil: time.Now().Add(time.Hour).Unix()}},
+	}
+
+	for _, c := range cases {
+		handler := newOAuthTestHandler(true, c.user)
+		state, cookie := startOAuthLogin(t, handler)
+
+		w := oauthCallback(handler, "code", state, cookie)
+		location := w.Header().Get("Location")
+		if !strings.Contains(location, "#/auth/oauth?error=") || strings.Contains(location, "token=") {
+			t.Errorf("%s: unexpected callback redirection: %s", c.name, location)
+		}
+	}
+}
+
+func TestOAuthCallbackRequiresTheSecondFactor(t *testing.T) {
+	handler := newOAuthTestHandler(false, portainer.User{ID: 2, Username: "user@example.com", External: true, TOTPEnabled: true})
+	state, cookie := startOAuthLogin(t, handler)
+
+	w := oauthCallback(handler, "code", state, cookie)
+	expected := "https://portainer.example.com/portainer/#/auth/oauth?enrollmentRequired=false&twoFactorToken=2fa-token-user%40example.com"
+	if location := w.Header().Get("Location"); location != expected {
+		t.Errorf("Unexpected callback redirection: %s", location)
+	}
+}
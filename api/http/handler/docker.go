@@ -21,6 +21,7 @@ type DockerHandler struct {
 	Logger                *log.Logger
 	EndpointService       portainer.EndpointService
 	TeamMembershipService portainer.TeamMembershipService
+	EndpointGroupService  portainer.EndpointGroupService
 	ProxyManager          *proxy.Manager
 }
 
@@ -35,22 +36,14 @@ func NewDockerHandler(bouncer *security.RequestBouncer) *DockerHandler {
 	return h
 }
 
-func (handler *DockerHandler) checkEndpointAccessControl(endpoint *portainer.Endpoint, userID portainer.UserID) bool {
-	for _, authorizedUserID := range endpoint.AuthorizedUsers {
-		if authorizedUserID == userID {
-			return true
-		}
-	}
+// checkEndpointAccessControl checks that a user is authorized to access an endpoint,
+// either directly or through one of his teams, on the endpoint itself or on its group.
+func checkEndpointAccessControl(endpoint *portainer.Endpoint, userID portainer.UserID, teamMembershipService portainer.TeamMembershipService, endpointGroupService portainer.EndpointGroupService) bool {
+	memberships, _ := teamMembershipService.TeamMembershipsByUserID(userID)
 
-	memberships, _ := handler.TeamMembershipService.TeamMembershipsByUserID(userID)
-	for _, authorizedTeamID := range endpoint.AuthorizedTeams {
-		for _, membership := range memberships {
-			if membership.TeamID == authorizedTeamID {
-				return true
-			}
-		}
-	}
-	return false
+	group, _ := endpointGroupService.EndpointGroup(endpoint.GroupID)
+
+	return security.AuthorizedEndpointAccess(endpoint, group, userID, memberships)
 }
 
 func (handler *DockerHandler) proxyRequestsToDockerAPI(w http.ResponseWriter, r *http.Request) {
@@ -75,13 +68,13 @@ func (handler *DockerHandler) proxyRequestsToDockerAPI(w http.ResponseWriter, r
 		httperror.WriteErrorResponse(w, err, http.StatusInternalServerError, handler.Logger)
 		return
 	}
-	if tokenData.Role != portainer.AdministratorRole && !handler.checkEndpointAccessControl(endpoint, tokenData.ID) {
+	if tokenData.Role != portainer.AdministratorRole && !checkEndpointAccessControl(endpoint, tokenData.ID, handler.TeamMembershipService, handler.EndpointGroupService) {
 		httperror.WriteErrorResponse(w, portainer.ErrEndpointAccessDenied, http.StatusForbidden, handler.Logger)
 		return
 	}
 
 	var proxy http.Handler
-	proxy = handler.ProxyManager.GetProxy(string(endpointID))
+	proxy = handler.ProxyManager.GetProxy(strconv.Itoa(int(endpointID)))
 	if proxy == nil {
 		proxy, err = handler.ProxyManager.CreateAndRegisterProxy(endpoint)
 		if err != nil {
@@ -0,0 +1,298 @@
+package handler
+
+import (
+	"bufio"
+	"crypto/tls"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/portainer/portainer"
+	"github.com/portainer/portainer/crypto"
+)
+
+const (
+	// dockerStreamHeaderSize is the size of the header preceding each frame of a multiplexed Docker stream.
+	dockerStreamHeaderSize = 8
+	dockerClientTimeout    = 10 * time.Second
+)
+
+type (
+	// dockerClient is a minimal client used to query the Docker API of an endpoint outside of the Docker API proxy.
+	dockerClient struct {
+		transport *http.Transport
+		url       string
+	}
+
+	// hijackedConn is a connection hijacked from a Docker API request. Reads go through the buffered
+	// reader used to parse the response headers as it may already contain the beginning of the stream.
+	hijackedConn struct {
+		net.Conn
+		reader *bufio.Reader
+	}
+)
+
+func newDockerClient(endpoint *portainer.Endpoint, encryptionService portainer.EncryptionService, tunnelService portainer.TunnelService, sshService portainer.SSHService) (*dockerClient, error) {
+	endpointURL, err := url.Parse(endpoint.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	// The client is only used for a few requests, connections must not be kept open once they are done.
+	transport := &http.Transport{
+		DisableKeepAlives: true,
+	}
+	baseURL := "http://" + endpointURL.Host
+	if endpointURL.Scheme == "unix" {
+		socketPath := endpointURL.Path
+		transport.Dial = func(proto, addr string) (net.Conn, error) {
+			return net.Dial("unix", socketPath)
+		}
+		baseURL = "http://unixsocket"
+	} else if endpointURL.Scheme == "agent" {
+		endpointID := endpoint.ID
+		transport.Dial = func(proto, addr string) (net.Conn, error) {
+			return tunnelService.Dial(endpointID)
+		}
+		baseURL = "http://agent"
+	} else if endpointURL.Scheme == "ssh" {
+		transport.Dial = func(proto, addr string) (net.Conn, error) {
+			return sshService.Dial(endpoint)
+		}
+		baseURL = "http://sshsocket"
+	} else if endpoint.TLS {
+		tlsConfig, err := crypto.CreateTLSConfiguration(endpoint.TLSCACertPath, endpoint.TLSCertPath, endpoint.TLSKeyPath, encryptionService)
+		if err != nil {
+			return nil, err
+		}
+		transport.TLSClientConfig = tlsConfig
+		baseURL = "https://" + endpointURL.Host
+	}
+
+	return &dockerClient{
+		transport: transport,
+		url:       baseURL,
+	}, nil
+}
+
+// inspect executes a GET request on the Docker API and returns the response as a JSON object.
+func (c *dockerClient) inspect(path string) (map[string]interface{}, error) {
+	client := &http.Client{
+		Transport: c.transport,
+		Timeout:   dockerClientTimeout,
+	}
+
+	resp, err := client.Get(c.url + path)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	err = checkDockerResponse(resp)
+	if err != nil {
+		return nil, err
+	}
+
+	object := make(map[string]interface{})
+	err = json.NewDecoder(resp.Body).Decode(&object)
+	if err != nil {
+		return nil, err
+	}
+	return object, nil
+}
+
+// post executes a POST request without body on the Docker API.
+func (c *dockerClient) post(path string) error {
+	client := &http.Client{
+		Transport: c.transport,
+		Timeout:   dockerClientTimeout,
+	}
+
+	resp, err := client.Post(c.url+path, "application/json", nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	return checkDockerResponse(resp)
+}
+
+// stream executes a request on the Docker API and returns the response without any timeout
+// so that its body can be streamed. The caller must close the response body.
+func (c *dockerClient) stream(method, path string) (*http.Response, error) {
+	req, err := http.NewRequest(method, c.url+path, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	client := &http.Client{
+		Transport: c.transport,
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	err = checkDockerResponse(resp)
+	if err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+	return resp, nil
+}
+
+// hijackDockerRequest executes a request on the Docker API of an endpoint and returns the underlying connection
+// once Docker starts streaming, as it is done by the Docker client for the attach and exec operations.
+func hijackDockerRequest(endpoint *portainer.Endpoint, encryptionService portainer.EncryptionService, tunnelService portainer.TunnelService, sshService portainer.SSHService, method, path string, body io.Reader) (*hijackedConn, error) {
+	endpointURL, err := url.Parse(endpoint.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	var conn net.Conn
+	host := endpointURL.Host
+	if endpointURL.Scheme == "unix" {
+		host = "unixsocket"
+		conn, err = net.Dial("unix", endpointURL.Path)
+	} else if endpointURL.Scheme == "agent" {
+		host = "agent"
+		conn, err = tunnelService.Dial(endpoint.ID)
+	} else if endpointURL.Scheme == "ssh" {
+		host = "sshsocket"
+		conn, err = sshService.Dial(endpoint)
+	} else if endpoint.TLS {
+		var tlsConfig *tls.Config
+		tlsConfig, err = crypto.CreateTLSConfiguration(endpoint.TLSCACertPath, endpoint.TLSCertPath, endpoint.TLSKeyPath, encryptionService)
+		if err != nil {
+			return nil, err
+		}
+		conn, err = tls.Dial("tcp", endpointURL.Host, tlsConfig)
+	} else {
+		conn, err = net.Dial("tcp", endpointURL.Host)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Streams can stay inactive for a long time, TCP keep-alive prevents
+	// the connection from being dropped by the network.
+	if tcpConn, ok := conn.(*net.TCPConn); ok {
+		tcpConn.SetKeepAlive(true)
+		tcpConn.SetKeepAlivePeriod(30 * time.Second)
+	}
+
+	req, err := http.NewRequest(method, path, body)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	req.Host = host
+	req.Header.Set("User-Agent", "Docker-Client")
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "tcp")
+
+	err = req.Write(conn)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, req)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		err = checkDockerResponse(resp)
+		if err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+
+	return &hijackedConn{
+		Conn:   conn,
+		reader: reader,
+	}, nil
+}
+
+func (conn *hijackedConn) Read(p []byte) (int, error) {
+	return conn.reader.Read(p)
+}
+
+// CloseWrite closes the write side of the connection so that Docker receives the end of the input stream.
+func (conn *hijackedConn) CloseWrite() error {
+	if c, ok := conn.Conn.(interface {
+		CloseWrite() error
+	}); ok {
+		return c.CloseWrite()
+	}
+	return nil
+}
+
+// checkDockerResponse returns an error when the status of a Docker API response is not successful.
+func checkDockerResponse(resp *http.Response) error {
+	if resp.StatusCode == http.StatusNotFound {
+		return ErrDockerResourceNotFound
+	} else if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("Unable to query the Docker API (status: %d)", resp.StatusCode)
+	}
+	return nil
+}
+
+// copyDockerStream copies a Docker output stream to w. When the container is not using a TTY,
+// stdout and stderr are multiplexed in the stream and each frame header is removed.
+// Stream format reference: https://docs.docker.com/engine/api/v1.28/#operation/ContainerAttach
+func copyDockerStream(w io.Writer, r io.Reader, tty bool) error {
+	if tty {
+		_, err := io.Copy(w, r)
+		return err
+	}
+
+	header := make([]byte, dockerStreamHeaderSize)
+	for {
+		_, err := io.ReadFull(r, header)
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		frameSize := binary.BigEndian.Uint32(header[4:])
+		_, err = io.CopyN(w, r, int64(frameSize))
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// isTTYContainer returns true when the container has been created with a TTY.
+// Container schema reference: https://docs.docker.com/engine/api/v1.28/#operation/ContainerInspect
+func isTTYContainer(containerObject map[string]interface{}) bool {
+	containerConfigObject, ok := containerObject["Config"].(map[string]interface{})
+	if !ok {
+		return false
+	}
+	tty, _ := containerConfigObject["Tty"].(bool)
+	return tty
+}
+
+// isTTYExec returns true when the exec instance has been created with a TTY.
+// Exec schema reference: https://docs.docker.com/engine/api/v1.28/#operation/ExecInspect
+func isTTYExec(execObject map[string]interface{}) bool {
+	processConfigObject, ok := execObject["ProcessConfig"].(map[string]interface{})
+	if !ok {
+		return false
+	}
+	tty, _ := processConfigObject["tty"].(bool)
+	return tty
+}
@@ -44,6 +44,8 @@ func (handler *DockerHubHandler) handleGetDockerHub(w http.ResponseWriter, r *ht
 		return
 	}
 
+	dockerhub.Password = ""
+
 	encodeJSON(w, dockerhub, handler.Logger)
 	return
 }
@@ -62,6 +64,12 @@ func (handler *DockerHubHandler) handlePutDockerHub(w http.ResponseWriter, r *ht
 		return
 	}
 
+	storedDockerHub, err := handler.DockerHubService.DockerHub()
+	if err != nil {
+		httperror.WriteErrorResponse(w, err, http.StatusInternalServerError, handler.Logger)
+		return
+	}
+
 	dockerhub := &portainer.DockerHub{
 		Authentication: false,
 		Username:       "",
@@ -72,6 +80,10 @@ func (handler *DockerHubHandler) handlePutDockerHub(w http.ResponseWriter, r *ht
 		dockerhub.Authentication = true
 		dockerhub.Username = req.Username
 		dockerhub.Password = req.Password
+		// The stored password is kept when no password is specified.
+		if req.Password == "" {
+			dockerhub.Password = storedDockerHub.Password
+		}
 	}
 
 	err = handler.DockerHubService.StoreDockerHub(dockerhub)
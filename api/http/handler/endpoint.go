@@ -2,15 +2,19 @@ package handler
 
 import (
 	"github.com/portainer/portainer"
+	"github.com/portainer/portainer/crypto"
 	httperror "github.com/portainer/portainer/http/error"
 	"github.com/portainer/portainer/http/proxy"
 	"github.com/portainer/portainer/http/security"
+	"github.com/portainer/portainer/ssh"
 
 	"encoding/json"
 	"log"
 	"net/http"
 	"os"
+	"sort"
 	"strconv"
+	"strings"
 
 	"github.com/asaskevich/govalidator"
 	"github.com/gorilla/mux"
@@ -22,14 +26,26 @@ type EndpointHandler struct {
 	Logger                      *log.Logger
 	authorizeEndpointManagement bool
 	EndpointService             portainer.EndpointService
+	EndpointGroupService        portainer.EndpointGroupService
 	FileService                 portainer.FileService
 	ProxyManager                *proxy.Manager
+	TunnelService               portainer.TunnelService
+	SSHService                  portainer.SSHService
 }
 
 const (
 	// ErrEndpointManagementDisabled is an error raised when trying to access the endpoints management endpoints
 	// when the server has been started with the --external-endpoints flag
 	ErrEndpointManagementDisabled = portainer.Error("Endpoint management is disabled")
+	// ErrInvalidResourceOwnershipPolicy is an error raised when the resource ownership policy of an endpoint is not supported
+	ErrInvalidResourceOwnershipPolicy = portainer.Error("Unsupported resource ownership policy")
+	// ErrInvalidEndpointTag is an error raised when an endpoint tag is empty, too long or contains a comma
+	ErrInvalidEndpointTag = portainer.Error("Invalid endpoint tag")
+)
+
+const (
+	// maxEndpointTagLength is the maximum length of an endpoint tag.
+	maxEndpointTagLength = 128
 )
 
 // NewEndpointHandler returns a new instance of EndpointHandler.
@@ -43,12 +59,18 @@ func NewEndpointHandler(bouncer *security.RequestBouncer, authorizeEndpointManag
 		bouncer.AdministratorAccess(http.HandlerFunc(h.handlePostEndpoints))).Methods(http.MethodPost)
 	h.Handle("/endpoints",
 		bouncer.RestrictedAccess(http.HandlerFunc(h.handleGetEndpoints))).Methods(http.MethodGet)
+	h.Handle("/endpoints/tags",
+		bouncer.RestrictedAccess(http.HandlerFunc(h.handleGetEndpointTags))).Methods(http.MethodGet)
 	h.Handle("/endpoints/{id}",
 		bouncer.AdministratorAccess(http.HandlerFunc(h.handleGetEndpoint))).Methods(http.MethodGet)
 	h.Handle("/endpoints/{id}",
 		bouncer.AdministratorAccess(http.HandlerFunc(h.handlePutEndpoint))).Methods(http.MethodPut)
 	h.Handle("/endpoints/{id}/access",
 		bouncer.AdministratorAccess(http.HandlerFunc(h.handlePutEndpointAccess))).Methods(http.MethodPut)
+	h.Handle("/endpoints/{id}/tags",
+		bouncer.AdministratorAccess(http.HandlerFunc(h.handlePutEndpointTags))).Methods(http.MethodPut)
+	h.Handle("/endpoints/{id}/agent_key",
+		bouncer.AdministratorAccess(http.HandlerFunc(h.handlePostEndpointAgentKey))).Methods(http.MethodPost)
 	h.Handle("/endpoints/{id}",
 		bouncer.AdministratorAccess(http.HandlerFunc(h.handleDeleteEndpoint))).Methods(http.MethodDelete)
 
@@ -56,6 +78,7 @@ func NewEndpointHandler(bouncer *security.RequestBouncer, authorizeEndpointManag
 }
 
 // handleGetEndpoints handles GET requests on /endpoints
+// The tags query parameter is a comma-separated list of tags, only the endpoints having all the tags are returned.
 func (handler *EndpointHandler) handleGetEndpoints(w http.ResponseWriter, r *http.Request) {
 	securityContext, err := security.RetrieveRestrictedRequestContext(r)
 	if err != nil {
@@ -69,15 +92,79 @@ func (handler *EndpointHandler) handleGetEndpoints(w http.ResponseWriter, r *htt
 		return
 	}
 
-	filteredEndpoints, err := security.FilterEndpoints(endpoints, securityContext)
+	groups, err := handler.EndpointGroupService.EndpointGroups()
+	if err != nil {
+		httperror.WriteErrorResponse(w, err, http.StatusInternalServerError, handler.Logger)
+		return
+	}
+
+	filteredEndpoints, err := security.FilterEndpoints(endpoints, groups, securityContext)
 	if err != nil {
 		httperror.WriteErrorResponse(w, err, http.StatusInternalServerError, handler.Logger)
 		return
 	}
 
+	tags := parseEndpointTagsQuery(r.URL.Query().Get("tags"))
+	if len(tags) > 0 {
+		taggedEndpoints := make([]portainer.Endpoint, 0)
+		for _, endpoint := range filteredEndpoints {
+			if endpointHasTags(&endpoint, tags) {
+				taggedEndpoints = append(taggedEndpoints, endpoint)
+			}
+		}
+		filteredEndpoints = taggedEndpoints
+	}
+
+	for idx := range filteredEndpoints {
+		filteredEndpoints[idx].AgentKeyDigest = ""
+	}
+
 	encodeJSON(w, filteredEndpoints, handler.Logger)
 }
 
+// handleGetEndpointTags handles GET requests on /endpoints/tags
+// It returns the sorted list of the tags used by the endpoints accessible to the user.
+func (handler *EndpointHandler) handleGetEndpointTags(w http.ResponseWriter, r *http.Request) {
+	securityContext, err := security.RetrieveRestrictedRequestContext(r)
+	if err != nil {
+		httperror.WriteErrorResponse(w, err, http.StatusInternalServerError, handler.Logger)
+		return
+	}
+
+	endpoints, err := handler.EndpointService.Endpoints()
+	if err != nil {
+		httperror.WriteErrorResponse(w, err, http.StatusInternalServerError, handler.Logger)
+		return
+	}
+
+	groups, err := handler.EndpointGroupService.EndpointGroups()
+	if err != nil {
+		httperror.WriteErrorResponse(w, err, http.StatusInternalServerError, handler.Logger)
+		return
+	}
+
+	filteredEndpoints, err := security.FilterEndpoints(endpoints, groups, securityContext)
+	if err != nil {
+		httperror.WriteErrorResponse(w, err, http.StatusInternalServerError, handler.Logger)
+		return
+	}
+
+	tagSet := make(map[string]bool)
+	for _, endpoint := range filteredEndpoints {
+		for _, tag := range endpoint.Tags {
+			tagSet[tag] = true
+		}
+	}
+
+	tags := make([]string, 0, len(tagSet))
+	for tag := range tagSet {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+
+	encodeJSON(w, tags, handler.Logger)
+}
+
 // handlePostEndpoints handles POST requests on /endpoints
 func (handler *EndpointHandler) handlePostEndpoints(w http.ResponseWriter, r *http.Request) {
 	if !handler.authorizeEndpointManagement {
@@ -97,13 +184,47 @@ func (handler *EndpointHandler) handlePostEndpoints(w http.ResponseWriter, r *ht
 		return
 	}
 
+	if isSSHEndpointURL(req.URL) {
+		_, _, _, err = ssh.ParseEndpointURL(req.URL)
+		if err != nil {
+			httperror.WriteErrorResponse(w, err, http.StatusBadRequest, handler.Logger)
+			return
+		}
+	}
+
+	ownershipPolicy := portainer.PublicResourceOwnership
+	if req.ResourceOwnershipPolicy != 0 {
+		if !isValidResourceOwnershipPolicy(req.ResourceOwnershipPolicy) {
+			httperror.WriteErrorResponse(w, ErrInvalidResourceOwnershipPolicy, http.StatusBadRequest, handler.Logger)
+			return
+		}
+		ownershipPolicy = portainer.ResourceOwnershipPolicy(req.ResourceOwnershipPolicy)
+	}
+
+	tags, err := normalizeEndpointTags(req.Tags)
+	if err != nil {
+		httperror.WriteErrorResponse(w, err, http.StatusBadRequest, handler.Logger)
+		return
+	}
+
+	groupID := portainer.EndpointGroupID(portainer.DefaultEndpointGroupID)
+	if req.GroupID != 0 {
+		groupID = portainer.EndpointGroupID(req.GroupID)
+		if !handler.checkEndpointGroup(w, groupID) {
+			return
+		}
+	}
+
 	endpoint := &portainer.Endpoint{
-		Name:            req.Name,
-		URL:             req.URL,
-		PublicURL:       req.PublicURL,
-		TLS:             req.TLS,
-		AuthorizedUsers: []portainer.UserID{},
-		AuthorizedTeams: []portainer.TeamID{},
+		Name:                    req.Name,
+		GroupID:                 groupID,
+		Tags:                    tags,
+		URL:                     req.URL,
+		PublicURL:               req.PublicURL,
+		TLS:                     req.TLS,
+		AuthorizedUsers:         []portainer.UserID{},
+		AuthorizedTeams:         []portainer.TeamID{},
+		ResourceOwnershipPolicy: ownershipPolicy,
 	}
 
 	err = handler.EndpointService.CreateEndpoint(endpoint)
@@ -112,13 +233,22 @@ func (handler *EndpointHandler) handlePostEndpoints(w http.ResponseWriter, r *ht
 		return
 	}
 
-	if req.TLS {
-		caCertPath, _ := handler.FileService.GetPathForTLSFile(endpoint.ID, portainer.TLSFileCA)
-		endpoint.TLSCACertPath = caCertPath
-		certPath, _ := handler.FileService.GetPathForTLSFile(endpoint.ID, portainer.TLSFileCert)
-		endpoint.TLSCertPath = certPath
-		keyPath, _ := handler.FileService.GetPathForTLSFile(endpoint.ID, portainer.TLSFileKey)
-		endpoint.TLSKeyPath = keyPath
+	if req.TLS || isSSHEndpointURL(endpoint.URL) {
+		folder := strconv.Itoa(int(endpoint.ID))
+		if req.TLS {
+			caCertPath, _ := handler.FileService.GetPathForTLSFile(folder, portainer.TLSFileCA)
+			endpoint.TLSCACertPath = caCertPath
+			certPath, _ := handler.FileService.GetPathForTLSFile(folder, portainer.TLSFileCert)
+			endpoint.TLSCertPath = certPath
+			keyPath, _ := handler.FileService.GetPathForTLSFile(folder, portainer.TLSFileKey)
+			endpoint.TLSKeyPath = keyPath
+		}
+		if isSSHEndpointURL(endpoint.URL) {
+			sshKeyPath, _ := handler.FileService.GetPathForSSHFile(folder, portainer.SSHFileKey)
+			endpoint.SSHKeyPath = sshKeyPath
+			knownHostsPath, _ := handler.FileService.GetPathForSSHFile(folder, portainer.SSHFileKnownHosts)
+			endpoint.SSHKnownHostsPath = knownHostsPath
+		}
 		err = handler.EndpointService.UpdateEndpoint(endpoint.ID, endpoint)
 		if err != nil {
 			httperror.WriteErrorResponse(w, err, http.StatusInternalServerError, handler.Logger)
@@ -130,10 +260,13 @@ func (handler *EndpointHandler) handlePostEndpoints(w http.ResponseWriter, r *ht
 }
 
 type postEndpointsRequest struct {
-	Name      string `valid:"required"`
-	URL       string `valid:"required"`
-	PublicURL string `valid:"-"`
-	TLS       bool
+	Name                    string `valid:"required"`
+	URL                     string `valid:"required"`
+	PublicURL               string `valid:"-"`
+	TLS                     bool
+	ResourceOwnershipPolicy int      `valid:"-"`
+	GroupID                 int      `valid:"-"`
+	Tags                    []string `valid:"-"`
 }
 
 type postEndpointsResponse struct {
@@ -160,6 +293,7 @@ func (handler *EndpointHandler) handleGetEndpoint(w http.ResponseWriter, r *http
 		return
 	}
 
+	endpoint.AgentKeyDigest = ""
 	encodeJSON(w, endpoint, handler.Logger)
 }
 
@@ -264,7 +398,15 @@ func (handler *EndpointHandler) handlePutEndpoint(w http.ResponseWriter, r *http
 		endpoint.Name = req.Name
 	}
 
+	previousURL := endpoint.URL
 	if req.URL != "" {
+		if isSSHEndpointURL(req.URL) {
+			_, _, _, err = ssh.ParseEndpointURL(req.URL)
+			if err != nil {
+				httperror.WriteErrorResponse(w, err, http.StatusBadRequest, handler.Logger)
+				return
+			}
+		}
 		endpoint.URL = req.URL
 	}
 
@@ -272,20 +414,53 @@ func (handler *EndpointHandler) handlePutEndpoint(w http.ResponseWriter, r *http
 		endpoint.PublicURL = req.PublicURL
 	}
 
+	if req.GroupID != 0 {
+		groupID := portainer.EndpointGroupID(req.GroupID)
+		if !handler.checkEndpointGroup(w, groupID) {
+			return
+		}
+		endpoint.GroupID = groupID
+	}
+
+	if req.ResourceOwnershipPolicy != 0 {
+		if !isValidResourceOwnershipPolicy(req.ResourceOwnershipPolicy) {
+			httperror.WriteErrorResponse(w, ErrInvalidResourceOwnershipPolicy, http.StatusBadRequest, handler.Logger)
+			return
+		}
+		endpoint.ResourceOwnershipPolicy = portainer.ResourceOwnershipPolicy(req.ResourceOwnershipPolicy)
+	}
+
 	if req.TLS {
 		endpoint.TLS = true
-		caCertPath, _ := handler.FileService.GetPathForTLSFile(endpoint.ID, portainer.TLSFileCA)
+		folder := strconv.Itoa(int(endpoint.ID))
+		caCertPath, _ := handler.FileService.GetPathForTLSFile(folder, portainer.TLSFileCA)
 		endpoint.TLSCACertPath = caCertPath
-		certPath, _ := handler.FileService.GetPathForTLSFile(endpoint.ID, portainer.TLSFileCert)
+		certPath, _ := handler.FileService.GetPathForTLSFile(folder, portainer.TLSFileCert)
 		endpoint.TLSCertPath = certPath
-		keyPath, _ := handler.FileService.GetPathForTLSFile(endpoint.ID, portainer.TLSFileKey)
+		keyPath, _ := handler.FileService.GetPathForTLSFile(folder, portainer.TLSFileKey)
 		endpoint.TLSKeyPath = keyPath
 	} else {
 		endpoint.TLS = false
 		endpoint.TLSCACertPath = ""
 		endpoint.TLSCertPath = ""
 		endpoint.TLSKeyPath = ""
-		err = handler.FileService.DeleteTLSFiles(endpoint.ID)
+		err = handler.FileService.DeleteTLSFiles(strconv.Itoa(int(endpoint.ID)))
+		if err != nil {
+			httperror.WriteErrorResponse(w, err, http.StatusInternalServerError, handler.Logger)
+			return
+		}
+	}
+
+	if isSSHEndpointURL(endpoint.URL) {
+		folder := strconv.Itoa(int(endpoint.ID))
+		sshKeyPath, _ := handler.FileService.GetPathForSSHFile(folder, portainer.SSHFileKey)
+		endpoint.SSHKeyPath = sshKeyPath
+		knownHostsPath, _ := handler.FileService.GetPathForSSHFile(folder, portainer.SSHFileKnownHosts)
+		endpoint.SSHKnownHostsPath = knownHostsPath
+	} else if endpoint.SSHKeyPath != "" {
+		endpoint.SSHKeyPath = ""
+		endpoint.SSHKnownHostsPath = ""
+		err = handler.FileService.DeleteSSHFiles(strconv.Itoa(int(endpoint.ID)))
 		if err != nil {
 			httperror.WriteErrorResponse(w, err, http.StatusInternalServerError, handler.Logger)
 			return
@@ -303,13 +478,208 @@ func (handler *EndpointHandler) handlePutEndpoint(w http.ResponseWriter, r *http
 		httperror.WriteErrorResponse(w, err, http.StatusInternalServerError, handler.Logger)
 		return
 	}
+
+	if endpoint.URL != previousURL && isAgentEndpointURL(previousURL) {
+		handler.TunnelService.CloseTunnel(endpoint.ID)
+	}
+
+	// The SSH files may have been replaced before the update, the connection is established again
+	// on the next request.
+	if isSSHEndpointURL(previousURL) {
+		handler.SSHService.CloseConnection(endpoint.ID)
+	}
 }
 
 type putEndpointsRequest struct {
-	Name      string `valid:"-"`
-	URL       string `valid:"-"`
-	PublicURL string `valid:"-"`
-	TLS       bool   `valid:"-"`
+	Name                    string `valid:"-"`
+	URL                     string `valid:"-"`
+	PublicURL               string `valid:"-"`
+	TLS                     bool   `valid:"-"`
+	ResourceOwnershipPolicy int    `valid:"-"`
+	GroupID                 int    `valid:"-"`
+}
+
+// checkEndpointGroup checks that an endpoint group exists. An error response is written when it does not.
+func (handler *EndpointHandler) checkEndpointGroup(w http.ResponseWriter, groupID portainer.EndpointGroupID) bool {
+	_, err := handler.EndpointGroupService.EndpointGroup(groupID)
+	if err == portainer.ErrEndpointGroupNotFound {
+		httperror.WriteErrorResponse(w, err, http.StatusBadRequest, handler.Logger)
+		return false
+	} else if err != nil {
+		httperror.WriteErrorResponse(w, err, http.StatusInternalServerError, handler.Logger)
+		return false
+	}
+	return true
+}
+
+// handlePutEndpointTags handles PUT requests on /endpoints/:id/tags
+// The tags of the endpoint are replaced by the tags specified in the request.
+func (handler *EndpointHandler) handlePutEndpointTags(w http.ResponseWriter, r *http.Request) {
+	if !handler.authorizeEndpointManagement {
+		httperror.WriteErrorResponse(w, ErrEndpointManagementDisabled, http.StatusServiceUnavailable, handler.Logger)
+		return
+	}
+
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	endpointID, err := strconv.Atoi(id)
+	if err != nil {
+		httperror.WriteErrorResponse(w, err, http.StatusBadRequest, handler.Logger)
+		return
+	}
+
+	var req putEndpointTagsRequest
+	if err = json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httperror.WriteErrorResponse(w, ErrInvalidJSON, http.StatusBadRequest, handler.Logger)
+		return
+	}
+
+	tags, err := normalizeEndpointTags(req.Tags)
+	if err != nil {
+		httperror.WriteErrorResponse(w, err, http.StatusBadRequest, handler.Logger)
+		return
+	}
+
+	endpoint, err := handler.EndpointService.Endpoint(portainer.EndpointID(endpointID))
+	if err == portainer.ErrEndpointNotFound {
+		httperror.WriteErrorResponse(w, err, http.StatusNotFound, handler.Logger)
+		return
+	} else if err != nil {
+		httperror.WriteErrorResponse(w, err, http.StatusInternalServerError, handler.Logger)
+		return
+	}
+
+	endpoint.Tags = tags
+
+	err = handler.EndpointService.UpdateEndpoint(endpoint.ID, endpoint)
+	if err != nil {
+		httperror.WriteErrorResponse(w, err, http.StatusInternalServerError, handler.Logger)
+		return
+	}
+}
+
+type putEndpointTagsRequest struct {
+	Tags []string `valid:"-"`
+}
+
+// handlePostEndpointAgentKey handles POST requests on /endpoints/:id/agent_key
+// A new agent key is generated for an agent endpoint and returned, only its digest is stored.
+// The current tunnel of the endpoint is closed, the agent must reconnect with the new key.
+func (handler *EndpointHandler) handlePostEndpointAgentKey(w http.ResponseWriter, r *http.Request) {
+	if !handler.authorizeEndpointManagement {
+		httperror.WriteErrorResponse(w, ErrEndpointManagementDisabled, http.StatusServiceUnavailable, handler.Logger)
+		return
+	}
+
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	endpointID, err := strconv.Atoi(id)
+	if err != nil {
+		httperror.WriteErrorResponse(w, err, http.StatusBadRequest, handler.Logger)
+		return
+	}
+
+	endpoint, err := handler.EndpointService.Endpoint(portainer.EndpointID(endpointID))
+	if err == portainer.ErrEndpointNotFound {
+		httperror.WriteErrorResponse(w, err, http.StatusNotFound, handler.Logger)
+		return
+	} else if err != nil {
+		httperror.WriteErrorResponse(w, err, http.StatusInternalServerError, handler.Logger)
+		return
+	}
+
+	if !isAgentEndpointURL(endpoint.URL) {
+		httperror.WriteErrorResponse(w, portainer.ErrEndpointNotAgent, http.StatusBadRequest, handler.Logger)
+		return
+	}
+
+	key, err := crypto.GenerateAgentKey()
+	if err != nil {
+		httperror.WriteErrorResponse(w, err, http.StatusInternalServerError, handler.Logger)
+		return
+	}
+
+	endpoint.AgentKeyDigest = crypto.HashAgentKey(key)
+
+	err = handler.EndpointService.UpdateEndpoint(endpoint.ID, endpoint)
+	if err != nil {
+		httperror.WriteErrorResponse(w, err, http.StatusInternalServerError, handler.Logger)
+		return
+	}
+
+	handler.TunnelService.CloseTunnel(endpoint.ID)
+
+	encodeJSON(w, &postEndpointAgentKeyResponse{Key: key}, handler.Logger)
+}
+
+type postEndpointAgentKeyResponse struct {
+	Key string `json:"Key"`
+}
+
+// isAgentEndpointURL returns true when the URL is the URL of an endpoint reached through an agent tunnel.
+func isAgentEndpointURL(endpointURL string) bool {
+	return strings.HasPrefix(endpointURL, "agent://")
+}
+
+// isSSHEndpointURL returns true when the URL is the URL of an endpoint reached through SSH.
+func isSSHEndpointURL(endpointURL string) bool {
+	return strings.HasPrefix(endpointURL, "ssh://")
+}
+
+// normalizeEndpointTags trims the tags and removes the duplicates. Tags cannot be empty, contain a comma
+// or exceed the maximum tag length.
+func normalizeEndpointTags(tags []string) ([]string, error) {
+	normalizedTags := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		if tag == "" || len(tag) > maxEndpointTagLength || strings.Contains(tag, ",") {
+			return nil, ErrInvalidEndpointTag
+		}
+		if !containsTag(normalizedTags, tag) {
+			normalizedTags = append(normalizedTags, tag)
+		}
+	}
+	return normalizedTags, nil
+}
+
+// parseEndpointTagsQuery returns the tags contained in a comma-separated list.
+func parseEndpointTagsQuery(query string) []string {
+	tags := make([]string, 0)
+	for _, tag := range strings.Split(query, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+func endpointHasTags(endpoint *portainer.Endpoint, tags []string) bool {
+	for _, tag := range tags {
+		if !containsTag(endpoint.Tags, tag) {
+			return false
+		}
+	}
+	return true
+}
+
+func containsTag(tags []string, tag string) bool {
+	for _, t := range tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+func isValidResourceOwnershipPolicy(policy int) bool {
+	switch portainer.ResourceOwnershipPolicy(policy) {
+	case portainer.PublicResourceOwnership, portainer.PrivateResourceOwnership, portainer.TeamResourceOwnership:
+		return true
+	}
+	return false
 }
 
 // handleDeleteEndpoint handles DELETE requests on /endpoints/:id
@@ -338,7 +708,13 @@ func (handler *EndpointHandler) handleDeleteEndpoint(w http.ResponseWriter, r *h
 		return
 	}
 
-	handler.ProxyManager.DeleteProxy(string(endpointID))
+	handler.ProxyManager.DeleteProxy(strconv.Itoa(endpointID))
+	if isAgentEndpointURL(endpoint.URL) {
+		handler.TunnelService.CloseTunnel(endpoint.ID)
+	}
+	if isSSHEndpointURL(endpoint.URL) {
+		handler.SSHService.CloseConnection(endpoint.ID)
+	}
 
 	err = handler.EndpointService.DeleteEndpoint(portainer.EndpointID(endpointID))
 	if err != nil {
@@ -347,7 +723,15 @@ func (handler *EndpointHandler) handleDeleteEndpoint(w http.ResponseWriter, r *h
 	}
 
 	if endpoint.TLS {
-		err = handler.FileService.DeleteTLSFiles(portainer.EndpointID(endpointID))
+		err = handler.FileService.DeleteTLSFiles(strconv.Itoa(endpointID))
+		if err != nil {
+			httperror.WriteErrorResponse(w, err, http.StatusInternalServerError, handler.Logger)
+			return
+		}
+	}
+
+	if endpoint.SSHKeyPath != "" {
+		err = handler.FileService.DeleteSSHFiles(strconv.Itoa(endpointID))
 		if err != nil {
 			httperror.WriteErrorResponse(w, err, http.StatusInternalServerError, handler.Logger)
 			return
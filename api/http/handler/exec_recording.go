@@ -0,0 +1,82 @@
+package handler
+
+import (
+	"github.com/portainer/portainer"
+	httperror "github.com/portainer/portainer/http/error"
+	"github.com/portainer/portainer/http/security"
+
+	"log"
+	"net/http"
+	"os"
+
+	"github.com/gorilla/mux"
+)
+
+// ExecRecordingHandler represents an HTTP API handler for managing the recordings of exec sessions.
+type ExecRecordingHandler struct {
+	*mux.Router
+	Logger      *log.Logger
+	FileService portainer.FileService
+}
+
+// NewExecRecordingHandler returns a new instance of ExecRecordingHandler.
+func NewExecRecordingHandler(bouncer *security.RequestBouncer) *ExecRecordingHandler {
+	h := &ExecRecordingHandler{
+		Router: mux.NewRouter(),
+		Logger: log.New(os.Stderr, "", log.LstdFlags),
+	}
+	h.Handle("/exec_recordings",
+		bouncer.AdministratorAccess(http.HandlerFunc(h.handleGetExecRecordings))).Methods(http.MethodGet)
+	h.Handle("/exec_recordings/{id}",
+		bouncer.AdministratorAccess(http.HandlerFunc(h.handleGetExecRecording))).Methods(http.MethodGet)
+	h.Handle("/exec_recordings/{id}",
+		bouncer.AdministratorAccess(http.HandlerFunc(h.handleDeleteExecRecording))).Methods(http.MethodDelete)
+
+	return h
+}
+
+// handleGetExecRecordings handles GET requests on /exec_recordings
+func (handler *ExecRecordingHandler) handleGetExecRecordings(w http.ResponseWriter, r *http.Request) {
+	recordings, err := handler.FileService.ExecRecordings()
+	if err != nil {
+		httperror.WriteErrorResponse(w, err, http.StatusInternalServerError, handler.Logger)
+		return
+	}
+
+	encodeJSON(w, recordings, handler.Logger)
+}
+
+// handleGetExecRecording handles GET requests on /exec_recordings/:id
+// The recording is returned as an asciicast file.
+func (handler *ExecRecordingHandler) handleGetExecRecording(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	recordingPath, err := handler.FileService.GetPathForExecRecording(id)
+	if err == portainer.ErrExecRecordingNotFound {
+		httperror.WriteErrorResponse(w, err, http.StatusNotFound, handler.Logger)
+		return
+	} else if err != nil {
+		httperror.WriteErrorResponse(w, err, http.StatusInternalServerError, handler.Logger)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-asciicast")
+	w.Header().Set("Content-Disposition", "attachment; filename=\""+id+".cast\"")
+	http.ServeFile(w, r, recordingPath)
+}
+
+// handleDeleteExecRecording handles DELETE requests on /exec_recordings/:id
+func (handler *ExecRecordingHandler) handleDeleteExecRecording(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	err := handler.FileService.DeleteExecRecording(id)
+	if err == portainer.ErrExecRecordingNotFound {
+		httperror.WriteErrorResponse(w, err, http.StatusNotFound, handler.Logger)
+		return
+	} else if err != nil {
+		httperror.WriteErrorResponse(w, err, http.StatusInternalServerError, handler.Logger)
+		return
+	}
+}
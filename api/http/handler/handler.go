@@ -13,10 +13,12 @@ import (
 // Handler is a collection of all the service handlers.
 type Handler struct {
 	AuthHandler           *AuthHandler
+	AgentHandler          *AgentHandler
 	UserHandler           *UserHandler
 	TeamHandler           *TeamHandler
 	TeamMembershipHandler *TeamMembershipHandler
 	EndpointHandler       *EndpointHandler
+	EndpointGroupHandler  *EndpointGroupHandler
 	RegistryHandler       *RegistryHandler
 	DockerHubHandler      *DockerHubHandler
 	ResourceHandler       *ResourceHandler
@@ -25,6 +27,8 @@ type Handler struct {
 	TemplatesHandler      *TemplatesHandler
 	DockerHandler         *DockerHandler
 	WebSocketHandler      *WebSocketHandler
+	ExecRecordingHandler  *ExecRecordingHandler
+	AuditLogHandler       *AuditLogHandler
 	UploadHandler         *UploadHandler
 	FileHandler           *FileHandler
 }
@@ -44,6 +48,10 @@ const (
 func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	if strings.HasPrefix(r.URL.Path, "/api/auth") {
 		http.StripPrefix("/api", h.AuthHandler).ServeHTTP(w, r)
+	} else if strings.HasPrefix(r.URL.Path, "/api/agents") {
+		http.StripPrefix("/api", h.AgentHandler).ServeHTTP(w, r)
+	} else if strings.HasPrefix(r.URL.Path, "/api/audit_logs") {
+		http.StripPrefix("/api", h.AuditLogHandler).ServeHTTP(w, r)
 	} else if strings.HasPrefix(r.URL.Path, "/api/users") {
 		http.StripPrefix("/api", h.UserHandler).ServeHTTP(w, r)
 	} else if strings.HasPrefix(r.URL.Path, "/api/teams") {
@@ -56,6 +64,8 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		} else {
 			http.StripPrefix("/api", h.EndpointHandler).ServeHTTP(w, r)
 		}
+	} else if strings.HasPrefix(r.URL.Path, "/api/endpoint_groups") {
+		http.StripPrefix("/api", h.EndpointGroupHandler).ServeHTTP(w, r)
 	} else if strings.HasPrefix(r.URL.Path, "/api/registries") {
 		http.StripPrefix("/api", h.RegistryHandler).ServeHTTP(w, r)
 	} else if strings.HasPrefix(r.URL.Path, "/api/dockerhub") {
@@ -70,6 +80,8 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		http.StripPrefix("/api", h.TemplatesHandler).ServeHTTP(w, r)
 	} else if strings.HasPrefix(r.URL.Path, "/api/upload") {
 		http.StripPrefix("/api", h.UploadHandler).ServeHTTP(w, r)
+	} else if strings.HasPrefix(r.URL.Path, "/api/exec_recordings") {
+		http.StripPrefix("/api", h.ExecRecordingHandler).ServeHTTP(w, r)
 	} else if strings.HasPrefix(r.URL.Path, "/api/websocket") {
 		http.StripPrefix("/api", h.WebSocketHandler).ServeHTTP(w, r)
 	} else if strings.HasPrefix(r.URL.Path, "/") {
@@ -9,6 +9,7 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"regexp"
 	"strconv"
 
 	"github.com/asaskevich/govalidator"
@@ -18,10 +19,17 @@ import (
 // RegistryHandler represents an HTTP API handler for managing Docker registries.
 type RegistryHandler struct {
 	*mux.Router
-	Logger          *log.Logger
-	RegistryService portainer.RegistryService
+	Logger                 *log.Logger
+	RegistryService        portainer.RegistryService
+	RegistryBrowserService portainer.RegistryBrowserService
 }
 
+var (
+	// repositoryNameFormat and manifestReferenceFormat are the formats defined by the Docker Registry HTTP API v2.
+	repositoryNameFormat    = regexp.MustCompile(`^[a-z0-9]+(?:(?:[._]|__|[-]*)[a-z0-9]+)*(?:/[a-z0-9]+(?:(?:[._]|__|[-]*)[a-z0-9]+)*)*$`)
+	manifestReferenceFormat = regexp.MustCompile(`^(?:[\w][\w.-]{0,127}|[a-z0-9]+(?:[.+_-][a-z0-9]+)*:[a-zA-Z0-9=_-]+)$`)
+)
+
 // NewRegistryHandler returns a new instance of RegistryHandler.
 func NewRegistryHandler(bouncer *security.RequestBouncer) *RegistryHandler {
 	h := &RegistryHandler{
@@ -40,6 +48,18 @@ func NewRegistryHandler(bouncer *security.RequestBouncer) *RegistryHandler {
 		bouncer.AdministratorAccess(http.HandlerFunc(h.handlePutRegistryAccess))).Methods(http.MethodPut)
 	h.Handle("/registries/{id}",
 		bouncer.AdministratorAccess(http.HandlerFunc(h.handleDeleteRegistry))).Methods(http.MethodDelete)
+	h.Handle("/registries/ping",
+		bouncer.AdministratorAccess(http.HandlerFunc(h.handlePostRegistriesPing))).Methods(http.MethodPost)
+	h.Handle("/registries/{id}/ping",
+		bouncer.AdministratorAccess(http.HandlerFunc(h.handlePostRegistryPing))).Methods(http.MethodPost)
+	h.Handle("/registries/{id}/v2/_catalog",
+		bouncer.RestrictedAccess(http.HandlerFunc(h.handleGetRegistryCatalog))).Methods(http.MethodGet)
+	h.Handle("/registries/{id}/v2/{repository:.+}/tags/list",
+		bouncer.RestrictedAccess(http.HandlerFunc(h.handleGetRegistryTags))).Methods(http.MethodGet)
+	h.Handle("/registries/{id}/v2/{repository:.+}/manifests/{reference}",
+		bouncer.RestrictedAccess(http.HandlerFunc(h.handleGetRegistryManifest))).Methods(http.MethodGet)
+	h.Handle("/registries/{id}/v2/{repository:.+}/manifests/{reference}",
+		bouncer.AdministratorAccess(http.HandlerFunc(h.handleDeleteRegistryManifest))).Methods(http.MethodDelete)
 
 	return h
 }
@@ -64,6 +84,10 @@ func (handler *RegistryHandler) handleGetRegistries(w http.ResponseWriter, r *ht
 		return
 	}
 
+	for idx := range filteredRegistries {
+		filteredRegistries[idx].Password = ""
+	}
+
 	encodeJSON(w, filteredRegistries, handler.Logger)
 }
 
@@ -144,6 +168,8 @@ func (handler *RegistryHandler) handleGetRegistry(w http.ResponseWriter, r *http
 		return
 	}
 
+	registry.Password = ""
+
 	encodeJSON(w, registry, handler.Logger)
 }
 
@@ -262,7 +288,10 @@ func (handler *RegistryHandler) handlePutRegistry(w http.ResponseWriter, r *http
 	if req.Authentication {
 		registry.Authentication = true
 		registry.Username = req.Username
-		registry.Password = req.Password
+		// The stored password is kept when no password is specified.
+		if req.Password != "" {
+			registry.Password = req.Password
+		}
 	} else {
 		registry.Authentication = false
 		registry.Username = ""
@@ -310,3 +339,186 @@ func (handler *RegistryHandler) handleDeleteRegistry(w http.ResponseWriter, r *h
 		return
 	}
 }
+
+// handlePostRegistriesPing handles POST requests on /registries/ping
+// It validates the connection to a registry before it is created.
+func (handler *RegistryHandler) handlePostRegistriesPing(w http.ResponseWriter, r *http.Request) {
+	var req postRegistriesPingRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httperror.WriteErrorResponse(w, ErrInvalidJSON, http.StatusBadRequest, handler.Logger)
+		return
+	}
+
+	_, err := govalidator.ValidateStruct(req)
+	if err != nil {
+		httperror.WriteErrorResponse(w, ErrInvalidRequestFormat, http.StatusBadRequest, handler.Logger)
+		return
+	}
+
+	registry := &portainer.Registry{
+		URL:            req.URL,
+		Authentication: req.Authentication,
+		Username:       req.Username,
+		Password:       req.Password,
+	}
+
+	encodeJSON(w, handler.RegistryBrowserService.Ping(registry), handler.Logger)
+}
+
+type postRegistriesPingRequest struct {
+	URL            string `valid:"required"`
+	Authentication bool   `valid:""`
+	Username       string `valid:""`
+	Password       string `valid:""`
+}
+
+// handlePostRegistryPing handles POST requests on /registries/:id/ping
+func (handler *RegistryHandler) handlePostRegistryPing(w http.ResponseWriter, r *http.Request) {
+	registry, err := handler.retrieveAuthorizedRegistry(w, r)
+	if err != nil {
+		return
+	}
+
+	encodeJSON(w, handler.RegistryBrowserService.Ping(registry), handler.Logger)
+}
+
+type getRegistryCatalogResponse struct {
+	Repositories []string `json:"repositories"`
+}
+
+type getRegistryTagsResponse struct {
+	Name string   `json:"name"`
+	Tags []string `json:"tags"`
+}
+
+// handleGetRegistryCatalog handles GET requests on /registries/:id/v2/_catalog
+func (handler *RegistryHandler) handleGetRegistryCatalog(w http.ResponseWriter, r *http.Request) {
+	registry, err := handler.retrieveAuthorizedRegistry(w, r)
+	if err != nil {
+		return
+	}
+
+	repositories, err := handler.RegistryBrowserService.Repositories(registry)
+	if err != nil {
+		handler.writeRegistryBrowserError(w, err)
+		return
+	}
+
+	encodeJSON(w, &getRegistryCatalogResponse{Repositories: repositories}, handler.Logger)
+}
+
+// handleGetRegistryTags handles GET requests on /registries/:id/v2/:repository/tags/list
+func (handler *RegistryHandler) handleGetRegistryTags(w http.ResponseWriter, r *http.Request) {
+	repository := mux.Vars(r)["repository"]
+	if !repositoryNameFormat.MatchString(repository) {
+		httperror.WriteErrorResponse(w, ErrInvalidQueryFormat, http.StatusBadRequest, handler.Logger)
+		return
+	}
+
+	registry, err := handler.retrieveAuthorizedRegistry(w, r)
+	if err != nil {
+		return
+	}
+
+	tags, err := handler.RegistryBrowserService.Tags(registry, repository)
+	if err != nil {
+		handler.writeRegistryBrowserError(w, err)
+		return
+	}
+
+	encodeJSON(w, &getRegistryTagsResponse{Name: repository, Tags: tags}, handler.Logger)
+}
+
+// handleGetRegistryManifest handles GET requests on /registries/:id/v2/:repository/manifests/:reference
+// The reference can either be a tag or a digest.
+func (handler *RegistryHandler) handleGetRegistryManifest(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	repository := vars["repository"]
+	reference := vars["reference"]
+	if !repositoryNameFormat.MatchString(repository) || !manifestReferenceFormat.MatchString(reference) {
+		httperror.WriteErrorResponse(w, ErrInvalidQueryFormat, http.StatusBadRequest, handler.Logger)
+		return
+	}
+
+	registry, err := handler.retrieveAuthorizedRegistry(w, r)
+	if err != nil {
+		return
+	}
+
+	manifest, err := handler.RegistryBrowserService.Manifest(registry, repository, reference)
+	if err != nil {
+		handler.writeRegistryBrowserError(w, err)
+		return
+	}
+
+	w.Header().Set("Docker-Content-Digest", manifest.Digest)
+	encodeJSON(w, manifest, handler.Logger)
+}
+
+// handleDeleteRegistryManifest handles DELETE requests on /registries/:id/v2/:repository/manifests/:reference
+// The reference can either be a tag or a digest, every tag referencing the same manifest is removed.
+func (handler *RegistryHandler) handleDeleteRegistryManifest(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	repository := vars["repository"]
+	reference := vars["reference"]
+	if !repositoryNameFormat.MatchString(repository) || !manifestReferenceFormat.MatchString(reference) {
+		httperror.WriteErrorResponse(w, ErrInvalidQueryFormat, http.StatusBadRequest, handler.Logger)
+		return
+	}
+
+	registry, err := handler.retrieveAuthorizedRegistry(w, r)
+	if err != nil {
+		return
+	}
+
+	err = handler.RegistryBrowserService.DeleteManifest(registry, repository, reference)
+	if err != nil {
+		handler.writeRegistryBrowserError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// retrieveAuthorizedRegistry returns the registry identified in the request URL when the user is
+// authorized to use it. The error response is written when the registry cannot be retrieved.
+func (handler *RegistryHandler) retrieveAuthorizedRegistry(w http.ResponseWriter, r *http.Request) (*portainer.Registry, error) {
+	registryID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		httperror.WriteErrorResponse(w, err, http.StatusBadRequest, handler.Logger)
+		return nil, err
+	}
+
+	registry, err := handler.RegistryService.Registry(portainer.RegistryID(registryID))
+	if err == portainer.ErrRegistryNotFound {
+		httperror.WriteErrorResponse(w, err, http.StatusNotFound, handler.Logger)
+		return nil, err
+	} else if err != nil {
+		httperror.WriteErrorResponse(w, err, http.StatusInternalServerError, handler.Logger)
+		return nil, err
+	}
+
+	securityContext, err := security.RetrieveRestrictedRequestContext(r)
+	if err != nil {
+		httperror.WriteErrorResponse(w, err, http.StatusInternalServerError, handler.Logger)
+		return nil, err
+	}
+
+	if !securityContext.IsAdmin && !security.AuthorizedRegistryAccess(registry, securityContext.UserID, securityContext.UserMemberships) {
+		httperror.WriteErrorResponse(w, portainer.ErrResourceAccessDenied, http.StatusForbidden, handler.Logger)
+		return nil, portainer.ErrResourceAccessDenied
+	}
+
+	return registry, nil
+}
+
+func (handler *RegistryHandler) writeRegistryBrowserError(w http.ResponseWriter, err error) {
+	switch err {
+	case portainer.ErrRegistryResourceNotFound:
+		httperror.WriteErrorResponse(w, err, http.StatusNotFound, handler.Logger)
+	case portainer.ErrRegistryDeletionNotSupported:
+		httperror.WriteErrorResponse(w, err, http.StatusMethodNotAllowed, handler.Logger)
+	default:
+		httperror.WriteErrorResponse(w, err, http.StatusInternalServerError, handler.Logger)
+	}
+}
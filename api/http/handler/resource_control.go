@@ -61,51 +61,31 @@ func (handler *ResourceHandler) handlePostResources(w http.ResponseWriter, r *ht
 		resourceControlType = portainer.ServiceResourceControl
 	case "volume":
 		resourceControlType = portainer.VolumeResourceControl
+	case "network":
+		resourceControlType = portainer.NetworkResourceControl
+	case "secret":
+		resourceControlType = portainer.SecretResourceControl
+	case "config":
+		resourceControlType = portainer.ConfigResourceControl
+	case "image":
+		resourceControlType = portainer.ImageResourceControl
 	default:
 		httperror.WriteErrorResponse(w, portainer.ErrInvalidResourceControlType, http.StatusBadRequest, handler.Logger)
 		return
 	}
 
-	if len(req.Users) == 0 && len(req.Teams) == 0 && !req.AdministratorsOnly {
+	if len(req.Users) == 0 && len(req.Teams) == 0 && len(req.ReadOnlyUsers) == 0 && len(req.ReadOnlyTeams) == 0 && !req.AdministratorsOnly {
 		httperror.WriteErrorResponse(w, ErrInvalidRequestFormat, http.StatusBadRequest, handler.Logger)
 		return
 	}
 
-	rc, err := handler.ResourceControlService.ResourceControlByResourceID(req.ResourceID)
-	if err != nil && err != portainer.ErrResourceControlNotFound {
-		httperror.WriteErrorResponse(w, err, http.StatusInternalServerError, handler.Logger)
-		return
-	}
-	if rc != nil {
-		httperror.WriteErrorResponse(w, portainer.ErrResourceControlAlreadyExists, http.StatusConflict, handler.Logger)
-		return
-	}
-
-	var userAccesses = make([]portainer.UserResourceAccess, 0)
-	for _, v := range req.Users {
-		userAccess := portainer.UserResourceAccess{
-			UserID:      portainer.UserID(v),
-			AccessLevel: portainer.ReadWriteAccessLevel,
-		}
-		userAccesses = append(userAccesses, userAccess)
-	}
-
-	var teamAccesses = make([]portainer.TeamResourceAccess, 0)
-	for _, v := range req.Teams {
-		teamAccess := portainer.TeamResourceAccess{
-			TeamID:      portainer.TeamID(v),
-			AccessLevel: portainer.ReadWriteAccessLevel,
-		}
-		teamAccesses = append(teamAccesses, teamAccess)
-	}
-
 	resourceControl := portainer.ResourceControl{
 		ResourceID:         req.ResourceID,
 		SubResourceIDs:     req.SubResourceIDs,
 		Type:               resourceControlType,
 		AdministratorsOnly: req.AdministratorsOnly,
-		UserAccesses:       userAccesses,
-		TeamAccesses:       teamAccesses,
+		UserAccesses:       buildUserResourceAccesses(req.Users, req.ReadOnlyUsers),
+		TeamAccesses:       buildTeamResourceAccesses(req.Teams, req.ReadOnlyTeams),
 	}
 
 	securityContext, err := security.RetrieveRestrictedRequestContext(r)
@@ -119,6 +99,27 @@ func (handler *ResourceHandler) handlePostResources(w http.ResponseWriter, r *ht
 		return
 	}
 
+	rc, err := handler.ResourceControlService.ResourceControlByResourceID(req.ResourceID)
+	if err != nil && err != portainer.ErrResourceControlNotFound {
+		httperror.WriteErrorResponse(w, err, http.StatusInternalServerError, handler.Logger)
+		return
+	}
+	if rc != nil {
+		// A resource control can be automatically associated to a resource when it is created (see the endpoint
+		// resource ownership policy), it is replaced when the user is allowed to modify it.
+		if !security.AuthorizedResourceControlModification(rc, securityContext) {
+			httperror.WriteErrorResponse(w, portainer.ErrResourceControlAlreadyExists, http.StatusConflict, handler.Logger)
+			return
+		}
+
+		resourceControl.ID = rc.ID
+		err = handler.ResourceControlService.UpdateResourceControl(rc.ID, &resourceControl)
+		if err != nil {
+			httperror.WriteErrorResponse(w, err, http.StatusInternalServerError, handler.Logger)
+		}
+		return
+	}
+
 	err = handler.ResourceControlService.CreateResourceControl(&resourceControl)
 	if err != nil {
 		httperror.WriteErrorResponse(w, ErrInvalidRequestFormat, http.StatusBadRequest, handler.Logger)
@@ -134,6 +135,8 @@ type postResourcesRequest struct {
 	AdministratorsOnly bool     `valid:"-"`
 	Users              []int    `valid:"-"`
 	Teams              []int    `valid:"-"`
+	ReadOnlyUsers      []int    `valid:"-"`
+	ReadOnlyTeams      []int    `valid:"-"`
 	SubResourceIDs     []string `valid:"-"`
 }
 
@@ -170,34 +173,21 @@ func (handler *ResourceHandler) handlePutResources(w http.ResponseWriter, r *htt
 		return
 	}
 
-	resourceControl.AdministratorsOnly = req.AdministratorsOnly
-
-	var userAccesses = make([]portainer.UserResourceAccess, 0)
-	for _, v := range req.Users {
-		userAccess := portainer.UserResourceAccess{
-			UserID:      portainer.UserID(v),
-			AccessLevel: portainer.ReadWriteAccessLevel,
-		}
-		userAccesses = append(userAccesses, userAccess)
-	}
-	resourceControl.UserAccesses = userAccesses
-
-	var teamAccesses = make([]portainer.TeamResourceAccess, 0)
-	for _, v := range req.Teams {
-		teamAccess := portainer.TeamResourceAccess{
-			TeamID:      portainer.TeamID(v),
-			AccessLevel: portainer.ReadWriteAccessLevel,
-		}
-		teamAccesses = append(teamAccesses, teamAccess)
-	}
-	resourceControl.TeamAccesses = teamAccesses
-
 	securityContext, err := security.RetrieveRestrictedRequestContext(r)
 	if err != nil {
 		httperror.WriteErrorResponse(w, err, http.StatusInternalServerError, handler.Logger)
 		return
 	}
 
+	if !security.AuthorizedResourceControlModification(resourceControl, securityContext) {
+		httperror.WriteErrorResponse(w, portainer.ErrResourceAccessDenied, http.StatusForbidden, handler.Logger)
+		return
+	}
+
+	resourceControl.AdministratorsOnly = req.AdministratorsOnly
+	resourceControl.UserAccesses = buildUserResourceAccesses(req.Users, req.ReadOnlyUsers)
+	resourceControl.TeamAccesses = buildTeamResourceAccesses(req.Teams, req.ReadOnlyTeams)
+
 	if !security.AuthorizedResourceControlUpdate(resourceControl, securityContext) {
 		httperror.WriteErrorResponse(w, portainer.ErrResourceAccessDenied, http.StatusForbidden, handler.Logger)
 		return
@@ -214,6 +204,8 @@ type putResourcesRequest struct {
 	AdministratorsOnly bool  `valid:"-"`
 	Users              []int `valid:"-"`
 	Teams              []int `valid:"-"`
+	ReadOnlyUsers      []int `valid:"-"`
+	ReadOnlyTeams      []int `valid:"-"`
 }
 
 // handleDeleteResources handles DELETE requests on /resources/:id
@@ -254,3 +246,64 @@ func (handler *ResourceHandler) handleDeleteResources(w http.ResponseWriter, r *
 		return
 	}
 }
+
+// buildUserResourceAccesses returns the user accesses associated to the read-write and read-only user lists.
+// A user present in both lists is granted a read-write access.
+func buildUserResourceAccesses(users, readOnlyUsers []int) []portainer.UserResourceAccess {
+	userAccesses := make([]portainer.UserResourceAccess, 0)
+	for _, v := range users {
+		userAccess := portainer.UserResourceAccess{
+			UserID:      portainer.UserID(v),
+			AccessLevel: portainer.ReadWriteAccessLevel,
+		}
+		userAccesses = append(userAccesses, userAccess)
+	}
+
+	for _, v := range readOnlyUsers {
+		if containsID(users, v) {
+			continue
+		}
+		userAccess := portainer.UserResourceAccess{
+			UserID:      portainer.UserID(v),
+			AccessLevel: portainer.ReadOnlyAccessLevel,
+		}
+		userAccesses = append(userAccesses, userAccess)
+	}
+
+	return userAccesses
+}
+
+// buildTeamResourceAccesses returns the team accesses associated to the read-write and read-only team lists.
+// A team present in both lists is granted a read-write access.
+func buildTeamResourceAccesses(teams, readOnlyTeams []int) []portainer.TeamResourceAccess {
+	teamAccesses := make([]portainer.TeamResourceAccess, 0)
+	for _, v := range teams {
+		teamAccess := portainer.TeamResourceAccess{
+			TeamID:      portainer.TeamID(v),
+			AccessLevel: portainer.ReadWriteAccessLevel,
+		}
+		teamAccesses = append(teamAccesses, teamAccess)
+	}
+
+	for _, v := range readOnlyTeams {
+		if containsID(teams, v) {
+			continue
+		}
+		teamAccess := portainer.TeamResourceAccess{
+			TeamID:      portainer.TeamID(v),
+			AccessLevel: portainer.ReadOnlyAccessLevel,
+		}
+		teamAccesses = append(teamAccesses, teamAccess)
+	}
+
+	return teamAccesses
+}
+
+func containsID(ids []int, id int) bool {
+	for _, v := range ids {
+		if v == id {
+			return true
+		}
+	}
+	return false
+}
@@ -5,6 +5,7 @@ import (
 
 	"github.com/asaskevich/govalidator"
 	"github.com/portainer/portainer"
+	"github.com/portainer/portainer/file"
 	httperror "github.com/portainer/portainer/http/error"
 	"github.com/portainer/portainer/http/security"
 
@@ -20,8 +21,17 @@ type SettingsHandler struct {
 	*mux.Router
 	Logger          *log.Logger
 	SettingsService portainer.SettingsService
+	LDAPService     portainer.LDAPService
+	LDAPSyncService portainer.LDAPSyncService
+	FileService     portainer.FileService
+	AuditForwarder  portainer.AuditForwarder
 }
 
+const (
+	// ErrInvalidAuthenticationMethod defines an error raised when the authentication method is not supported
+	ErrInvalidAuthenticationMethod = portainer.Error("Unsupported authentication method")
+)
+
 // NewSettingsHandler returns a new instance of OldSettingsHandler.
 func NewSettingsHandler(bouncer *security.RequestBouncer) *SettingsHandler {
 	h := &SettingsHandler{
@@ -29,9 +39,17 @@ func NewSettingsHandler(bouncer *security.RequestBouncer) *SettingsHandler {
 		Logger: log.New(os.Stderr, "", log.LstdFlags),
 	}
 	h.Handle("/settings",
-		bouncer.PublicAccess(http.HandlerFunc(h.handleGetSettings))).Methods(http.MethodGet)
+		bouncer.AdministratorAccess(http.HandlerFunc(h.handleGetSettings))).Methods(http.MethodGet)
+	h.Handle("/settings/public",
+		bouncer.PublicAccess(http.HandlerFunc(h.handleGetPublicSettings))).Methods(http.MethodGet)
 	h.Handle("/settings",
 		bouncer.AdministratorAccess(http.HandlerFunc(h.handlePutSettings))).Methods(http.MethodPut)
+	h.Handle("/settings/authentication/checkLDAP",
+		bouncer.AdministratorAccess(http.HandlerFunc(h.handlePutSettingsLDAPCheck))).Methods(http.MethodPut)
+	h.Handle("/settings/authentication/ldap/sync",
+		bouncer.AdministratorAccess(http.HandlerFunc(h.handleGetSettingsLDAPSync))).Methods(http.MethodGet)
+	h.Handle("/settings/authentication/ldap/sync",
+		bouncer.AdministratorAccess(http.HandlerFunc(h.handlePostSettingsLDAPSync))).Methods(http.MethodPost)
 
 	return h
 }
@@ -44,10 +62,46 @@ func (handler *SettingsHandler) handleGetSettings(w http.ResponseWriter, r *http
 		return
 	}
 
+	settings.LDAPSettings.Password = ""
+	settings.OAuthSettings.ClientSecret = ""
+	for idx := range settings.AuditSinks {
+		settings.AuditSinks[idx].Token = ""
+	}
+
 	encodeJSON(w, settings, handler.Logger)
 	return
 }
 
+// handleGetPublicSettings handles GET requests on /settings/public
+func (handler *SettingsHandler) handleGetPublicSettings(w http.ResponseWriter, r *http.Request) {
+	settings, err := handler.SettingsService.Settings()
+	if err != nil {
+		httperror.WriteErrorResponse(w, err, http.StatusInternalServerError, handler.Logger)
+		return
+	}
+
+	publicSettings := &publicSettingsResponse{
+		LogoURL:                     settings.LogoURL,
+		DisplayExternalContributors: settings.DisplayExternalContributors,
+		AuthenticationMethod:        settings.AuthenticationMethod,
+	}
+	if settings.AuthenticationMethod == portainer.AuthenticationOAuth {
+		publicSettings.OAuthLoginURI = oauthLoginURI
+	}
+
+	encodeJSON(w, publicSettings, handler.Logger)
+}
+
+// publicSettingsResponse represents the subset of the settings required before authentication.
+type publicSettingsResponse struct {
+	LogoURL                     string                         `json:"LogoURL"`
+	DisplayExternalContributors bool                           `json:"DisplayExternalContributors"`
+	AuthenticationMethod        portainer.AuthenticationMethod `json:"AuthenticationMethod"`
+	// OAuthLoginURI is only set when the OAuth authentication is enabled,
+	// the provider settings are never exposed.
+	OAuthLoginURI string `json:"OAuthLoginURI,omitempty"`
+}
+
 // handlePutSettings handles PUT requests on /settings
 func (handler *SettingsHandler) handlePutSettings(w http.ResponseWriter, r *http.Request) {
 	var req putSettingsRequest
@@ -62,11 +116,99 @@ func (handler *SettingsHandler) handlePutSettings(w http.ResponseWriter, r *http
 		return
 	}
 
+	storedSettings, err := handler.SettingsService.Settings()
+	if err != nil {
+		httperror.WriteErrorResponse(w, err, http.StatusInternalServerError, handler.Logger)
+		return
+	}
+
 	settings := &portainer.Settings{
 		TemplatesURL:                req.TemplatesURL,
 		LogoURL:                     req.LogoURL,
 		BlackListedLabels:           req.BlackListedLabels,
 		DisplayExternalContributors: req.DisplayExternalContributors,
+		LDAPSettings:                req.LDAPSettings,
+		OAuthSettings:               req.OAuthSettings,
+		LoginRateLimitSettings:      storedSettings.LoginRateLimitSettings,
+		EnforceTwoFactorForAdmins:   storedSettings.EnforceTwoFactorForAdmins,
+		ExecRecordingSettings:       storedSettings.ExecRecordingSettings,
+		AuditSinks:                  storedSettings.AuditSinks,
+	}
+
+	if req.EnforceTwoFactorForAdmins != nil {
+		settings.EnforceTwoFactorForAdmins = *req.EnforceTwoFactorForAdmins
+	}
+
+	if req.LoginRateLimitSettings != nil {
+		limits := req.LoginRateLimitSettings
+		if limits.MaxFailedAttemptsPerUser < 0 || limits.MaxFailedAttemptsPerIP < 0 || limits.LockoutDuration < 0 ||
+			limits.BackoffBaseDelay < 0 || limits.BackoffMaxDelay < 0 {
+			httperror.WriteErrorResponse(w, ErrInvalidRequestFormat, http.StatusBadRequest, handler.Logger)
+			return
+		}
+		settings.LoginRateLimitSettings = *limits
+	}
+
+	if req.ExecRecordingSettings != nil {
+		if req.ExecRecordingSettings.RetentionDays < 0 {
+			httperror.WriteErrorResponse(w, ErrInvalidRequestFormat, http.StatusBadRequest, handler.Logger)
+			return
+		}
+		settings.ExecRecordingSettings = *req.ExecRecordingSettings
+	}
+
+	if req.AuditSinks != nil {
+		settings.AuditSinks = *req.AuditSinks
+		for idx := range settings.AuditSinks {
+			sink := &settings.AuditSinks[idx]
+			// Webhook tokens are never returned by the API, keep the stored token of an unchanged webhook.
+			if sink.Token == "" && idx < len(storedSettings.AuditSinks) && storedSettings.AuditSinks[idx].URL == sink.URL {
+				sink.Token = storedSettings.AuditSinks[idx].Token
+			}
+		}
+	}
+
+	switch req.AuthenticationMethod {
+	case 0, int(portainer.AuthenticationInternal):
+		settings.AuthenticationMethod = portainer.AuthenticationInternal
+	case int(portainer.AuthenticationLDAP):
+		settings.AuthenticationMethod = portainer.AuthenticationLDAP
+	case int(portainer.AuthenticationOAuth):
+		settings.AuthenticationMethod = portainer.AuthenticationOAuth
+	default:
+		httperror.WriteErrorResponse(w, ErrInvalidAuthenticationMethod, http.StatusBadRequest, handler.Logger)
+		return
+	}
+
+	// The LDAP password and the OAuth client secret are never returned by the API,
+	// keep the stored ones when they are not specified.
+	if settings.LDAPSettings.Password == "" {
+		settings.LDAPSettings.Password = storedSettings.LDAPSettings.Password
+	}
+	if settings.OAuthSettings.ClientSecret == "" {
+		settings.OAuthSettings.ClientSecret = storedSettings.OAuthSettings.ClientSecret
+	}
+
+	if settings.LDAPSettings.TLSConfig.TLS || settings.LDAPSettings.StartTLS {
+		settings.LDAPSettings.TLSConfig.TLSCACertPath = handler.ldapCACertPath()
+	} else {
+		settings.LDAPSettings.TLSConfig.TLSCACertPath = ""
+		err = handler.FileService.DeleteTLSFiles(file.LDAPStorePath)
+		if err != nil {
+			httperror.WriteErrorResponse(w, err, http.StatusInternalServerError, handler.Logger)
+			return
+		}
+	}
+
+	if req.AuditSinks != nil {
+		err = handler.AuditForwarder.Configure(settings.AuditSinks)
+		if err == portainer.ErrInvalidAuditSink {
+			httperror.WriteErrorResponse(w, err, http.StatusBadRequest, handler.Logger)
+			return
+		} else if err != nil {
+			httperror.WriteErrorResponse(w, err, http.StatusInternalServerError, handler.Logger)
+			return
+		}
 	}
 
 	err = handler.SettingsService.StoreSettings(settings)
@@ -76,8 +218,91 @@ func (handler *SettingsHandler) handlePutSettings(w http.ResponseWriter, r *http
 }
 
 type putSettingsRequest struct {
-	TemplatesURL                string           `valid:"required"`
-	LogoURL                     string           `valid:""`
-	BlackListedLabels           []portainer.Pair `valid:""`
-	DisplayExternalContributors bool             `valid:""`
+	TemplatesURL                string                  `valid:"required"`
+	LogoURL                     string                  `valid:""`
+	BlackListedLabels           []portainer.Pair        `valid:""`
+	DisplayExternalContributors bool                    `valid:""`
+	AuthenticationMethod        int                     `valid:""`
+	LDAPSettings                portainer.LDAPSettings  `valid:""`
+	OAuthSettings               portainer.OAuthSettings `valid:""`
+	// LoginRateLimitSettings is optional, the stored settings are kept when it is not specified.
+	LoginRateLimitSettings *portainer.LoginRateLimitSettings `valid:""`
+	// EnforceTwoFactorForAdmins is optional, the stored setting is kept when it is not specified.
+	EnforceTwoFactorForAdmins *bool `valid:"-"`
+	// ExecRecordingSettings is optional, the stored settings are kept when it is not specified.
+	ExecRecordingSettings *portainer.ExecRecordingSettings `valid:""`
+	// AuditSinks is optional, the stored sinks are kept when it is not specified.
+	AuditSinks *[]portainer.AuditSink `valid:""`
+}
+
+// handlePutSettingsLDAPCheck handles PUT requests on /settings/authentication/checkLDAP
+func (handler *SettingsHandler) handlePutSettingsLDAPCheck(w http.ResponseWriter, r *http.Request) {
+	var req putSettingsLDAPCheckRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httperror.WriteErrorResponse(w, ErrInvalidJSON, http.StatusBadRequest, handler.Logger)
+		return
+	}
+
+	_, err := govalidator.ValidateStruct(req)
+	if err != nil {
+		httperror.WriteErrorResponse(w, ErrInvalidRequestFormat, http.StatusBadRequest, handler.Logger)
+		return
+	}
+
+	if req.LDAPSettings.Password == "" {
+		storedSettings, err := handler.SettingsService.Settings()
+		if err != nil {
+			httperror.WriteErrorResponse(w, err, http.StatusInternalServerError, handler.Logger)
+			return
+		}
+		req.LDAPSettings.Password = storedSettings.LDAPSettings.Password
+	}
+
+	req.LDAPSettings.TLSConfig.TLSCACertPath = ""
+	if req.LDAPSettings.TLSConfig.TLS || req.LDAPSettings.StartTLS {
+		req.LDAPSettings.TLSConfig.TLSCACertPath = handler.ldapCACertPath()
+	}
+
+	err = handler.LDAPService.TestConnectivity(&req.LDAPSettings)
+	if err != nil {
+		httperror.WriteErrorResponse(w, err, http.StatusInternalServerError, handler.Logger)
+		return
+	}
+}
+
+type putSettingsLDAPCheckRequest struct {
+	LDAPSettings portainer.LDAPSettings `valid:""`
+}
+
+// handleGetSettingsLDAPSync handles GET requests on /settings/authentication/ldap/sync
+func (handler *SettingsHandler) handleGetSettingsLDAPSync(w http.ResponseWriter, r *http.Request) {
+	encodeJSON(w, handler.LDAPSyncService.Reports(), handler.Logger)
+}
+
+// handlePostSettingsLDAPSync handles POST requests on /settings/authentication/ldap/sync
+func (handler *SettingsHandler) handlePostSettingsLDAPSync(w http.ResponseWriter, r *http.Request) {
+	report, err := handler.LDAPSyncService.Synchronize()
+	if err == portainer.ErrLDAPSyncDisabled {
+		httperror.WriteErrorResponse(w, err, http.StatusServiceUnavailable, handler.Logger)
+		return
+	} else if err != nil {
+		httperror.WriteErrorResponse(w, err, http.StatusInternalServerError, handler.Logger)
+		return
+	}
+
+	encodeJSON(w, report, handler.Logger)
+}
+
+// ldapCACertPath returns the path to the uploaded LDAP CA certificate or an empty string
+// when no CA certificate has been uploaded, in which case the system CA pool is used.
+func (handler *SettingsHandler) ldapCACertPath() string {
+	caCertPath, err := handler.FileService.GetPathForTLSFile(file.LDAPStorePath, portainer.TLSFileCA)
+	if err != nil {
+		return ""
+	}
+
+	if _, err := os.Stat(caCertPath); err != nil {
+		return ""
+	}
+	return caCertPath
 }
@@ -0,0 +1,239 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/portainer/portainer"
+	"github.com/portainer/portainer/ldap"
+
+	"gopkg.in/asn1-ber.v1"
+)
+
+const (
+	ldapBindRequest              = 0
+	ldapBindResponse             = 1
+	ldapResultSuccess            = 0
+	ldapResultInvalidCredentials = 49
+	stubReaderDN                 = "cn=readonly,dc=example,dc=com"
+	stubReaderPassword           = "readonly"
+)
+
+// stubLDAPServer is an in-process LDAP server answering the bind requests of the reader account.
+type stubLDAPServer struct {
+	listener net.Listener
+}
+
+func newStubLDAPServer(t *testing.T) *stubLDAPServer {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Unable to start the LDAP server: %s", err)
+	}
+
+	server := &stubLDAPServer{listener: listener}
+	go server.serve()
+	return server
+}
+
+func (server *stubLDAPServer) serve() {
+	for {
+		conn, err := server.listener.Accept()
+		if err != nil {
+			return
+		}
+		go server.handle(conn)
+	}
+}
+
+func (server *stubLDAPServer) handle(conn net.Conn) {
+	defer conn.Close()
+
+	for {
+		packet, err := ber.ReadPacket(conn)
+		if err != nil || len(packet.Children) < 2 {
+			return
+		}
+
+		messageID, _ := packet.Children[0].Value.(int64)
+		request := packet.Children[1]
+		if request.ClassType != ber.ClassApplication || request.Tag != ldapBindRequest || len(request.Children) < 3 {
+			return
+		}
+
+		resultCode := ldapResultInvalidCredentials
+		dn, _ := request.Children[1].Value.(string)
+		if dn == stubReaderDN && request.Children[2].Data.String() == stubReaderPassword {
+			resultCode = ldapResultSuccess
+		}
+
+		response := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "LDAP Response")
+		response.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagInteger, messageID, "MessageID"))
+		bindResponse := ber.Encode(ber.ClassApplication, ber.TypeConstructed, ldapBindResponse, nil, "Bind Response")
+		bindResponse.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagEnumerated, resultCode, "Result Code"))
+		bindResponse.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", "Matched DN"))
+		bindResponse.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", "Error Message"))
+		response.AppendChild(bindResponse)
+
+		_, err = conn.Write(response.Bytes())
+		if err != nil {
+			return
+		}
+	}
+}
+
+func (server *stubLDAPServer) Close() {
+	server.listener.Close()
+}
+
+func checkLDAP(handler *SettingsHandler, settings portainer.LDAPSettings) int {
+	body, _ := json.Marshal(&putSettingsLDAPCheckRequest{LDAPSettings: settings})
+	w := httptest.NewRecorder()
+	handler.handlePutSettingsLDAPCheck(w, httptest.NewRequest(http.MethodPut, "/api/settings/authentication/checkLDAP", bytes.NewReader(body)))
+	return w.Code
+}
+
+func TestCheckLDAP(t *testing.T) {
+	server := newStubLDAPServer(t)
+	defer server.Close()
+
+	handler := &SettingsHandler{
+		Logger: log.New(ioutil.Discard, "", 0),
+		SettingsService: &stubSettingsService{settings: &portainer.Settings{
+			LDAPSettings: portainer.LDAPSettings{Password: stubReaderPassword},
+		}},
+		LDAPService: &ldap.Service{},
+	}
+
+	settings := portainer.LDAPSettings{
+		ReaderDN: stubReaderDN,
+		Password: stubReaderPassword,
+		URL:      server.listener.Addr().String(),
+	}
+	if code := checkLDAP(handler, settings); code != http.StatusOK {
+		t.Errorf("Unexpected status with valid settings: %d", code)
+	}
+
+	// The stored password is used when the password is not specified.
+	settings.Password = ""
+	if code := checkLDAP(handler, settings); code != http.StatusOK {
+		t.Errorf("Unexpected status with the stored password: %d", code)
+	}
+
+	settings.Password = "invalid"
+	if code := checkLDAP(handler, settings); code != http.StatusInternalServerError {
+		t.Errorf("Unexpected status with an invalid password: %d", code)
+	}
+
+	settings.Password = stubReaderPassword
+	settings.URL = "127.0.0.1:1"
+	if code := checkLDAP(handler, settings); code != http.StatusInternalServerError {
+		t.Errorf("Unexpected status with an unreachable server: %d", code)
+	}
+}
+
+func TestPublicSettings(t *testing.T) {
+	handler := &SettingsHandler{
+		Logger: log.New(ioutil.Discard, "", 0),
+		SettingsService: &stubSettingsService{settings: &portainer.Settings{
+			LogoURL:              "https://example.com/logo.png",
+			AuthenticationMethod: portainer.AuthenticationLDAP,
+			LDAPSettings: portainer.LDAPSettings{
+				ReaderDN: stubReaderDN,
+				Password: stubReaderPassword,
+				URL:      "ldap.example.com:389",
+			},
+			AuditSinks: []portainer.AuditSink{
+				{Type: portainer.WebhookAuditSink, URL: "https://audit.example.com", Token: "token"},
+				{Type: portainer.FileAuditSink, Path: "/data/audit/audit.log"},
+			},
+		}},
+	}
+
+	w := httptest.NewRecorder()
+	handler.handleGetPublicSettings(w, httptest.NewRequest(http.MethodGet, "/api/settings/public", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Unexpected status: %d", w.Code)
+	}
+
+	var settings map[string]interface{}
+	err := json.Unmarshal(w.Body.Bytes(), &settings)
+	if err != nil {
+		t.Fatalf("Invalid response: %s", err)
+	}
+
+	expected := map[string]interface{}{
+		"LogoURL":                     "https://example.com/logo.png",
+		"DisplayExternalContributors": false,
+		"AuthenticationMethod":        float64(portainer.AuthenticationLDAP),
+	}
+	if len(settings) != len(expected) {
+		t.Errorf("Unexpected public settings: %v", settings)
+	}
+	for key, value := range expected {
+		if settings[key] != value {
+			t.Errorf("Unexpected %s: %v", key, settings[key])
+		}
+	}
+}
+
+func TestPublicSettingsWithOAuth(t *testing.T) {
+	handler := &SettingsHandler{
+		Logger: log.New(ioutil.Discard, "", 0),
+		SettingsService: &stubSettingsService{settings: &portainer.Settings{
+			AuthenticationMethod: portainer.AuthenticationOAuth,
+			OAuthSettings: portainer.OAuthSettings{
+				ClientID:         "portainer",
+				AuthorizationURI: "https://provider.example.com/authorize",
+			},
+		}},
+	}
+
+	w := httptest.NewRecorder()
+	handler.handleGetPublicSettings(w, httptest.NewRequest(http.MethodGet, "/api/settings/public", nil))
+
+	var settings map[string]interface{}
+	err := json.Unmarshal(w.Body.Bytes(), &settings)
+	if err != nil {
+		t.Fatalf("Invalid response: %s", err)
+	}
+	if settings["OAuthLoginURI"] != oauthLoginURI {
+		t.Errorf("Unexpected OAuth login URI: %v", settings["OAuthLoginURI"])
+	}
+	if _, ok := settings["OAuthSettings"]; ok || len(settings) != 4 {
+		t.Errorf("Unexpected public settings: %v", settings)
+	}
+}
+
+func TestGetSettingsHidesSecrets(t *testing.T) {
+	handler := &SettingsHandler{
+		Logger: log.New(ioutil.Discard, "", 0),
+		SettingsService: &stubSettingsService{settings: &portainer.Settings{
+			LDAPSettings:  portainer.LDAPSettings{ReaderDN: stubReaderDN, Password: stubReaderPassword},
+			OAuthSettings: portainer.OAuthSettings{ClientID: "portainer", ClientSecret: "secret"},
+			AuditSinks: []portainer.AuditSink{
+				{Type: portainer.WebhookAuditSink, URL: "https://audit.example.com", Token: "token"},
+			},
+		}},
+	}
+
+	w := httptest.NewRecorder()
+	handler.handleGetSettings(w, httptest.NewRequest(http.MethodGet, "/api/settings", nil))
+
+	var settings portainer.Settings
+	err := json.Unmarshal(w.Body.Bytes(), &settings)
+	if err != nil {
+		t.Fatalf("Invalid response: %s", err)
+	}
+	if settings.LDAPSettings.Password != "" || settings.OAuthSettings.ClientSecret != "" {
+		t.Errorf("The authentication secrets are returned")
+	}
+	if len(settings.AuditSinks) != 1 || settings.AuditSinks[0].URL != "https://audit.example.com" || settings.AuditSinks[0].Token != "" {
+		t.Errorf("Unexpected audit sinks: %+v", settings.AuditSinks)
+	}
+}
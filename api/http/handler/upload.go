@@ -2,13 +2,13 @@ package handler
 
 import (
 	"github.com/portainer/portainer"
+	"github.com/portainer/portainer/file"
 	httperror "github.com/portainer/portainer/http/error"
 	"github.com/portainer/portainer/http/security"
 
 	"log"
 	"net/http"
 	"os"
-	"strconv"
 
 	"github.com/gorilla/mux"
 )
@@ -26,8 +26,10 @@ func NewUploadHandler(bouncer *security.RequestBouncer) *UploadHandler {
 		Router: mux.NewRouter(),
 		Logger: log.New(os.Stderr, "", log.LstdFlags),
 	}
-	h.Handle("/upload/tls/{endpointID}/{certificate:(?:ca|cert|key)}",
+	h.Handle("/upload/tls/{folder:[a-zA-Z0-9]+}/{certificate:(?:ca|cert|key)}",
 		bouncer.AuthenticatedAccess(http.HandlerFunc(h.handlePostUploadTLS)))
+	h.Handle("/upload/ssh/{folder:[a-zA-Z0-9]+}/{file:(?:key|known_hosts)}",
+		bouncer.AdministratorAccess(http.HandlerFunc(h.handlePostUploadSSH)))
 	return h
 }
 
@@ -38,12 +40,21 @@ func (handler *UploadHandler) handlePostUploadTLS(w http.ResponseWriter, r *http
 	}
 
 	vars := mux.Vars(r)
-	endpointID := vars["endpointID"]
+	folder := vars["folder"]
 	certificate := vars["certificate"]
-	ID, err := strconv.Atoi(endpointID)
-	if err != nil {
-		httperror.WriteErrorResponse(w, err, http.StatusInternalServerError, handler.Logger)
-		return
+
+	// The LDAP TLS files are part of the settings, only an administrator can replace them.
+	if folder == file.LDAPStorePath {
+		tokenData, err := security.RetrieveTokenData(r)
+		if err != nil {
+			httperror.WriteErrorResponse(w, err, http.StatusInternalServerError, handler.Logger)
+			return
+		}
+
+		if tokenData.Role != portainer.AdministratorRole {
+			httperror.WriteErrorResponse(w, portainer.ErrResourceAccessDenied, http.StatusForbidden, handler.Logger)
+			return
+		}
 	}
 
 	file, _, err := r.FormFile("file")
@@ -66,7 +77,41 @@ func (handler *UploadHandler) handlePostUploadTLS(w http.ResponseWriter, r *http
 		return
 	}
 
-	err = handler.FileService.StoreTLSFile(portainer.EndpointID(ID), fileType, file)
+	err = handler.FileService.StoreTLSFile(folder, fileType, file)
+	if err != nil {
+		httperror.WriteErrorResponse(w, err, http.StatusInternalServerError, handler.Logger)
+		return
+	}
+}
+
+func (handler *UploadHandler) handlePostUploadSSH(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		httperror.WriteMethodNotAllowedResponse(w, []string{http.MethodPost})
+		return
+	}
+
+	vars := mux.Vars(r)
+	folder := vars["folder"]
+
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		httperror.WriteErrorResponse(w, err, http.StatusInternalServerError, handler.Logger)
+		return
+	}
+	defer file.Close()
+
+	var fileType portainer.SSHFileType
+	switch vars["file"] {
+	case "key":
+		fileType = portainer.SSHFileKey
+	case "known_hosts":
+		fileType = portainer.SSHFileKnownHosts
+	default:
+		httperror.WriteErrorResponse(w, portainer.ErrUndefinedSSHFileType, http.StatusInternalServerError, handler.Logger)
+		return
+	}
+
+	err = handler.FileService.StoreSSHFile(folder, fileType, file)
 	if err != nil {
 		httperror.WriteErrorResponse(w, err, http.StatusInternalServerError, handler.Logger)
 		return
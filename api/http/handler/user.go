@@ -3,8 +3,10 @@ package handler
 import (
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/portainer/portainer"
+	"github.com/portainer/portainer/crypto"
 	httperror "github.com/portainer/portainer/http/error"
 	"github.com/portainer/portainer/http/security"
 
@@ -22,10 +24,13 @@ type UserHandler struct {
 	*mux.Router
 	Logger                 *log.Logger
 	UserService            portainer.UserService
+	APIKeyService          portainer.APIKeyService
 	TeamService            portainer.TeamService
 	TeamMembershipService  portainer.TeamMembershipService
 	ResourceControlService portainer.ResourceControlService
 	CryptoService          portainer.CryptoService
+	JWTService             portainer.JWTService
+	LoginLimiter           *security.LoginLimiter
 }
 
 // NewUserHandler returns a new instance of UserHandler.
@@ -48,6 +53,20 @@ func NewUserHandler(bouncer *security.RequestBouncer) *UserHandler {
 		bouncer.AuthenticatedAccess(http.HandlerFunc(h.handleGetMemberships))).Methods(http.MethodGet)
 	h.Handle("/users/{id}/teams",
 		bouncer.RestrictedAccess(http.HandlerFunc(h.handleGetTeams))).Methods(http.MethodGet)
+	h.Handle("/users/{id}/api_keys",
+		bouncer.AuthenticatedAccess(http.HandlerFunc(h.handleGetAPIKeys))).Methods(http.MethodGet)
+	h.Handle("/users/{id}/api_keys",
+		bouncer.AuthenticatedAccess(http.HandlerFunc(h.handlePostAPIKeys))).Methods(http.MethodPost)
+	h.Handle("/users/{id}/api_keys/{keyId}",
+		bouncer.AuthenticatedAccess(http.HandlerFunc(h.handleDeleteAPIKey))).Methods(http.MethodDelete)
+	h.Handle("/users/{id}/2fa",
+		bouncer.AuthenticatedAccess(http.HandlerFunc(h.handlePostTwoFactor))).Methods(http.MethodPost)
+	h.Handle("/users/{id}/2fa/verify",
+		bouncer.AuthenticatedAccess(http.HandlerFunc(h.handlePostTwoFactorVerify))).Methods(http.MethodPost)
+	h.Handle("/users/{id}/2fa",
+		bouncer.AuthenticatedAccess(http.HandlerFunc(h.handleDeleteTwoFactor))).Methods(http.MethodDelete)
+	h.Handle("/users/{id}/unlock",
+		bouncer.AdministratorAccess(http.HandlerFunc(h.handlePostUserUnlock))).Methods(http.MethodPost)
 	h.Handle("/users/{id}/passwd",
 		bouncer.AuthenticatedAccess(http.HandlerFunc(h.handlePostUserPasswd)))
 	h.Handle("/users/admin/check",
@@ -156,7 +175,7 @@ func (handler *UserHandler) handleGetUsers(w http.ResponseWriter, r *http.Reques
 	filteredUsers := security.FilterUsers(users, securityContext)
 
 	for i := range filteredUsers {
-		filteredUsers[i].Password = ""
+		hideUserSecrets(&filteredUsers[i])
 	}
 
 	encodeJSON(w, filteredUsers, handler.Logger)
@@ -218,6 +237,38 @@ type postUserPasswdResponse struct {
 	Valid bool `json:"valid"`
 }
 
+// handlePostUserUnlock handles POST requests on /users/:id/unlock
+func (handler *UserHandler) handlePostUserUnlock(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	userID, err := strconv.Atoi(id)
+	if err != nil {
+		httperror.WriteErrorResponse(w, err, http.StatusBadRequest, handler.Logger)
+		return
+	}
+
+	user, err := handler.UserService.User(portainer.UserID(userID))
+	if err == portainer.ErrUserNotFound {
+		httperror.WriteErrorResponse(w, err, http.StatusNotFound, handler.Logger)
+		return
+	} else if err != nil {
+		httperror.WriteErrorResponse(w, err, http.StatusInternalServerError, handler.Logger)
+		return
+	}
+
+	handler.LoginLimiter.UnlockUser(user.Username)
+
+	if user.LockedUntil != 0 {
+		user.LockedUntil = 0
+		err = handler.UserService.UpdateUser(user.ID, user)
+		if err != nil {
+			httperror.WriteErrorResponse(w, err, http.StatusInternalServerError, handler.Logger)
+			return
+		}
+	}
+}
+
 // handleGetUser handles GET requests on /users/:id
 func (handler *UserHandler) handleGetUser(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
@@ -238,7 +289,7 @@ func (handler *UserHandler) handleGetUser(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	user.Password = ""
+	hideUserSecrets(user)
 	encodeJSON(w, &user, handler.Logger)
 }
 
@@ -276,7 +327,7 @@ func (handler *UserHandler) handlePutUser(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	if req.Password == "" && req.Role == 0 {
+	if req.Password == "" && req.Role == 0 && req.External == nil {
 		httperror.WriteErrorResponse(w, ErrInvalidRequestFormat, http.StatusBadRequest, handler.Logger)
 		return
 	}
@@ -290,12 +341,15 @@ func (handler *UserHandler) handlePutUser(w http.ResponseWriter, r *http.Request
 		return
 	}
 
+	revokeTokens := false
+
 	if req.Password != "" {
 		user.Password, err = handler.CryptoService.Hash(req.Password)
 		if err != nil {
 			httperror.WriteErrorResponse(w, portainer.ErrCryptoHashFailure, http.StatusBadRequest, handler.Logger)
 			return
 		}
+		revokeTokens = true
 	}
 
 	if req.Role != 0 {
@@ -303,11 +357,23 @@ func (handler *UserHandler) handlePutUser(w http.ResponseWriter, r *http.Request
 			httperror.WriteErrorResponse(w, portainer.ErrUnauthorized, http.StatusForbidden, handler.Logger)
 			return
 		}
+		previousRole := user.Role
 		if req.Role == 1 {
 			user.Role = portainer.AdministratorRole
 		} else {
 			user.Role = portainer.StandardUserRole
 		}
+		if user.Role != previousRole {
+			revokeTokens = true
+		}
+	}
+
+	if req.External != nil {
+		if tokenData.Role != portainer.AdministratorRole {
+			httperror.WriteErrorResponse(w, portainer.ErrUnauthorized, http.StatusForbidden, handler.Logger)
+			return
+		}
+		user.External = *req.External
 	}
 
 	err = handler.UserService.UpdateUser(user.ID, user)
@@ -315,11 +381,21 @@ func (handler *UserHandler) handlePutUser(w http.ResponseWriter, r *http.Request
 		httperror.WriteErrorResponse(w, err, http.StatusInternalServerError, handler.Logger)
 		return
 	}
+
+	if revokeTokens {
+		err = handler.revokeUserTokens(user.ID)
+		if err != nil {
+			httperror.WriteErrorResponse(w, err, http.StatusInternalServerError, handler.Logger)
+			return
+		}
+	}
 }
 
 type putUserRequest struct {
 	Password string `valid:"-"`
 	Role     int    `valid:"-"`
+	// External is optional and can only be updated by an administrator.
+	External *bool `valid:"-"`
 }
 
 // handlePostAdminInit handles GET requests on /users/admin/check
@@ -422,6 +498,27 @@ func (handler *UserHandler) handleDeleteUser(w http.ResponseWriter, r *http.Requ
 		httperror.WriteErrorResponse(w, err, http.StatusInternalServerError, handler.Logger)
 		return
 	}
+
+	err = handler.APIKeyService.DeleteAPIKeysByUserID(portainer.UserID(userID))
+	if err != nil {
+		httperror.WriteErrorResponse(w, err, http.StatusInternalServerError, handler.Logger)
+		return
+	}
+
+	err = handler.revokeUserTokens(portainer.UserID(userID))
+	if err != nil {
+		httperror.WriteErrorResponse(w, err, http.StatusInternalServerError, handler.Logger)
+		return
+	}
+}
+
+// revokeUserTokens invalidates all the JWT tokens issued to a user.
+// No token is issued when authentication is disabled.
+func (handler *UserHandler) revokeUserTokens(userID portainer.UserID) error {
+	if handler.JWTService == nil {
+		return nil
+	}
+	return handler.JWTService.RevokeUserTokens(userID)
 }
 
 // handleGetMemberships handles GET requests on /users/:id/memberships
@@ -488,3 +585,403 @@ func (handler *UserHandler) handleGetTeams(w http.ResponseWriter, r *http.Reques
 
 	encodeJSON(w, filteredTeams, handler.Logger)
 }
+
+// handleGetAPIKeys handles GET requests on /users/:id/api_keys
+func (handler *UserHandler) handleGetAPIKeys(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	userID, err := strconv.Atoi(id)
+	if err != nil {
+		httperror.WriteErrorResponse(w, err, http.StatusBadRequest, handler.Logger)
+		return
+	}
+
+	tokenData, err := security.RetrieveTokenData(r)
+	if err != nil {
+		httperror.WriteErrorResponse(w, err, http.StatusInternalServerError, handler.Logger)
+		return
+	}
+
+	if tokenData.Role != portainer.AdministratorRole && tokenData.ID != portainer.UserID(userID) {
+		httperror.WriteErrorResponse(w, portainer.ErrUnauthorized, http.StatusForbidden, handler.Logger)
+		return
+	}
+
+	apiKeys, err := handler.APIKeyService.APIKeysByUserID(portainer.UserID(userID))
+	if err != nil {
+		httperror.WriteErrorResponse(w, err, http.StatusInternalServerError, handler.Logger)
+		return
+	}
+
+	for i := range apiKeys {
+		apiKeys[i].Digest = ""
+	}
+
+	encodeJSON(w, apiKeys, handler.Logger)
+}
+
+// handlePostAPIKeys handles POST requests on /users/:id/api_keys
+// The generated key is only returned in the response of this request.
+func (handler *UserHandler) handlePostAPIKeys(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	userID, err := strconv.Atoi(id)
+	if err != nil {
+		httperror.WriteErrorResponse(w, err, http.StatusBadRequest, handler.Logger)
+		return
+	}
+
+	tokenData, err := security.RetrieveTokenData(r)
+	if err != nil {
+		httperror.WriteErrorResponse(w, err, http.StatusInternalServerError, handler.Logger)
+		return
+	}
+
+	// Keys can only be created by their owner, an administrator could otherwise impersonate any user.
+	if tokenData.ID != portainer.UserID(userID) {
+		httperror.WriteErrorResponse(w, portainer.ErrUnauthorized, http.StatusForbidden, handler.Logger)
+		return
+	}
+
+	var req postAPIKeysRequest
+	if err = json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httperror.WriteErrorResponse(w, ErrInvalidJSON, http.StatusBadRequest, handler.Logger)
+		return
+	}
+
+	_, err = govalidator.ValidateStruct(req)
+	if err != nil {
+		httperror.WriteErrorResponse(w, ErrInvalidRequestFormat, http.StatusBadRequest, handler.Logger)
+		return
+	}
+
+	now := time.Now().Unix()
+	if req.ExpiresAt != 0 && req.ExpiresAt <= now {
+		httperror.WriteErrorResponse(w, ErrInvalidRequestFormat, http.StatusBadRequest, handler.Logger)
+		return
+	}
+
+	_, err = handler.UserService.User(portainer.UserID(userID))
+	if err == portainer.ErrUserNotFound {
+		httperror.WriteErrorResponse(w, err, http.StatusNotFound, handler.Logger)
+		return
+	} else if err != nil {
+		httperror.WriteErrorResponse(w, err, http.StatusInternalServerError, handler.Logger)
+		return
+	}
+
+	key, err := crypto.GenerateAPIKey()
+	if err != nil {
+		httperror.WriteErrorResponse(w, err, http.StatusInternalServerError, handler.Logger)
+		return
+	}
+
+	apiKey := &portainer.APIKey{
+		UserID:    portainer.UserID(userID),
+		Name:      req.Name,
+		Prefix:    key[:len(crypto.APIKeyPrefix)+4],
+		Digest:    crypto.HashAPIKey(key),
+		CreatedAt: now,
+		ExpiresAt: req.ExpiresAt,
+	}
+
+	err = handler.APIKeyService.CreateAPIKey(apiKey)
+	if err != nil {
+		httperror.WriteErrorResponse(w, err, http.StatusInternalServerError, handler.Logger)
+		return
+	}
+
+	encodeJSON(w, &postAPIKeysResponse{ID: int(apiKey.ID), Key: key}, handler.Logger)
+}
+
+type postAPIKeysRequest struct {
+	Name      string `valid:"required"`
+	ExpiresAt int64  `valid:"-"`
+}
+
+type postAPIKeysResponse struct {
+	ID  int    `json:"Id"`
+	Key string `json:"Key"`
+}
+
+// handleDeleteAPIKey handles DELETE requests on /users/:id/api_keys/:keyId
+func (handler *UserHandler) handleDeleteAPIKey(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+	keyID := vars["keyId"]
+
+	userID, err := strconv.Atoi(id)
+	if err != nil {
+		httperror.WriteErrorResponse(w, err, http.StatusBadRequest, handler.Logger)
+		return
+	}
+
+	apiKeyID, err := strconv.Atoi(keyID)
+	if err != nil {
+		httperror.WriteErrorResponse(w, err, http.StatusBadRequest, handler.Logger)
+		return
+	}
+
+	tokenData, err := security.RetrieveTokenData(r)
+	if err != nil {
+		httperror.WriteErrorResponse(w, err, http.StatusInternalServerError, handler.Logger)
+		return
+	}
+
+	if tokenData.Role != portainer.AdministratorRole && tokenData.ID != portainer.UserID(userID) {
+		httperror.WriteErrorResponse(w, portainer.ErrUnauthorized, http.StatusForbidden, handler.Logger)
+		return
+	}
+
+	apiKey, err := handler.APIKeyService.APIKey(portainer.APIKeyID(apiKeyID))
+	if err == portainer.ErrAPIKeyNotFound {
+		httperror.WriteErrorResponse(w, err, http.StatusNotFound, handler.Logger)
+		return
+	} else if err != nil {
+		httperror.WriteErrorResponse(w, err, http.StatusInternalServerError, handler.Logger)
+		return
+	}
+
+	if apiKey.UserID != portainer.UserID(userID) {
+		httperror.WriteErrorResponse(w, portainer.ErrAPIKeyNotFound, http.StatusNotFound, handler.Logger)
+		return
+	}
+
+	err = handler.APIKeyService.DeleteAPIKey(apiKey.ID)
+	if err != nil {
+		httperror.WriteErrorResponse(w, err, http.StatusInternalServerError, handler.Logger)
+		return
+	}
+}
+
+// handlePostTwoFactor handles POST requests on /users/:id/2fa
+// It starts the two-factor authentication enrollment of the user.
+func (handler *UserHandler) handlePostTwoFactor(w http.ResponseWriter, r *http.Request) {
+	user, err := handler.retrieveSelf(r)
+	if err != nil {
+		writeRetrieveUserError(w, err, handler.Logger)
+		return
+	}
+
+	if user.TOTPEnabled {
+		httperror.WriteErrorResponse(w, portainer.ErrTwoFactorAlreadyEnabled, http.StatusConflict, handler.Logger)
+		return
+	}
+
+	enrollment, err := startTwoFactorEnrollment(user, handler.UserService, handler.CryptoService)
+	if err != nil {
+		httperror.WriteErrorResponse(w, err, http.StatusInternalServerError, handler.Logger)
+		return
+	}
+
+	encodeJSON(w, enrollment, handler.Logger)
+}
+
+// handlePostTwoFactorVerify handles POST requests on /users/:id/2fa/verify
+// It enables the two-factor authentication once a first code has been validated.
+func (handler *UserHandler) handlePostTwoFactorVerify(w http.ResponseWriter, r *http.Request) {
+	var req postTwoFactorVerifyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httperror.WriteErrorResponse(w, ErrInvalidJSON, http.StatusBadRequest, handler.Logger)
+		return
+	}
+
+	_, err := govalidator.ValidateStruct(req)
+	if err != nil {
+		httperror.WriteErrorResponse(w, ErrInvalidRequestFormat, http.StatusBadRequest, handler.Logger)
+		return
+	}
+
+	user, err := handler.retrieveSelf(r)
+	if err != nil {
+		writeRetrieveUserError(w, err, handler.Logger)
+		return
+	}
+
+	if user.TOTPEnabled {
+		httperror.WriteErrorResponse(w, portainer.ErrTwoFactorAlreadyEnabled, http.StatusConflict, handler.Logger)
+		return
+	}
+
+	if user.TOTPSecret == "" {
+		httperror.WriteErrorResponse(w, portainer.ErrTwoFactorNotEnrolled, http.StatusBadRequest, handler.Logger)
+		return
+	}
+
+	if !validateTwoFactorCode(user, req.Code, "", handler.CryptoService) {
+		httperror.WriteErrorResponse(w, portainer.ErrInvalidTwoFactorCode, http.StatusUnprocessableEntity, handler.Logger)
+		return
+	}
+
+	user.TOTPEnabled = true
+	err = handler.UserService.UpdateUser(user.ID, user)
+	if err != nil {
+		httperror.WriteErrorResponse(w, err, http.StatusInternalServerError, handler.Logger)
+		return
+	}
+}
+
+type postTwoFactorVerifyRequest struct {
+	Code string `valid:"required"`
+}
+
+// handleDeleteTwoFactor handles DELETE requests on /users/:id/2fa
+// Administrators can disable the two-factor authentication of any user, e.g. after the loss of a device.
+func (handler *UserHandler) handleDeleteTwoFactor(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	userID, err := strconv.Atoi(id)
+	if err != nil {
+		httperror.WriteErrorResponse(w, err, http.StatusBadRequest, handler.Logger)
+		return
+	}
+
+	tokenData, err := security.RetrieveTokenData(r)
+	if err != nil {
+		httperror.WriteErrorResponse(w, err, http.StatusInternalServerError, handler.Logger)
+		return
+	}
+
+	if tokenData.Role != portainer.AdministratorRole && tokenData.ID != portainer.UserID(userID) {
+		httperror.WriteErrorResponse(w, portainer.ErrUnauthorized, http.StatusForbidden, handler.Logger)
+		return
+	}
+
+	user, err := handler.UserService.User(portainer.UserID(userID))
+	if err != nil {
+		writeRetrieveUserError(w, err, handler.Logger)
+		return
+	}
+
+	user.TOTPEnabled = false
+	user.TOTPSecret = ""
+	user.TOTPRecoveryCodes = nil
+	user.TOTPLastCounter = 0
+
+	err = handler.UserService.UpdateUser(user.ID, user)
+	if err != nil {
+		httperror.WriteErrorResponse(w, err, http.StatusInternalServerError, handler.Logger)
+		return
+	}
+}
+
+// retrieveSelf returns the user identified in the URL, which must be the user associated to the request.
+func (handler *UserHandler) retrieveSelf(r *http.Request) (*portainer.User, error) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	userID, err := strconv.Atoi(id)
+	if err != nil {
+		return nil, ErrInvalidQueryFormat
+	}
+
+	tokenData, err := security.RetrieveTokenData(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if tokenData.ID != portainer.UserID(userID) {
+		return nil, portainer.ErrUnauthorized
+	}
+
+	return handler.UserService.User(portainer.UserID(userID))
+}
+
+func writeRetrieveUserError(w http.ResponseWriter, err error, logger *log.Logger) {
+	switch err {
+	case ErrInvalidQueryFormat:
+		httperror.WriteErrorResponse(w, err, http.StatusBadRequest, logger)
+	case portainer.ErrUnauthorized:
+		httperror.WriteErrorResponse(w, err, http.StatusForbidden, logger)
+	case portainer.ErrUserNotFound:
+		httperror.WriteErrorResponse(w, err, http.StatusNotFound, logger)
+	default:
+		httperror.WriteErrorResponse(w, err, http.StatusInternalServerError, logger)
+	}
+}
+
+const (
+	twoFactorIssuer        = "Portainer"
+	twoFactorRecoveryCodes = 10
+)
+
+type twoFactorEnrollmentResponse struct {
+	Secret          string   `json:"Secret"`
+	ProvisioningURI string   `json:"ProvisioningURI"`
+	RecoveryCodes   []string `json:"RecoveryCodes"`
+}
+
+// startTwoFactorEnrollment generates and persists a new TOTP secret and recovery codes for the user.
+// The recovery codes are only returned here, a hash of each code is stored.
+func startTwoFactorEnrollment(user *portainer.User, userService portainer.UserService, cryptoService portainer.CryptoService) (*twoFactorEnrollmentResponse, error) {
+	secret, err := crypto.GenerateTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	recoveryCodes, err := crypto.GenerateRecoveryCodes(twoFactorRecoveryCodes)
+	if err != nil {
+		return nil, err
+	}
+
+	hashedCodes := make([]string, 0, len(recoveryCodes))
+	for _, code := range recoveryCodes {
+		hash, err := cryptoService.Hash(code)
+		if err != nil || hash == "" {
+			return nil, portainer.ErrCryptoHashFailure
+		}
+		hashedCodes = append(hashedCodes, hash)
+	}
+
+	user.TOTPEnabled = false
+	user.TOTPSecret = secret
+	user.TOTPRecoveryCodes = hashedCodes
+	user.TOTPLastCounter = 0
+
+	err = userService.UpdateUser(user.ID, user)
+	if err != nil {
+		return nil, err
+	}
+
+	return &twoFactorEnrollmentResponse{
+		Secret:          secret,
+		ProvisioningURI: crypto.TOTPProvisioningURI(secret, twoFactorIssuer, user.Username),
+		RecoveryCodes:   recoveryCodes,
+	}, nil
+}
+
+// validateTwoFactorCode checks a TOTP code, or a recovery code when two-factor authentication is enabled.
+// A TOTP code cannot be used twice and a recovery code is removed once used, the caller must persist the user.
+func validateTwoFactorCode(user *portainer.User, code, recoveryCode string, cryptoService portainer.CryptoService) bool {
+	if code != "" {
+		step, valid := crypto.ValidateTOTPCode(user.TOTPSecret, code, time.Now())
+		if !valid || step <= user.TOTPLastCounter {
+			return false
+		}
+		user.TOTPLastCounter = step
+		return true
+	}
+
+	if recoveryCode == "" || !user.TOTPEnabled {
+		return false
+	}
+
+	for idx, hash := range user.TOTPRecoveryCodes {
+		if cryptoService.CompareHashAndData(hash, strings.ToLower(recoveryCode)) == nil {
+			user.TOTPRecoveryCodes = append(user.TOTPRecoveryCodes[:idx], user.TOTPRecoveryCodes[idx+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// hideUserSecrets removes the credentials of a user before it is returned by the API.
+func hideUserSecrets(user *portainer.User) {
+	user.Password = ""
+	user.TOTPSecret = ""
+	user.TOTPRecoveryCodes = nil
+	user.TOTPLastCounter = 0
+}
@@ -2,197 +2,539 @@ package handler
 
 import (
 	"bytes"
-	"crypto/tls"
 	"encoding/json"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"log"
-	"net"
 	"net/http"
-	"net/http/httputil"
 	"net/url"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/portainer/portainer"
-	"github.com/portainer/portainer/crypto"
+	httperror "github.com/portainer/portainer/http/error"
+	"github.com/portainer/portainer/http/proxy"
+	"github.com/portainer/portainer/http/security"
 	"golang.org/x/net/websocket"
 )
 
 // WebSocketHandler represents an HTTP API handler for proxying requests to a web socket.
 type WebSocketHandler struct {
 	*mux.Router
-	Logger          *log.Logger
-	EndpointService portainer.EndpointService
+	Logger                 *log.Logger
+	EndpointService        portainer.EndpointService
+	TeamMembershipService  portainer.TeamMembershipService
+	EndpointGroupService   portainer.EndpointGroupService
+	ResourceControlService portainer.ResourceControlService
+	SettingsService        portainer.SettingsService
+	FileService            portainer.FileService
+	EncryptionService      portainer.EncryptionService
+	TunnelService          portainer.TunnelService
+	SSHService             portainer.SSHService
+}
+
+const (
+	// ErrDockerResourceNotFound defines an error raised when a resource cannot be found on a Docker endpoint
+	ErrDockerResourceNotFound = portainer.Error("Docker resource not found")
+	// ErrDockerExecStillRunning defines an error raised when an exec instance is still running after the end of its streams
+	ErrDockerExecStillRunning = portainer.Error("Docker exec instance still running")
+)
+
+const (
+	execResizeMessageType = "resize"
+	execExitMessageType   = "exit"
+	execExitCodeAttempts  = 10
+	execExitCodeInterval  = 100 * time.Millisecond
+)
+
+type (
+	// execStartConfig is the configuration used to start an exec instance.
+	execStartConfig struct {
+		Detach bool
+		Tty    bool
+	}
+
+	// execResizeMessage is the control message sent by the client when the size of its terminal changes.
+	execResizeMessage struct {
+		Type   string
+		Height int
+		Width  int
+	}
+
+	// execExitMessage is the control message sent to the client when the exec instance exits.
+	execExitMessage struct {
+		Type     string
+		ExitCode int
+	}
+
+	// recordedWriter is a writer recording the data successfully written to the underlying writer.
+	recordedWriter struct {
+		io.Writer
+		record func(data []byte) error
+	}
+
+	// webSocketFrame is a websocket frame received along with its payload type.
+	webSocketFrame struct {
+		payloadType byte
+		data        []byte
+	}
+)
+
+// webSocketFrameCodec receives websocket frames without discarding their payload type so that
+// text and binary frames can be handled differently.
+var webSocketFrameCodec = websocket.Codec{
+	Unmarshal: func(data []byte, payloadType byte, v interface{}) error {
+		frame := v.(*webSocketFrame)
+		frame.payloadType = payloadType
+		frame.data = data
+		return nil
+	},
 }
 
 // NewWebSocketHandler returns a new instance of WebSocketHandler.
-func NewWebSocketHandler() *WebSocketHandler {
+func NewWebSocketHandler(bouncer *security.RequestBouncer) *WebSocketHandler {
 	h := &WebSocketHandler{
 		Router: mux.NewRouter(),
 		Logger: log.New(os.Stderr, "", log.LstdFlags),
 	}
-	h.Handle("/websocket/exec", websocket.Handler(h.webSocketDockerExec))
+	h.Handle("/websocket/exec",
+		bouncer.WebSocketAccess(http.HandlerFunc(h.handleWebSocketExec)))
+	h.Handle("/websocket/attach",
+		bouncer.WebSocketAccess(http.HandlerFunc(h.handleWebSocketAttach)))
+	h.Handle("/websocket/logs",
+		bouncer.WebSocketAccess(http.HandlerFunc(h.handleWebSocketLogs)))
 	return h
 }
 
-func (handler *WebSocketHandler) webSocketDockerExec(ws *websocket.Conn) {
-	qry := ws.Request().URL.Query()
-	execID := qry.Get("id")
-	edpID := qry.Get("endpointId")
+// handleWebSocketExec handles requests on /websocket/exec?id=<execID>&endpointId=<endpointID>
+// The connection is upgraded to a websocket once the user is authorized to use the exec instance.
+func (handler *WebSocketHandler) handleWebSocketExec(w http.ResponseWriter, r *http.Request) {
+	execID := r.URL.Query().Get("id")
+	if execID == "" {
+		httperror.WriteErrorResponse(w, ErrInvalidQueryFormat, http.StatusBadRequest, handler.Logger)
+		return
+	}
 
-	parsedID, err := strconv.Atoi(edpID)
-	if err != nil {
-		log.Printf("Unable to parse endpoint ID: %s", err)
+	endpoint, tokenData, ok := handler.retrieveAuthorizedEndpoint(w, r)
+	if !ok {
 		return
 	}
 
-	endpointID := portainer.EndpointID(parsedID)
-	endpoint, err := handler.EndpointService.Endpoint(endpointID)
-	if err != nil {
-		log.Printf("Unable to retrieve endpoint: %s", err)
+	if tokenData.Role != portainer.AdministratorRole {
+		err := handler.checkExecAccessControl(endpoint, execID, tokenData.ID)
+		if err != nil {
+			handler.writeAccessControlError(w, err)
+			return
+		}
+	}
+
+	websocket.Server{
+		Handshake: webSocketHandshake,
+		Handler: func(ws *websocket.Conn) {
+			handler.webSocketDockerExec(ws, endpoint, execID, tokenData)
+		},
+	}.ServeHTTP(w, r)
+}
+
+// handleWebSocketAttach handles requests on /websocket/attach?id=<containerID>&endpointId=<endpointID>
+// The connection is upgraded to a websocket attached to the container stdin, stdout and stderr streams.
+// Attaching to a container requires a read-write access to the container.
+func (handler *WebSocketHandler) handleWebSocketAttach(w http.ResponseWriter, r *http.Request) {
+	containerID := r.URL.Query().Get("id")
+	if containerID == "" {
+		httperror.WriteErrorResponse(w, ErrInvalidQueryFormat, http.StatusBadRequest, handler.Logger)
+		return
+	}
+
+	endpoint, tokenData, ok := handler.retrieveAuthorizedEndpoint(w, r)
+	if !ok {
 		return
 	}
 
-	endpointURL, err := url.Parse(endpoint.URL)
+	containerObject, err := handler.inspectAuthorizedContainer(endpoint, containerID, tokenData, false)
 	if err != nil {
-		log.Printf("Unable to parse endpoint URL: %s", err)
+		handler.writeAccessControlError(w, err)
 		return
 	}
 
-	var host string
-	if endpointURL.Scheme == "tcp" {
-		host = endpointURL.Host
-	} else if endpointURL.Scheme == "unix" {
-		host = endpointURL.Path
+	websocket.Server{
+		Handshake: webSocketHandshake,
+		Handler: func(ws *websocket.Conn) {
+			handler.webSocketDockerAttach(ws, endpoint, containerID, isTTYContainer(containerObject))
+		},
+	}.ServeHTTP(w, r)
+}
+
+// handleWebSocketLogs handles requests on /websocket/logs?id=<containerID>&endpointId=<endpointID>
+// The connection is upgraded to a websocket streaming the container logs. The optional tail, since
+// and timestamps query parameters are forwarded to the Docker API.
+func (handler *WebSocketHandler) handleWebSocketLogs(w http.ResponseWriter, r *http.Request) {
+	qry := r.URL.Query()
+	containerID := qry.Get("id")
+	if containerID == "" {
+		httperror.WriteErrorResponse(w, ErrInvalidQueryFormat, http.StatusBadRequest, handler.Logger)
+		return
 	}
 
-	// Should not be managed here
-	var tlsConfig *tls.Config
-	if endpoint.TLS {
-		tlsConfig, err = crypto.CreateTLSConfiguration(endpoint.TLSCACertPath,
-			endpoint.TLSCertPath,
-			endpoint.TLSKeyPath)
-		if err != nil {
-			log.Fatalf("Unable to create TLS configuration: %s", err)
-			return
-		}
+	endpoint, tokenData, ok := handler.retrieveAuthorizedEndpoint(w, r)
+	if !ok {
+		return
 	}
 
-	if err := hijack(host, endpointURL.Scheme, "POST", "/exec/"+execID+"/start", tlsConfig, true, ws, ws, ws, nil, nil); err != nil {
-		log.Fatalf("error during hijack: %s", err)
+	containerObject, err := handler.inspectAuthorizedContainer(endpoint, containerID, tokenData, true)
+	if err != nil {
+		handler.writeAccessControlError(w, err)
 		return
 	}
+
+	logsQuery := url.Values{}
+	logsQuery.Set("follow", "1")
+	logsQuery.Set("stdout", "1")
+	logsQuery.Set("stderr", "1")
+	for _, parameter := range []string{"tail", "since", "timestamps"} {
+		if value := qry.Get(parameter); value != "" {
+			logsQuery.Set(parameter, value)
+		}
+	}
+
+	websocket.Server{
+		Handshake: webSocketHandshake,
+		Handler: func(ws *websocket.Conn) {
+			handler.webSocketDockerLogs(ws, endpoint, containerID, logsQuery, isTTYContainer(containerObject))
+		},
+	}.ServeHTTP(w, r)
+}
+
+// retrieveAuthorizedEndpoint retrieves the endpoint specified in the request query and checks that the user
+// is authorized to access it. An error response is written when the endpoint cannot be accessed.
+func (handler *WebSocketHandler) retrieveAuthorizedEndpoint(w http.ResponseWriter, r *http.Request) (*portainer.Endpoint, *portainer.TokenData, bool) {
+	parsedID, err := strconv.Atoi(r.URL.Query().Get("endpointId"))
+	if err != nil {
+		httperror.WriteErrorResponse(w, ErrInvalidQueryFormat, http.StatusBadRequest, handler.Logger)
+		return nil, nil, false
+	}
+
+	endpoint, err := handler.EndpointService.Endpoint(portainer.EndpointID(parsedID))
+	if err == portainer.ErrEndpointNotFound {
+		httperror.WriteErrorResponse(w, err, http.StatusNotFound, handler.Logger)
+		return nil, nil, false
+	} else if err != nil {
+		httperror.WriteErrorResponse(w, err, http.StatusInternalServerError, handler.Logger)
+		return nil, nil, false
+	}
+
+	tokenData, err := security.RetrieveTokenData(r)
+	if err != nil {
+		httperror.WriteErrorResponse(w, err, http.StatusInternalServerError, handler.Logger)
+		return nil, nil, false
+	}
+
+	if tokenData.Role != portainer.AdministratorRole && !checkEndpointAccessControl(endpoint, tokenData.ID, handler.TeamMembershipService, handler.EndpointGroupService) {
+		httperror.WriteErrorResponse(w, portainer.ErrEndpointAccessDenied, http.StatusForbidden, handler.Logger)
+		return nil, nil, false
+	}
+
+	return endpoint, tokenData, true
 }
 
-type execConfig struct {
-	Tty    bool
-	Detach bool
+// writeAccessControlError writes the error response associated to an access control check.
+func (handler *WebSocketHandler) writeAccessControlError(w http.ResponseWriter, err error) {
+	if err == portainer.ErrResourceAccessDenied {
+		httperror.WriteErrorResponse(w, err, http.StatusForbidden, handler.Logger)
+	} else if err == ErrDockerResourceNotFound {
+		httperror.WriteErrorResponse(w, err, http.StatusNotFound, handler.Logger)
+	} else {
+		httperror.WriteErrorResponse(w, err, http.StatusInternalServerError, handler.Logger)
+	}
 }
 
-// hijack allows to upgrade an HTTP connection to a TCP connection
-// It redirects IO streams for stdin, stdout and stderr to a websocket
-func hijack(addr, scheme, method, path string, tlsConfig *tls.Config, setRawTerminal bool, in io.ReadCloser, stdout, stderr io.Writer, started chan io.Closer, data interface{}) error {
-	execConfig := &execConfig{
-		Tty:    true,
-		Detach: false,
+// checkExecAccessControl resolves the container associated to an exec instance and checks that the user
+// has a read-write access to this container based on resource controls.
+func (handler *WebSocketHandler) checkExecAccessControl(endpoint *portainer.Endpoint, execID string, userID portainer.UserID) error {
+	client, err := newDockerClient(endpoint, handler.EncryptionService, handler.TunnelService, handler.SSHService)
+	if err != nil {
+		return err
 	}
 
-	buf, err := json.Marshal(execConfig)
+	execObject, err := client.inspect("/exec/" + url.PathEscape(execID) + "/json")
 	if err != nil {
-		return fmt.Errorf("error marshaling exec config: %s", err)
+		return err
 	}
 
-	rdr := bytes.NewReader(buf)
+	containerID, ok := execObject["ContainerID"].(string)
+	if !ok {
+		return ErrDockerResourceNotFound
+	}
+
+	tokenData := &portainer.TokenData{ID: userID, Role: portainer.StandardUserRole}
+	_, err = handler.inspectAuthorizedContainer(endpoint, containerID, tokenData, false)
+	return err
+}
+
+// inspectAuthorizedContainer inspects a container and checks that the user has access to it based on resource
+// controls, the same way the Docker API proxy does. A read-write access is required unless readOnly is set.
+func (handler *WebSocketHandler) inspectAuthorizedContainer(endpoint *portainer.Endpoint, containerID string, tokenData *portainer.TokenData, readOnly bool) (map[string]interface{}, error) {
+	client, err := newDockerClient(endpoint, handler.EncryptionService, handler.TunnelService, handler.SSHService)
+	if err != nil {
+		return nil, err
+	}
 
-	req, err := http.NewRequest(method, path, rdr)
+	containerObject, err := client.inspect("/containers/" + url.PathEscape(containerID) + "/json")
 	if err != nil {
-		return fmt.Errorf("error during hijack request: %s", err)
+		return nil, err
 	}
 
-	req.Header.Set("User-Agent", "Docker-Client")
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Connection", "Upgrade")
-	req.Header.Set("Upgrade", "tcp")
-	req.Host = addr
+	if tokenData.Role == portainer.AdministratorRole {
+		return containerObject, nil
+	}
 
-	var (
-		dial    net.Conn
-		dialErr error
-	)
+	memberships, err := handler.TeamMembershipService.TeamMembershipsByUserID(tokenData.ID)
+	if err != nil {
+		return nil, err
+	}
 
-	if tlsConfig == nil {
-		dial, dialErr = net.Dial(scheme, addr)
-	} else {
-		dial, dialErr = tls.Dial(scheme, addr, tlsConfig)
+	userTeamIDs := make([]portainer.TeamID, 0)
+	for _, membership := range memberships {
+		userTeamIDs = append(userTeamIDs, membership.TeamID)
 	}
 
-	if dialErr != nil {
-		return dialErr
+	resourceControls, err := handler.ResourceControlService.ResourceControls()
+	if err != nil {
+		return nil, err
 	}
 
-	// When we set up a TCP connection for hijack, there could be long periods
-	// of inactivity (a long running command with no output) that in certain
-	// network setups may cause ECONNTIMEOUT, leaving the client in an unknown
-	// state. Setting TCP KeepAlive on the socket connection will prohibit
-	// ECONNTIMEOUT unless the socket connection truly is broken
-	if tcpConn, ok := dial.(*net.TCPConn); ok {
-		tcpConn.SetKeepAlive(true)
-		tcpConn.SetKeepAlivePeriod(30 * time.Second)
+	if !proxy.AuthorizedContainerOperation(containerObject, resourceControls, tokenData.ID, userTeamIDs, readOnly) {
+		return nil, portainer.ErrResourceAccessDenied
 	}
+	return containerObject, nil
+}
+
+// webSocketDockerExec starts an exec instance and streams it over the websocket. Text frames carry the
+// terminal input and output while binary frames carry control messages: the client can send resize
+// messages and an exit message containing the exit code of the process is sent when the session ends.
+// The session is recorded when the recording of exec sessions is enabled in the settings.
+func (handler *WebSocketHandler) webSocketDockerExec(ws *websocket.Conn, endpoint *portainer.Endpoint, execID string, tokenData *portainer.TokenData) {
+	client, err := newDockerClient(endpoint, handler.EncryptionService, handler.TunnelService, handler.SSHService)
 	if err != nil {
-		return err
+		handler.Logger.Printf("Unable to create Docker client: %s", err)
+		return
 	}
-	clientconn := httputil.NewClientConn(dial, nil)
-	defer clientconn.Close()
 
-	// Server hijacks the connection, error 'connection closed' expected
-	clientconn.Do(req)
+	execPath := "/exec/" + url.PathEscape(execID)
+	execObject, err := client.inspect(execPath + "/json")
+	if err != nil {
+		handler.Logger.Printf("Unable to inspect exec instance %s: %s", execID, err)
+		return
+	}
+	tty := isTTYExec(execObject)
 
-	rwc, br := clientconn.Hijack()
-	defer rwc.Close()
+	recorder, err := handler.createExecRecorder(endpoint, execID, execObject, tokenData)
+	if err != nil {
+		handler.Logger.Printf("Unable to record exec instance %s: %s", execID, err)
+		return
+	}
+	if recorder != nil {
+		defer recorder.Close()
+	}
 
-	if started != nil {
-		started <- rwc
+	startConfig, err := json.Marshal(execStartConfig{Detach: false, Tty: tty})
+	if err != nil {
+		handler.Logger.Printf("Unable to encode exec start configuration: %s", err)
+		return
 	}
 
-	var receiveStdout chan error
+	conn, err := hijackDockerRequest(endpoint, handler.EncryptionService, handler.TunnelService, handler.SSHService, http.MethodPost, execPath+"/start", bytes.NewReader(startConfig))
+	if err != nil {
+		handler.Logger.Printf("Unable to start exec instance %s: %s", execID, err)
+		return
+	}
+	defer conn.Close()
 
-	if stdout != nil || stderr != nil {
-		go func() (err error) {
-			if setRawTerminal && stdout != nil {
-				_, err = io.Copy(stdout, br)
-			}
-			return err
-		}()
+	go func() {
+		handler.forwardExecInput(ws, conn, client, execPath, recorder)
+		conn.CloseWrite()
+	}()
+
+	var output io.Writer = ws
+	if recorder != nil {
+		output = &recordedWriter{Writer: ws, record: recorder.RecordOutput}
+	}
+
+	err = copyDockerStream(output, conn, tty)
+	if err != nil {
+		handler.Logger.Printf("Error while streaming exec instance %s: %s", execID, err)
+		return
+	}
+
+	exitCode, err := waitExecExitCode(client, execPath)
+	if err != nil {
+		handler.Logger.Printf("Unable to retrieve the exit code of exec instance %s: %s", execID, err)
+		return
+	}
+
+	message, err := json.Marshal(execExitMessage{Type: execExitMessageType, ExitCode: exitCode})
+	if err != nil {
+		return
 	}
+	websocket.Message.Send(ws, message)
+}
 
-	go func() error {
-		if in != nil {
-			io.Copy(rwc, in)
+// forwardExecInput forwards the input received in text frames to an exec instance and handles the control
+// messages received in binary frames until the websocket is closed. The input and the resize events are recorded
+// when recorder is not nil.
+func (handler *WebSocketHandler) forwardExecInput(ws *websocket.Conn, conn io.Writer, client *dockerClient, execPath string, recorder portainer.ExecRecorder) {
+	for {
+		var frame webSocketFrame
+		err := webSocketFrameCodec.Receive(ws, &frame)
+		if err != nil {
+			return
 		}
 
-		if conn, ok := rwc.(interface {
-			CloseWrite() error
-		}); ok {
-			if err := conn.CloseWrite(); err != nil {
+		if frame.payloadType != websocket.BinaryFrame {
+			_, err = conn.Write(frame.data)
+			if err != nil {
+				return
+			}
+			if recorder != nil {
+				recorder.RecordInput(frame.data)
 			}
+			continue
+		}
+
+		var message execResizeMessage
+		err = json.Unmarshal(frame.data, &message)
+		if err != nil || message.Type != execResizeMessageType || message.Height <= 0 || message.Width <= 0 {
+			continue
 		}
-		return nil
-	}()
 
-	if stdout != nil || stderr != nil {
-		if err := <-receiveStdout; err != nil {
-			return err
+		err = client.post(execPath + "/resize?h=" + strconv.Itoa(message.Height) + "&w=" + strconv.Itoa(message.Width))
+		if err != nil {
+			handler.Logger.Printf("Unable to resize exec instance: %s", err)
+		} else if recorder != nil {
+			recorder.RecordResize(message.Width, message.Height)
 		}
 	}
-	go func() {
-		for {
-			fmt.Println(br)
+}
+
+// createExecRecorder starts the recording of an exec session. It returns a nil recorder when the recording
+// of exec sessions is disabled.
+func (handler *WebSocketHandler) createExecRecorder(endpoint *portainer.Endpoint, execID string, execObject map[string]interface{}, tokenData *portainer.TokenData) (portainer.ExecRecorder, error) {
+	settings, err := handler.SettingsService.Settings()
+	if err != nil {
+		return nil, err
+	}
+
+	if !settings.ExecRecordingSettings.Enabled {
+		return nil, nil
+	}
+
+	containerID, _ := execObject["ContainerID"].(string)
+	recording := &portainer.ExecRecording{
+		ID:          execID,
+		EndpointID:  endpoint.ID,
+		ContainerID: containerID,
+		UserID:      tokenData.ID,
+		Username:    tokenData.Username,
+	}
+	return handler.FileService.CreateExecRecording(recording)
+}
+
+// waitExecExitCode returns the exit code of an exec instance. Docker can still report the instance as running
+// for a short time after the end of its output stream.
+func waitExecExitCode(client *dockerClient, execPath string) (int, error) {
+	for attempt := 0; ; attempt++ {
+		execObject, err := client.inspect(execPath + "/json")
+		if err != nil {
+			return 0, err
+		}
+
+		running, _ := execObject["Running"].(bool)
+		exitCode, ok := execObject["ExitCode"].(float64)
+		if !running && ok {
+			return int(exitCode), nil
+		} else if attempt == execExitCodeAttempts {
+			return 0, ErrDockerExecStillRunning
 		}
+		time.Sleep(execExitCodeInterval)
+	}
+}
+
+func (handler *WebSocketHandler) webSocketDockerAttach(ws *websocket.Conn, endpoint *portainer.Endpoint, containerID string, tty bool) {
+	path := "/containers/" + url.PathEscape(containerID) + "/attach?stream=1&stdin=1&stdout=1&stderr=1"
+	conn, err := hijackDockerRequest(endpoint, handler.EncryptionService, handler.TunnelService, handler.SSHService, http.MethodPost, path, nil)
+	if err != nil {
+		handler.Logger.Printf("Unable to attach to container %s: %s", containerID, err)
+		return
+	}
+	defer conn.Close()
+
+	go func() {
+		io.Copy(conn, ws)
+		conn.CloseWrite()
+	}()
+
+	err = copyDockerStream(ws, conn, tty)
+	if err != nil {
+		handler.Logger.Printf("Error while streaming container %s: %s", containerID, err)
+	}
+}
+
+func (handler *WebSocketHandler) webSocketDockerLogs(ws *websocket.Conn, endpoint *portainer.Endpoint, containerID string, query url.Values, tty bool) {
+	client, err := newDockerClient(endpoint, handler.EncryptionService, handler.TunnelService, handler.SSHService)
+	if err != nil {
+		handler.Logger.Printf("Unable to create Docker client: %s", err)
+		return
+	}
+
+	resp, err := client.stream(http.MethodGet, "/containers/"+url.PathEscape(containerID)+"/logs?"+query.Encode())
+	if err != nil {
+		handler.Logger.Printf("Unable to retrieve the logs of container %s: %s", containerID, err)
+		return
+	}
+	defer resp.Body.Close()
+
+	// The logs are streamed until the client closes the websocket.
+	go func() {
+		io.Copy(ioutil.Discard, ws)
+		resp.Body.Close()
 	}()
 
-	return nil
+	copyDockerStream(ws, resp.Body, tty)
+}
+
+// webSocketHandshake validates the origin of a websocket connection and selects its subprotocol.
+// Browsers require one of the requested subprotocols to be selected, the subprotocol used
+// to authenticate the connection is only selected when no other subprotocol is requested.
+func webSocketHandshake(config *websocket.Config, req *http.Request) error {
+	var err error
+	config.Origin, err = websocket.Origin(config, req)
+	if err == nil && config.Origin == nil {
+		return fmt.Errorf("null origin")
+	}
+
+	protocols := make([]string, 0)
+	for _, protocol := range config.Protocol {
+		if !strings.HasPrefix(protocol, security.WebSocketTokenProtocolPrefix) {
+			protocols = append(protocols, protocol)
+		}
+	}
+
+	if len(protocols) > 0 {
+		config.Protocol = protocols[:1]
+	} else if len(config.Protocol) > 0 {
+		config.Protocol = config.Protocol[:1]
+	}
+	return err
+}
+
+func (w *recordedWriter) Write(p []byte) (int, error) {
+	n, err := w.Writer.Write(p)
+	if n > 0 {
+		w.record(p[:n])
+	}
+	return n, err
 }
@@ -1,21 +1,91 @@
 package proxy
 
-import "github.com/portainer/portainer"
+import (
+	"net/http"
+	"strings"
 
+	"github.com/portainer/portainer"
+)
+
+// canUserAccessResource checks if the user has been granted any access level on the resource,
+// either directly or through one of his teams.
 func canUserAccessResource(userID portainer.UserID, userTeamIDs []portainer.TeamID, resourceControl *portainer.ResourceControl) bool {
+	_, ok := getUserResourceAccessLevel(userID, userTeamIDs, resourceControl)
+	return ok
+}
+
+// canUserModifyResource checks if the user has been granted a read-write access on the resource,
+// either directly or through one of his teams.
+func canUserModifyResource(userID portainer.UserID, userTeamIDs []portainer.TeamID, resourceControl *portainer.ResourceControl) bool {
+	accessLevel, ok := getUserResourceAccessLevel(userID, userTeamIDs, resourceControl)
+	return ok && accessLevel == portainer.ReadWriteAccessLevel
+}
+
+// getUserResourceAccessLevel returns the highest access level granted to the user on the resource.
+// Accesses that do not specify a level are considered read-write.
+func getUserResourceAccessLevel(userID portainer.UserID, userTeamIDs []portainer.TeamID, resourceControl *portainer.ResourceControl) (portainer.ResourceAccessLevel, bool) {
+	granted := false
+
 	for _, authorizedUserAccess := range resourceControl.UserAccesses {
 		if userID == authorizedUserAccess.UserID {
-			return true
+			granted = true
+			if authorizedUserAccess.AccessLevel != portainer.ReadOnlyAccessLevel {
+				return portainer.ReadWriteAccessLevel, true
+			}
 		}
 	}
 
 	for _, authorizedTeamAccess := range resourceControl.TeamAccesses {
 		for _, userTeamID := range userTeamIDs {
 			if userTeamID == authorizedTeamAccess.TeamID {
-				return true
+				granted = true
+				if authorizedTeamAccess.AccessLevel != portainer.ReadOnlyAccessLevel {
+					return portainer.ReadWriteAccessLevel, true
+				}
 			}
 		}
 	}
 
-	return false
+	return portainer.ReadOnlyAccessLevel, granted
+}
+
+// isReadOperation checks if a request only reads the state of a resource (inspect, logs, stats, list...).
+// Attaching to a container through a websocket uses a GET request but is considered as a mutating operation.
+func isReadOperation(request *http.Request) bool {
+	if request.Method != http.MethodGet && request.Method != http.MethodHead {
+		return false
+	}
+	return !strings.HasSuffix(request.URL.Path, "/attach/ws")
+}
+
+// AuthorizedContainerOperation checks that a user can execute an operation on a container based on the resource
+// controls associated to the container and to its optional Swarm service, the same way the Docker API proxy does.
+// A read-write access is required unless readOnly is set. The container must be the response of a container inspect request.
+func AuthorizedContainerOperation(container map[string]interface{}, resourceControls []portainer.ResourceControl, userID portainer.UserID, userTeamIDs []portainer.TeamID, readOnly bool) bool {
+	resourceIDs := make([]string, 0)
+	if containerID, ok := container[containerIdentifier].(string); ok {
+		resourceIDs = append(resourceIDs, containerID)
+	}
+
+	containerLabels := extractContainerLabelsFromContainerInspectObject(container)
+	if serviceID, ok := containerLabels[containerLabelForServiceIdentifier].(string); ok {
+		resourceIDs = append(resourceIDs, serviceID)
+	}
+
+	for _, resourceID := range resourceIDs {
+		resourceControl := getResourceControlByResourceID(resourceID, resourceControls)
+		if resourceControl == nil {
+			continue
+		}
+
+		if !canUserAccessResource(userID, userTeamIDs, resourceControl) {
+			return false
+		}
+
+		if !readOnly && !canUserModifyResource(userID, userTeamIDs, resourceControl) {
+			return false
+		}
+	}
+
+	return true
 }
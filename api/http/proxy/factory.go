@@ -14,17 +14,22 @@ type proxyFactory struct {
 	ResourceControlService portainer.ResourceControlService
 	TeamMembershipService  portainer.TeamMembershipService
 	SettingsService        portainer.SettingsService
+	RegistryService        portainer.RegistryService
+	DockerHubService       portainer.DockerHubService
+	EncryptionService      portainer.EncryptionService
+	TunnelService          portainer.TunnelService
+	SSHService             portainer.SSHService
 }
 
-func (factory *proxyFactory) newHTTPProxy(u *url.URL) http.Handler {
+func (factory *proxyFactory) newHTTPProxy(u *url.URL, endpoint *portainer.Endpoint) http.Handler {
 	u.Scheme = "http"
-	return factory.createReverseProxy(u)
+	return factory.createReverseProxy(u, endpoint)
 }
 
 func (factory *proxyFactory) newHTTPSProxy(u *url.URL, endpoint *portainer.Endpoint) (http.Handler, error) {
 	u.Scheme = "https"
-	proxy := factory.createReverseProxy(u)
-	config, err := crypto.CreateTLSConfiguration(endpoint.TLSCACertPath, endpoint.TLSCertPath, endpoint.TLSKeyPath)
+	proxy := factory.createReverseProxy(u, endpoint)
+	config, err := crypto.CreateTLSConfiguration(endpoint.TLSCACertPath, endpoint.TLSCertPath, endpoint.TLSKeyPath, factory.EncryptionService)
 	if err != nil {
 		return nil, err
 	}
@@ -33,25 +38,61 @@ func (factory *proxyFactory) newHTTPSProxy(u *url.URL, endpoint *portainer.Endpo
 	return proxy, nil
 }
 
-func (factory *proxyFactory) newSocketProxy(path string) http.Handler {
+func (factory *proxyFactory) newSocketProxy(path string, endpoint *portainer.Endpoint) http.Handler {
 	proxy := &socketProxy{}
 	transport := &proxyTransport{
-		ResourceControlService: factory.ResourceControlService,
-		TeamMembershipService:  factory.TeamMembershipService,
-		SettingsService:        factory.SettingsService,
-		dockerTransport:        newSocketTransport(path),
+		ResourceControlService:  factory.ResourceControlService,
+		TeamMembershipService:   factory.TeamMembershipService,
+		SettingsService:         factory.SettingsService,
+		RegistryService:         factory.RegistryService,
+		DockerHubService:        factory.DockerHubService,
+		ResourceOwnershipPolicy: endpoint.ResourceOwnershipPolicy,
+		dockerTransport:         newSocketTransport(path),
 	}
 	proxy.Transport = transport
 	return proxy
 }
 
-func (factory *proxyFactory) createReverseProxy(u *url.URL) *httputil.ReverseProxy {
+func (factory *proxyFactory) newAgentProxy(endpoint *portainer.Endpoint) http.Handler {
+	proxy := &socketProxy{}
+	transport := &proxyTransport{
+		ResourceControlService:  factory.ResourceControlService,
+		TeamMembershipService:   factory.TeamMembershipService,
+		SettingsService:         factory.SettingsService,
+		RegistryService:         factory.RegistryService,
+		DockerHubService:        factory.DockerHubService,
+		ResourceOwnershipPolicy: endpoint.ResourceOwnershipPolicy,
+		dockerTransport:         newAgentTransport(factory.TunnelService, endpoint.ID),
+	}
+	proxy.Transport = transport
+	return proxy
+}
+
+func (factory *proxyFactory) newSSHProxy(endpoint *portainer.Endpoint) http.Handler {
+	proxy := &socketProxy{}
+	transport := &proxyTransport{
+		ResourceControlService:  factory.ResourceControlService,
+		TeamMembershipService:   factory.TeamMembershipService,
+		SettingsService:         factory.SettingsService,
+		RegistryService:         factory.RegistryService,
+		DockerHubService:        factory.DockerHubService,
+		ResourceOwnershipPolicy: endpoint.ResourceOwnershipPolicy,
+		dockerTransport:         newSSHTransport(factory.SSHService, endpoint),
+	}
+	proxy.Transport = transport
+	return proxy
+}
+
+func (factory *proxyFactory) createReverseProxy(u *url.URL, endpoint *portainer.Endpoint) *httputil.ReverseProxy {
 	proxy := newSingleHostReverseProxyWithHostHeader(u)
 	transport := &proxyTransport{
-		ResourceControlService: factory.ResourceControlService,
-		TeamMembershipService:  factory.TeamMembershipService,
-		SettingsService:        factory.SettingsService,
-		dockerTransport:        newHTTPTransport(),
+		ResourceControlService:  factory.ResourceControlService,
+		TeamMembershipService:   factory.TeamMembershipService,
+		SettingsService:         factory.SettingsService,
+		RegistryService:         factory.RegistryService,
+		DockerHubService:        factory.DockerHubService,
+		ResourceOwnershipPolicy: endpoint.ResourceOwnershipPolicy,
+		dockerTransport:         newHTTPTransport(),
 	}
 	proxy.Transport = transport
 	return proxy
@@ -0,0 +1,64 @@
+package proxy
+
+import (
+	"net/http"
+
+	"github.com/portainer/portainer"
+)
+
+const (
+	// ErrDockerImageIdentifierNotFound defines an error raised when Portainer is unable to find a image identifier
+	ErrDockerImageIdentifierNotFound = portainer.Error("Docker image identifier not found")
+	imageIdentifier                  = "Id"
+)
+
+// imageListOperation extracts the response as a JSON array, loop through the image array
+// decorate and/or filter the images based on resource controls before rewriting the response
+func imageListOperation(request *http.Request, response *http.Response, executor *operationExecutor) error {
+	var err error
+	// ImageList response is a JSON array
+	// https://docs.docker.com/engine/api/v1.28/#operation/ImageList
+	responseArray, err := getResponseAsJSONArray(response)
+	if err != nil {
+		return err
+	}
+
+	if executor.operationContext.isAdmin {
+		responseArray, err = decorateImageList(responseArray, executor.operationContext.resourceControls)
+	} else {
+		responseArray, err = filterImageList(responseArray, executor.operationContext.resourceControls, executor.operationContext.userID, executor.operationContext.userTeamIDs)
+	}
+	if err != nil {
+		return err
+	}
+
+	return rewriteResponse(response, responseArray, http.StatusOK)
+}
+
+// imageInspectOperation extracts the response as a JSON object, verify that the user
+// has access to the image based on resource control and either rewrite an access denied response
+// or a decorated image.
+func imageInspectOperation(request *http.Request, response *http.Response, executor *operationExecutor) error {
+	// ImageInspect response is a JSON object
+	// https://docs.docker.com/engine/api/v1.28/#operation/ImageInspect
+	responseObject, err := getResponseAsJSONOBject(response)
+	if err != nil {
+		return err
+	}
+
+	if responseObject[imageIdentifier] == nil {
+		return ErrDockerImageIdentifierNotFound
+	}
+	imageID := responseObject[imageIdentifier].(string)
+
+	resourceControl := getResourceControlByResourceID(imageID, executor.operationContext.resourceControls)
+	if resourceControl != nil {
+		if executor.operationContext.isAdmin || canUserAccessResource(executor.operationContext.userID, executor.operationContext.userTeamIDs, resourceControl) {
+			responseObject = decorateObject(responseObject, resourceControl)
+		} else {
+			return rewriteAccessDeniedResponse(response)
+		}
+	}
+
+	return rewriteResponse(response, responseObject, http.StatusOK)
+}
@@ -2,12 +2,19 @@ package proxy
 
 import (
 	"net/http"
+	"net/http/httputil"
 	"net/url"
+	"strconv"
 
 	"github.com/orcaman/concurrent-map"
 	"github.com/portainer/portainer"
 )
 
+const (
+	// ErrUnsupportedProxy defines an error raised when the transport of a proxy cannot be retrieved
+	ErrUnsupportedProxy = portainer.Error("Unsupported endpoint proxy")
+)
+
 // Manager represents a service used to manage Docker proxies.
 type Manager struct {
 	proxyFactory *proxyFactory
@@ -15,13 +22,18 @@ type Manager struct {
 }
 
 // NewManager initializes a new proxy Service
-func NewManager(resourceControlService portainer.ResourceControlService, teamMembershipService portainer.TeamMembershipService, settingsService portainer.SettingsService) *Manager {
+func NewManager(resourceControlService portainer.ResourceControlService, teamMembershipService portainer.TeamMembershipService, settingsService portainer.SettingsService, registryService portainer.RegistryService, dockerHubService portainer.DockerHubService, encryptionService portainer.EncryptionService, tunnelService portainer.TunnelService, sshService portainer.SSHService) *Manager {
 	return &Manager{
 		proxies: cmap.New(),
 		proxyFactory: &proxyFactory{
 			ResourceControlService: resourceControlService,
 			TeamMembershipService:  teamMembershipService,
 			SettingsService:        settingsService,
+			RegistryService:        registryService,
+			DockerHubService:       dockerHubService,
+			EncryptionService:      encryptionService,
+			TunnelService:          tunnelService,
+			SSHService:             sshService,
 		},
 	}
 }
@@ -43,14 +55,18 @@ func (manager *Manager) CreateAndRegisterProxy(endpoint *portainer.Endpoint) (ht
 				return nil, err
 			}
 		} else {
-			proxy = manager.proxyFactory.newHTTPProxy(endpointURL)
+			proxy = manager.proxyFactory.newHTTPProxy(endpointURL, endpoint)
 		}
+	} else if endpointURL.Scheme == "agent" {
+		proxy = manager.proxyFactory.newAgentProxy(endpoint)
+	} else if endpointURL.Scheme == "ssh" {
+		proxy = manager.proxyFactory.newSSHProxy(endpoint)
 	} else {
 		// Assume unix:// scheme
-		proxy = manager.proxyFactory.newSocketProxy(endpointURL.Path)
+		proxy = manager.proxyFactory.newSocketProxy(endpointURL.Path, endpoint)
 	}
 
-	manager.proxies.Set(string(endpoint.ID), proxy)
+	manager.proxies.Set(strconv.Itoa(int(endpoint.ID)), proxy)
 	return proxy, nil
 }
 
@@ -63,6 +79,37 @@ func (manager *Manager) GetProxy(key string) http.Handler {
 	return proxy.(http.Handler)
 }
 
+// DockerTransport returns the transport used by the proxy of an endpoint to execute requests against
+// the Docker API, along with the base URL of these requests. The proxy is created and registered when
+// it does not exist yet.
+func (manager *Manager) DockerTransport(endpoint *portainer.Endpoint) (http.RoundTripper, *url.URL, error) {
+	proxy := manager.GetProxy(strconv.Itoa(int(endpoint.ID)))
+	if proxy == nil {
+		var err error
+		proxy, err = manager.CreateAndRegisterProxy(endpoint)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	switch p := proxy.(type) {
+	case *socketProxy:
+		return p.Transport.dockerTransport, &url.URL{Scheme: "http", Host: "unixsocket"}, nil
+	case *httputil.ReverseProxy:
+		transport := p.Transport.(*proxyTransport).dockerTransport
+		endpointURL, err := url.Parse(endpoint.URL)
+		if err != nil {
+			return nil, nil, err
+		}
+		endpointURL.Scheme = "http"
+		if transport.TLSClientConfig != nil {
+			endpointURL.Scheme = "https"
+		}
+		return transport, endpointURL, nil
+	}
+	return nil, nil, ErrUnsupportedProxy
+}
+
 // DeleteProxy deletes the proxy associated to a key
 func (manager *Manager) DeleteProxy(key string) {
 	manager.proxies.Remove(key)
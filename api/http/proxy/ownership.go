@@ -0,0 +1,254 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/portainer/portainer"
+	"github.com/portainer/portainer/http/security"
+)
+
+const (
+	// ErrInvalidResourceTeams defines an error raised when the teams specified for a new resource are invalid
+	ErrInvalidResourceTeams = portainer.Error("Invalid resource teams")
+	// ErrDockerResourceIdentifierNotFound defines an error raised when Portainer is unable to find the identifier of a created resource
+	ErrDockerResourceIdentifierNotFound = portainer.Error("Docker resource identifier not found")
+	// resourceTeamsHeader is the request header used to specify the teams that own a new resource.
+	resourceTeamsHeader = "X-Portainer-Teams"
+	// resourceTeamsLabel is the resource label used to specify the teams that own a new resource.
+	resourceTeamsLabel = "io.portainer.accesscontrol.teams"
+)
+
+// createResourceOperation executes a resource creation request and associates a resource control to the
+// created resource. The resource is owned by the teams specified in the request (X-Portainer-Teams header or
+// io.portainer.accesscontrol.teams label), or follows the resource ownership policy of the endpoint when
+// the resource is created by a standard user. The resource is removed when the resource control cannot be created
+// so that it never stays accessible to every user. Volume creation requests are idempotent: the ownership of a volume
+// that already exists or that is already associated to a resource control is left untouched.
+func (p *proxyTransport) createResourceOperation(request *http.Request, resourceControlType portainer.ResourceControlType, resourcePath, identifier string) (*http.Response, error) {
+	tokenData, err := security.RetrieveTokenData(request)
+	if err != nil {
+		return nil, err
+	}
+
+	teamIDs, err := retrieveRequestedResourceTeams(request)
+	if err == ErrInvalidResourceTeams {
+		response := &http.Response{}
+		err = rewriteResponse(response, err, http.StatusBadRequest)
+		return response, err
+	} else if err != nil {
+		return nil, err
+	}
+	request.Header.Del(resourceTeamsHeader)
+
+	isAdmin := tokenData.Role == portainer.AdministratorRole
+	if len(teamIDs) == 0 && (isAdmin || p.ResourceOwnershipPolicy != portainer.PrivateResourceOwnership && p.ResourceOwnershipPolicy != portainer.TeamResourceOwnership) {
+		return p.executeDockerRequest(request)
+	}
+
+	userTeamIDs := make([]portainer.TeamID, 0)
+	if !isAdmin {
+		teamMemberships, err := p.TeamMembershipService.TeamMembershipsByUserID(tokenData.ID)
+		if err != nil {
+			return nil, err
+		}
+		for _, membership := range teamMemberships {
+			userTeamIDs = append(userTeamIDs, membership.TeamID)
+		}
+
+		for _, teamID := range teamIDs {
+			if !containsTeamID(userTeamIDs, teamID) {
+				return writeAccessDeniedResponse()
+			}
+		}
+	}
+
+	if len(teamIDs) == 0 && p.ResourceOwnershipPolicy == portainer.TeamResourceOwnership {
+		teamIDs = userTeamIDs
+	}
+
+	if resourceControlType == portainer.VolumeResourceControl {
+		exists, err := p.requestedVolumeExists(request)
+		if err != nil {
+			return nil, err
+		}
+		if exists {
+			return p.executeDockerRequest(request)
+		}
+	}
+
+	response, err := p.executeDockerRequest(request)
+	if err != nil || response.StatusCode < 200 || response.StatusCode >= 300 {
+		return response, err
+	}
+
+	responseObject, err := getResponseAsJSONOBject(response)
+	if err != nil {
+		return nil, err
+	}
+
+	resourceID, ok := responseObject[identifier].(string)
+	if !ok {
+		return nil, ErrDockerResourceIdentifierNotFound
+	}
+
+	_, err = p.ResourceControlService.ResourceControlByResourceID(resourceID)
+	if err == nil {
+		return response, rewriteResponse(response, responseObject, response.StatusCode)
+	} else if err != portainer.ErrResourceControlNotFound {
+		return nil, err
+	}
+
+	resourceControl := newOwnershipResourceControl(resourceID, resourceControlType, tokenData.ID, teamIDs)
+	err = p.ResourceControlService.CreateResourceControl(resourceControl)
+	if err != nil {
+		p.removeResource(request, resourcePath+"/"+resourceID)
+		return nil, err
+	}
+
+	return response, rewriteResponse(response, responseObject, response.StatusCode)
+}
+
+// requestedVolumeExists returns true when the volume named in a volume creation request already exists,
+// in which case Docker returns the existing volume instead of creating a new one.
+func (p *proxyTransport) requestedVolumeExists(request *http.Request) (bool, error) {
+	body, err := readRequestBody(request)
+	if err != nil {
+		return false, err
+	}
+
+	var volume struct {
+		Name string
+	}
+	if json.Unmarshal(body, &volume) != nil || volume.Name == "" {
+		return false, nil
+	}
+
+	inspectURL := *request.URL
+	inspectURL.Path = "/volumes/" + volume.Name
+	inspectURL.RawQuery = ""
+
+	inspectRequest, err := http.NewRequest(http.MethodGet, inspectURL.String(), nil)
+	if err != nil {
+		return false, err
+	}
+
+	response, err := p.executeDockerRequest(inspectRequest)
+	if err != nil {
+		return false, err
+	}
+	response.Body.Close()
+
+	return response.StatusCode == http.StatusOK, nil
+}
+
+// removeResource removes a resource created by a request, errors are ignored as there is nothing more
+// that can be done about them.
+func (p *proxyTransport) removeResource(request *http.Request, removePath string) {
+	removeURL := *request.URL
+	removeURL.Path = removePath
+	removeURL.RawQuery = "force=1"
+
+	removeRequest, err := http.NewRequest(http.MethodDelete, removeURL.String(), nil)
+	if err != nil {
+		return
+	}
+
+	response, err := p.executeDockerRequest(removeRequest)
+	if err == nil {
+		response.Body.Close()
+	}
+}
+
+// newOwnershipResourceControl returns a resource control giving access to the specified teams,
+// or to the user only when no team is specified.
+func newOwnershipResourceControl(resourceID string, resourceControlType portainer.ResourceControlType, userID portainer.UserID, teamIDs []portainer.TeamID) *portainer.ResourceControl {
+	resourceControl := &portainer.ResourceControl{
+		ResourceID:     resourceID,
+		SubResourceIDs: []string{},
+		Type:           resourceControlType,
+		UserAccesses:   []portainer.UserResourceAccess{},
+		TeamAccesses:   []portainer.TeamResourceAccess{},
+	}
+
+	if len(teamIDs) == 0 {
+		resourceControl.UserAccesses = append(resourceControl.UserAccesses, portainer.UserResourceAccess{
+			UserID:      userID,
+			AccessLevel: portainer.ReadWriteAccessLevel,
+		})
+		return resourceControl
+	}
+
+	for _, teamID := range teamIDs {
+		resourceControl.TeamAccesses = append(resourceControl.TeamAccesses, portainer.TeamResourceAccess{
+			TeamID:      teamID,
+			AccessLevel: portainer.ReadWriteAccessLevel,
+		})
+	}
+	return resourceControl
+}
+
+// retrieveRequestedResourceTeams returns the teams specified in the request header or, when the header
+// is not set, in the labels of the resource to create. The request body is restored after being read.
+func retrieveRequestedResourceTeams(request *http.Request) ([]portainer.TeamID, error) {
+	if value := request.Header.Get(resourceTeamsHeader); value != "" {
+		return parseResourceTeams(value)
+	}
+
+	body, err := readRequestBody(request)
+	if err != nil {
+		return nil, err
+	}
+
+	var resource struct {
+		Labels map[string]string
+	}
+	if json.Unmarshal(body, &resource) != nil || resource.Labels[resourceTeamsLabel] == "" {
+		return nil, nil
+	}
+
+	return parseResourceTeams(resource.Labels[resourceTeamsLabel])
+}
+
+// readRequestBody returns the body of a request and restores it so that the request can still be executed.
+func readRequestBody(request *http.Request) ([]byte, error) {
+	if request.Body == nil {
+		return nil, nil
+	}
+
+	body, err := ioutil.ReadAll(request.Body)
+	if err != nil {
+		return nil, err
+	}
+	request.Body.Close()
+	request.Body = ioutil.NopCloser(bytes.NewReader(body))
+	return body, nil
+}
+
+// parseResourceTeams parses a comma separated list of team identifiers.
+func parseResourceTeams(value string) ([]portainer.TeamID, error) {
+	teamIDs := make([]portainer.TeamID, 0)
+	for _, item := range strings.Split(value, ",") {
+		teamID, err := strconv.Atoi(strings.TrimSpace(item))
+		if err != nil || teamID <= 0 {
+			return nil, ErrInvalidResourceTeams
+		}
+		if !containsTeamID(teamIDs, portainer.TeamID(teamID)) {
+			teamIDs = append(teamIDs, portainer.TeamID(teamID))
+		}
+	}
+	return teamIDs, nil
+}
+
+func containsTeamID(teamIDs []portainer.TeamID, teamID portainer.TeamID) bool {
+	for _, id := range teamIDs {
+		if id == teamID {
+			return true
+		}
+	}
+	return false
+}
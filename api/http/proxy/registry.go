@@ -0,0 +1,186 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/portainer/portainer"
+	"github.com/portainer/portainer/http/security"
+)
+
+const (
+	// registryAuthenticationHeader is the header used by the Docker API to retrieve registry credentials.
+	registryAuthenticationHeader = "X-Registry-Auth"
+	// dockerHubRegistryHost is the registry used by Docker when an image reference does not specify a registry.
+	dockerHubRegistryHost = "docker.io"
+	// dockerHubServerAddress is the server address used by Docker for DockerHub credentials.
+	dockerHubServerAddress = "https://index.docker.io/v1/"
+)
+
+// registryAuthenticationConfig represents the content of the X-Registry-Auth header.
+// https://docs.docker.com/engine/api/v1.28/#section/Authentication
+type registryAuthenticationConfig struct {
+	Username      string `json:"username"`
+	Password      string `json:"password"`
+	ServerAddress string `json:"serveraddress"`
+}
+
+// registryAuthenticatedOperation resolves the registry hosting an image and injects the credentials of this
+// registry in the request before executing the next operation. When the registry is managed by Portainer,
+// the X-Registry-Auth header sent by the client is discarded and a non-administrator user must be authorized
+// to use the registry. The DockerHub credentials are injected for the images hosted on DockerHub, this operation
+// must not be used to push these images. Requests targeting another registry are executed unchanged.
+func (p *proxyTransport) registryAuthenticatedOperation(request *http.Request, image string, next func(*http.Request) (*http.Response, error)) (*http.Response, error) {
+	if image == "" {
+		return next(request)
+	}
+
+	tokenData, err := security.RetrieveTokenData(request)
+	if err != nil {
+		return nil, err
+	}
+
+	registry, err := p.findImageRegistry(image)
+	if err != nil {
+		return nil, err
+	}
+
+	if registry != nil {
+		request.Header.Del(registryAuthenticationHeader)
+
+		if tokenData.Role != portainer.AdministratorRole {
+			memberships, err := p.TeamMembershipService.TeamMembershipsByUserID(tokenData.ID)
+			if err != nil {
+				return nil, err
+			}
+
+			if !security.AuthorizedRegistryAccess(registry, tokenData.ID, memberships) {
+				return writeAccessDeniedResponse()
+			}
+		}
+
+		if registry.Authentication {
+			err = setRegistryAuthenticationHeader(request, registry.Username, registry.Password, registry.URL)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return next(request)
+	}
+
+	if registryHostFromImage(image) == dockerHubRegistryHost {
+		dockerhub, err := p.DockerHubService.DockerHub()
+		if err != nil {
+			return nil, err
+		}
+
+		if dockerhub.Authentication {
+			err = setRegistryAuthenticationHeader(request, dockerhub.Username, dockerhub.Password, dockerHubServerAddress)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return next(request)
+}
+
+// findImageRegistry returns the registry hosting an image. When several registries match the image
+// reference, the one with the longest URL is returned. It returns nil when no registry matches.
+func (p *proxyTransport) findImageRegistry(image string) (*portainer.Registry, error) {
+	registries, err := p.RegistryService.Registries()
+	if err != nil {
+		return nil, err
+	}
+
+	image = strings.ToLower(image)
+
+	var match *portainer.Registry
+	matchLength := 0
+	for idx := range registries {
+		registryURL := normalizeRegistryURL(registries[idx].URL)
+		if registryURL == "" || len(registryURL) <= matchLength {
+			continue
+		}
+		if strings.HasPrefix(image, registryURL+"/") {
+			match = &registries[idx]
+			matchLength = len(registryURL)
+		}
+	}
+	return match, nil
+}
+
+// normalizeRegistryURL removes the scheme and the trailing slash of a registry URL.
+func normalizeRegistryURL(registryURL string) string {
+	registryURL = strings.ToLower(strings.TrimSpace(registryURL))
+	if idx := strings.Index(registryURL, "://"); idx != -1 {
+		registryURL = registryURL[idx+3:]
+	}
+	return strings.TrimRight(registryURL, "/")
+}
+
+// registryHostFromImage returns the registry host of an image reference. Like Docker, the first
+// component of the reference is a registry host only when it contains a dot or a port, or is localhost.
+func registryHostFromImage(image string) string {
+	idx := strings.Index(image, "/")
+	if idx == -1 {
+		return dockerHubRegistryHost
+	}
+
+	host := strings.ToLower(image[:idx])
+	if !strings.ContainsAny(host, ".:") && host != "localhost" {
+		return dockerHubRegistryHost
+	}
+	if host == "index.docker.io" || host == "registry-1.docker.io" {
+		return dockerHubRegistryHost
+	}
+	return host
+}
+
+func setRegistryAuthenticationHeader(request *http.Request, username, password, serverAddress string) error {
+	config := registryAuthenticationConfig{
+		Username:      username,
+		Password:      password,
+		ServerAddress: serverAddress,
+	}
+
+	data, err := json.Marshal(config)
+	if err != nil {
+		return err
+	}
+
+	request.Header.Set(registryAuthenticationHeader, base64.URLEncoding.EncodeToString(data))
+	return nil
+}
+
+// serviceImageFromRequest returns the image of the service specified in a service create or update request.
+// The request body is restored after being read.
+func serviceImageFromRequest(request *http.Request) (string, error) {
+	if request.Body == nil {
+		return "", nil
+	}
+
+	body, err := ioutil.ReadAll(request.Body)
+	if err != nil {
+		return "", err
+	}
+	request.Body.Close()
+	request.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	var service struct {
+		TaskTemplate struct {
+			ContainerSpec struct {
+				Image string
+			}
+		}
+	}
+	if json.Unmarshal(body, &service) != nil {
+		return "", nil
+	}
+
+	return service.TaskTemplate.ContainerSpec.Image, nil
+}
@@ -12,10 +12,13 @@ import (
 
 type (
 	proxyTransport struct {
-		dockerTransport        *http.Transport
-		ResourceControlService portainer.ResourceControlService
-		TeamMembershipService  portainer.TeamMembershipService
-		SettingsService        portainer.SettingsService
+		dockerTransport         *http.Transport
+		ResourceControlService  portainer.ResourceControlService
+		TeamMembershipService   portainer.TeamMembershipService
+		SettingsService         portainer.SettingsService
+		RegistryService         portainer.RegistryService
+		DockerHubService        portainer.DockerHubService
+		ResourceOwnershipPolicy portainer.ResourceOwnershipPolicy
 	}
 	restrictedOperationContext struct {
 		isAdmin          bool
@@ -38,6 +41,26 @@ func newSocketTransport(socketPath string) *http.Transport {
 	}
 }
 
+// newAgentTransport returns a transport opening the connections to the Docker API of an endpoint
+// through the tunnel of its agent.
+func newAgentTransport(tunnelService portainer.TunnelService, endpointID portainer.EndpointID) *http.Transport {
+	return &http.Transport{
+		Dial: func(proto, addr string) (conn net.Conn, err error) {
+			return tunnelService.Dial(endpointID)
+		},
+	}
+}
+
+// newSSHTransport returns a transport opening the connections to the Docker API of an endpoint
+// over the SSH connection to its host.
+func newSSHTransport(sshService portainer.SSHService, endpoint *portainer.Endpoint) *http.Transport {
+	return &http.Transport{
+		Dial: func(proto, addr string) (conn net.Conn, err error) {
+			return sshService.Dial(endpoint)
+		},
+	}
+}
+
 func newHTTPTransport() *http.Transport {
 	return &http.Transport{}
 }
@@ -59,6 +82,14 @@ func (p *proxyTransport) proxyDockerRequest(request *http.Request) (*http.Respon
 		return p.proxyServiceRequest(request)
 	} else if strings.HasPrefix(path, "/volumes") {
 		return p.proxyVolumeRequest(request)
+	} else if strings.HasPrefix(path, "/networks") {
+		return p.proxyNetworkRequest(request)
+	} else if strings.HasPrefix(path, "/secrets") {
+		return p.proxySecretRequest(request)
+	} else if strings.HasPrefix(path, "/configs") {
+		return p.proxyConfigRequest(request)
+	} else if strings.HasPrefix(path, "/images") {
+		return p.proxyImageRequest(request)
 	} else if strings.HasPrefix(path, "/swarm") {
 		return p.proxySwarmRequest(request)
 	}
@@ -69,7 +100,7 @@ func (p *proxyTransport) proxyDockerRequest(request *http.Request) (*http.Respon
 func (p *proxyTransport) proxyContainerRequest(request *http.Request) (*http.Response, error) {
 	switch requestPath := request.URL.Path; requestPath {
 	case "/containers/create":
-		return p.executeDockerRequest(request)
+		return p.createResourceOperation(request, portainer.ContainerResourceControl, "/containers", containerIdentifier)
 
 	case "/containers/prune":
 		return p.administratorOperation(request)
@@ -100,7 +131,13 @@ func (p *proxyTransport) proxyContainerRequest(request *http.Request) (*http.Res
 func (p *proxyTransport) proxyServiceRequest(request *http.Request) (*http.Response, error) {
 	switch requestPath := request.URL.Path; requestPath {
 	case "/services/create":
-		return p.executeDockerRequest(request)
+		image, err := serviceImageFromRequest(request)
+		if err != nil {
+			return nil, err
+		}
+		return p.registryAuthenticatedOperation(request, image, func(request *http.Request) (*http.Response, error) {
+			return p.createResourceOperation(request, portainer.ServiceResourceControl, "/services", serviceIdentifier)
+		})
 
 	case "/services":
 		return p.rewriteOperation(request, serviceListOperation)
@@ -110,6 +147,16 @@ func (p *proxyTransport) proxyServiceRequest(request *http.Request) (*http.Respo
 		if match, _ := path.Match("/services/*/*", requestPath); match {
 			// Handle /services/{id}/{action} requests
 			serviceID := path.Base(path.Dir(requestPath))
+
+			if path.Base(requestPath) == "update" {
+				image, err := serviceImageFromRequest(request)
+				if err != nil {
+					return nil, err
+				}
+				return p.registryAuthenticatedOperation(request, image, func(request *http.Request) (*http.Response, error) {
+					return p.restrictedOperation(request, serviceID)
+				})
+			}
 			return p.restrictedOperation(request, serviceID)
 		} else if match, _ := path.Match("/services/*", requestPath); match {
 			// Handle /services/{id} requests
@@ -127,7 +174,7 @@ func (p *proxyTransport) proxyServiceRequest(request *http.Request) (*http.Respo
 func (p *proxyTransport) proxyVolumeRequest(request *http.Request) (*http.Response, error) {
 	switch requestPath := request.URL.Path; requestPath {
 	case "/volumes/create":
-		return p.executeDockerRequest(request)
+		return p.createResourceOperation(request, portainer.VolumeResourceControl, "/volumes", volumeIdentifier)
 
 	case "/volumes/prune":
 		return p.administratorOperation(request)
@@ -145,12 +192,134 @@ func (p *proxyTransport) proxyVolumeRequest(request *http.Request) (*http.Respon
 	}
 }
 
+func (p *proxyTransport) proxyNetworkRequest(request *http.Request) (*http.Response, error) {
+	switch requestPath := request.URL.Path; requestPath {
+	case "/networks/create":
+		return p.executeDockerRequest(request)
+
+	case "/networks/prune":
+		return p.administratorOperation(request)
+
+	case "/networks":
+		return p.rewriteOperation(request, networkListOperation)
+
+	default:
+		// This section assumes /networks/**
+		if match, _ := path.Match("/networks/*/*", requestPath); match {
+			// Handle /networks/{id}/{action} requests
+			networkID := path.Base(path.Dir(requestPath))
+			return p.restrictedResourceOperation(request, networkID, "/networks/"+networkID, networkIdentifier)
+		} else if match, _ := path.Match("/networks/*", requestPath); match {
+			// Handle /networks/{id} requests
+			networkID := path.Base(requestPath)
+
+			if request.Method == http.MethodGet {
+				return p.rewriteOperation(request, networkInspectOperation)
+			}
+			return p.restrictedResourceOperation(request, networkID, requestPath, networkIdentifier)
+		}
+		return p.executeDockerRequest(request)
+	}
+}
+
+func (p *proxyTransport) proxySecretRequest(request *http.Request) (*http.Response, error) {
+	switch requestPath := request.URL.Path; requestPath {
+	case "/secrets/create":
+		return p.executeDockerRequest(request)
+
+	case "/secrets":
+		return p.rewriteOperation(request, secretListOperation)
+
+	default:
+		// This section assumes /secrets/**
+		if match, _ := path.Match("/secrets/*/*", requestPath); match {
+			// Handle /secrets/{id}/{action} requests
+			secretID := path.Base(path.Dir(requestPath))
+			return p.restrictedResourceOperation(request, secretID, "/secrets/"+secretID, secretIdentifier)
+		} else if match, _ := path.Match("/secrets/*", requestPath); match {
+			// Handle /secrets/{id} requests
+			secretID := path.Base(requestPath)
+
+			if request.Method == http.MethodGet {
+				return p.rewriteOperation(request, secretInspectOperation)
+			}
+			return p.restrictedResourceOperation(request, secretID, requestPath, secretIdentifier)
+		}
+		return p.executeDockerRequest(request)
+	}
+}
+
+func (p *proxyTransport) proxyConfigRequest(request *http.Request) (*http.Response, error) {
+	switch requestPath := request.URL.Path; requestPath {
+	case "/configs/create":
+		return p.executeDockerRequest(request)
+
+	case "/configs":
+		return p.rewriteOperation(request, configListOperation)
+
+	default:
+		// This section assumes /configs/**
+		if match, _ := path.Match("/configs/*/*", requestPath); match {
+			// Handle /configs/{id}/{action} requests
+			configID := path.Base(path.Dir(requestPath))
+			return p.restrictedResourceOperation(request, configID, "/configs/"+configID, configIdentifier)
+		} else if match, _ := path.Match("/configs/*", requestPath); match {
+			// Handle /configs/{id} requests
+			configID := path.Base(requestPath)
+
+			if request.Method == http.MethodGet {
+				return p.rewriteOperation(request, configInspectOperation)
+			}
+			return p.restrictedResourceOperation(request, configID, requestPath, configIdentifier)
+		}
+		return p.executeDockerRequest(request)
+	}
+}
+
+func (p *proxyTransport) proxyImageRequest(request *http.Request) (*http.Response, error) {
+	switch requestPath := request.URL.Path; requestPath {
+	case "/images/create":
+		return p.registryAuthenticatedOperation(request, request.URL.Query().Get("fromImage"), p.executeDockerRequest)
+
+	case "/images/load", "/images/search", "/images/get":
+		return p.executeDockerRequest(request)
+
+	case "/images/prune":
+		return p.administratorOperation(request)
+
+	case "/images/json":
+		return p.rewriteOperation(request, imageListOperation)
+
+	default:
+		// This section assumes /images/**
+		// Image names can contain slashes, the action is identified by the last element of the path.
+		imageName := strings.TrimPrefix(requestPath, "/images/")
+		action := path.Base(requestPath)
+		switch action {
+		case "json":
+			return p.rewriteOperation(request, imageInspectOperation)
+		case "history", "push", "tag", "get":
+			imageName = path.Dir(imageName)
+		}
+
+		// The DockerHub credentials are shared by all the users and are only used to pull images,
+		// pushing an image to DockerHub requires the credentials to be sent by the client.
+		if action == "push" && registryHostFromImage(imageName) != dockerHubRegistryHost {
+			return p.registryAuthenticatedOperation(request, imageName, func(request *http.Request) (*http.Response, error) {
+				return p.restrictedResourceOperation(request, imageName, "/images/"+imageName+"/json", imageIdentifier)
+			})
+		}
+		return p.restrictedResourceOperation(request, imageName, "/images/"+imageName+"/json", imageIdentifier)
+	}
+}
+
 func (p *proxyTransport) proxySwarmRequest(request *http.Request) (*http.Response, error) {
 	return p.administratorOperation(request)
 }
 
 // restrictedOperation ensures that the current user has the required authorizations
-// before executing the original request.
+// before executing the original request. A read-write access is required for any operation
+// that is not a read operation.
 func (p *proxyTransport) restrictedOperation(request *http.Request, resourceID string) (*http.Response, error) {
 	var err error
 	tokenData, err := security.RetrieveTokenData(request)
@@ -176,14 +345,78 @@ func (p *proxyTransport) restrictedOperation(request *http.Request, resourceID s
 		}
 
 		resourceControl := getResourceControlByResourceID(resourceID, resourceControls)
-		if resourceControl != nil && !canUserAccessResource(tokenData.ID, userTeamIDs, resourceControl) {
-			return writeAccessDeniedResponse()
+		if resourceControl != nil {
+			if !canUserAccessResource(tokenData.ID, userTeamIDs, resourceControl) {
+				return writeAccessDeniedResponse()
+			}
+
+			// Read-only accesses only allow the operations that do not modify the resource.
+			if !isReadOperation(request) && !canUserModifyResource(tokenData.ID, userTeamIDs, resourceControl) {
+				return writeAccessDeniedResponse()
+			}
 		}
 	}
 
 	return p.executeDockerRequest(request)
 }
 
+// restrictedResourceOperation is similar to restrictedOperation for resources that can be referenced
+// either by name or by identifier in the request path. Resource controls are associated to the resource
+// identifier, it is retrieved by inspecting the resource before checking the authorizations.
+func (p *proxyTransport) restrictedResourceOperation(request *http.Request, resourceName, inspectPath, identifier string) (*http.Response, error) {
+	tokenData, err := security.RetrieveTokenData(request)
+	if err != nil {
+		return nil, err
+	}
+
+	if tokenData.Role == portainer.AdministratorRole {
+		return p.executeDockerRequest(request)
+	}
+
+	resourceID, err := p.retrieveResourceIdentifier(request, inspectPath, identifier)
+	if err != nil {
+		return nil, err
+	}
+	if resourceID == "" {
+		resourceID = resourceName
+	}
+
+	return p.restrictedOperation(request, resourceID)
+}
+
+// retrieveResourceIdentifier inspects a resource and returns its identifier. It returns an empty string
+// when the resource cannot be inspected.
+func (p *proxyTransport) retrieveResourceIdentifier(request *http.Request, inspectPath, identifier string) (string, error) {
+	inspectURL := *request.URL
+	inspectURL.Path = inspectPath
+	inspectURL.RawQuery = ""
+
+	inspectRequest, err := http.NewRequest(http.MethodGet, inspectURL.String(), nil)
+	if err != nil {
+		return "", err
+	}
+
+	response, err := p.executeDockerRequest(inspectRequest)
+	if err != nil {
+		return "", err
+	}
+
+	if response.StatusCode != http.StatusOK {
+		response.Body.Close()
+		return "", nil
+	}
+
+	responseObject, err := getResponseAsJSONOBject(response)
+	if err != nil {
+		return "", err
+	}
+
+	if resourceID, ok := responseObject[identifier].(string); ok {
+		return resourceID, nil
+	}
+	return "", nil
+}
+
 // rewriteOperation will create a new operation context with data that will be used
 // to decorate the original request's response as well as retrieve all the black listed labels
 // to filter the resources.
@@ -0,0 +1,206 @@
+package security
+
+import (
+	"bufio"
+	"context"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/portainer/portainer"
+)
+
+const (
+	// ErrHijackNotSupported defines an error raised when a connection cannot be hijacked
+	ErrHijackNotSupported = portainer.Error("Connection hijacking is not supported")
+)
+
+// AuditLogger represents an entity recording the mutating requests sent to the API.
+type AuditLogger struct {
+	auditLogService portainer.AuditLogService
+	auditForwarder  portainer.AuditForwarder
+	logger          *log.Logger
+}
+
+// auditResponseWriter is a response writer keeping track of the status of the response.
+type auditResponseWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+var (
+	// auditedManagementResources are the API resources whose identifier follows the resource name in the request path.
+	auditedManagementResources = []string{"endpoints", "endpoint_groups", "exec_recordings", "registries", "resource_controls",
+		"team_memberships", "teams", "users", "api_keys"}
+	// dockerResourceActions are the Docker API operations that do not target an existing resource.
+	dockerResourceActions = []string{"create", "prune", "load", "search", "json", "init", "join", "leave", "update", "unlock"}
+)
+
+// NewAuditLogger initializes a new AuditLogger.
+func NewAuditLogger(auditLogService portainer.AuditLogService, auditForwarder portainer.AuditForwarder) *AuditLogger {
+	return &AuditLogger{
+		auditLogService: auditLogService,
+		auditForwarder:  auditForwarder,
+		logger:          log.New(os.Stderr, "", log.LstdFlags),
+	}
+}
+
+// Audit defines a middleware recording the requests sent to the API, including the requests proxied to the
+// Docker API of an endpoint, except the read-only ones. The user associated to an audit log is set during
+// the authentication of the request. Audit logs are also forwarded to the audit sinks.
+func (auditor *AuditLogger) Audit(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.URL.Path, "/api/") || r.Method == http.MethodGet || r.Method == http.MethodHead || r.Method == http.MethodOptions {
+			h.ServeHTTP(w, r)
+			return
+		}
+
+		auditLog := newAuditLog(r)
+		writer := &auditResponseWriter{ResponseWriter: w}
+		ctx := context.WithValue(r.Context(), contextAuditLog, auditLog)
+		h.ServeHTTP(writer, r.WithContext(ctx))
+
+		auditLog.Status = writer.status
+		if auditLog.Status == 0 {
+			auditLog.Status = http.StatusOK
+		}
+
+		err := auditor.auditLogService.CreateAuditLog(auditLog)
+		if err != nil {
+			auditor.logger.Printf("Unable to create audit log for %s %s: %s", auditLog.Method, auditLog.Path, err)
+		}
+
+		auditor.auditForwarder.Forward(auditLog)
+	})
+}
+
+// setAuditLogUser associates the authenticated user to the audit log of the request, if any.
+func setAuditLogUser(request *http.Request, tokenData *portainer.TokenData) {
+	auditLog, ok := request.Context().Value(contextAuditLog).(*portainer.AuditLog)
+	if ok {
+		auditLog.UserID = tokenData.ID
+		auditLog.Username = tokenData.Username
+	}
+}
+
+// SetAuditLogUsername associates a username to the audit log of an unauthenticated request, if any.
+// It is used to record the username used by the authentication attempts.
+func SetAuditLogUsername(request *http.Request, username string) {
+	auditLog, ok := request.Context().Value(contextAuditLog).(*portainer.AuditLog)
+	if ok {
+		auditLog.Username = username
+	}
+}
+
+// newAuditLog returns the audit log of a request, the endpoint and the resource targeted by the request
+// are extracted from its path.
+func newAuditLog(r *http.Request) *portainer.AuditLog {
+	auditLog := &portainer.AuditLog{
+		Timestamp:  time.Now().Unix(),
+		Method:     r.Method,
+		Path:       r.URL.Path,
+		RemoteAddr: r.RemoteAddr,
+	}
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		auditLog.RemoteAddr = host
+	}
+
+	segments := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/"), "/"), "/")
+	if len(segments) >= 3 && segments[0] == "endpoints" && segments[2] == "docker" {
+		endpointID, err := strconv.Atoi(segments[1])
+		if err == nil {
+			auditLog.EndpointID = portainer.EndpointID(endpointID)
+		}
+		auditLog.ResourceID = dockerResourceID(r.Method, segments[3:])
+		return auditLog
+	}
+
+	auditLog.ResourceID = managementResourceID(segments)
+	if len(segments) >= 2 && segments[0] == "endpoints" {
+		endpointID, err := strconv.Atoi(segments[1])
+		if err == nil {
+			auditLog.EndpointID = portainer.EndpointID(endpointID)
+		}
+	}
+	return auditLog
+}
+
+// managementResourceID returns the identifier following the last resource name found in the path segments.
+func managementResourceID(segments []string) string {
+	resourceID := ""
+	for idx := 0; idx < len(segments)-1; idx++ {
+		if containsString(auditedManagementResources, segments[idx]) && segments[idx+1] != "admin" {
+			resourceID = segments[idx+1]
+		}
+	}
+	return resourceID
+}
+
+// dockerResourceID returns the identifier of the resource targeted by a Docker API request.
+// Image names can contain slashes, the last segment of the path is an action unless the image is removed.
+func dockerResourceID(method string, segments []string) string {
+	if len(segments) > 0 && strings.HasPrefix(segments[0], "v") {
+		if _, err := strconv.ParseFloat(segments[0][1:], 64); err == nil {
+			segments = segments[1:]
+		}
+	}
+
+	if len(segments) < 2 || containsString(dockerResourceActions, segments[1]) {
+		return ""
+	}
+
+	if segments[0] == "images" {
+		if method == http.MethodDelete || len(segments) == 2 {
+			return strings.Join(segments[1:], "/")
+		}
+		return strings.Join(segments[1:len(segments)-1], "/")
+	}
+	return segments[1]
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func (w *auditResponseWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *auditResponseWriter) Write(data []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+// Flush is used to implement the http.Flusher interface, streamed responses are flushed by the Docker API proxy.
+func (w *auditResponseWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Hijack is used to implement the http.Hijacker interface, connections are hijacked when the Docker API
+// proxy upgrades a connection.
+func (w *auditResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, ErrHijackNotSupported
+	}
+	if w.status == 0 {
+		w.status = http.StatusSwitchingProtocols
+	}
+	return hijacker.Hijack()
+}
@@ -3,11 +3,17 @@ package security
 import "github.com/portainer/portainer"
 
 // AuthorizedResourceControlDeletion ensure that the user can delete a resource control object.
-// A non-administrator user cannot delete a resource control where:
-// * the AdministratorsOnly flag is set
-// * he is not one of the users in the user accesses
-// * he is not a member of any team within the team accesses
+// It applies the same restrictions as AuthorizedResourceControlModification.
 func AuthorizedResourceControlDeletion(resourceControl *portainer.ResourceControl, context *RestrictedRequestContext) bool {
+	return AuthorizedResourceControlModification(resourceControl, context)
+}
+
+// AuthorizedResourceControlModification ensure that the user can modify an existing resource control object.
+// A non-administrator user cannot modify a resource control where:
+// * the AdministratorsOnly flag is set
+// * he is not one of the users in the user accesses with a read-write access level
+// * he is not a member of any team within the team accesses with a read-write access level
+func AuthorizedResourceControlModification(resourceControl *portainer.ResourceControl, context *RestrictedRequestContext) bool {
 	if context.IsAdmin {
 		return true
 	}
@@ -16,24 +22,20 @@ func AuthorizedResourceControlDeletion(resourceControl *portainer.ResourceContro
 		return false
 	}
 
-	userAccessesCount := len(resourceControl.UserAccesses)
-	teamAccessesCount := len(resourceControl.TeamAccesses)
-
-	if teamAccessesCount > 0 {
-		for _, access := range resourceControl.TeamAccesses {
-			for _, membership := range context.UserMemberships {
-				if membership.TeamID == access.TeamID {
-					return true
-				}
+	for _, access := range resourceControl.TeamAccesses {
+		if access.AccessLevel == portainer.ReadOnlyAccessLevel {
+			continue
+		}
+		for _, membership := range context.UserMemberships {
+			if membership.TeamID == access.TeamID {
+				return true
 			}
 		}
 	}
 
-	if userAccessesCount > 0 {
-		for _, access := range resourceControl.UserAccesses {
-			if access.UserID == context.UserID {
-				return true
-			}
+	for _, access := range resourceControl.UserAccesses {
+		if access.AccessLevel != portainer.ReadOnlyAccessLevel && access.UserID == context.UserID {
+			return true
 		}
 	}
 
@@ -121,3 +123,19 @@ func AuthorizedUserManagement(userID portainer.UserID, context *RestrictedReques
 	}
 	return false
 }
+
+// AuthorizedRegistryAccess ensure that the user can use a registry.
+// A non-administrator user can only use a registry where:
+// * he is one of the authorized users
+// * he is a member of one of the authorized teams
+func AuthorizedRegistryAccess(registry *portainer.Registry, userID portainer.UserID, memberships []portainer.TeamMembership) bool {
+	return isRegistryAccessAuthorized(registry, userID, memberships)
+}
+
+// AuthorizedEndpointAccess ensure that the user can access an endpoint.
+// A non-administrator user can only access an endpoint where:
+// * he is one of the authorized users of the endpoint or of its group
+// * he is a member of one of the authorized teams of the endpoint or of its group
+func AuthorizedEndpointAccess(endpoint *portainer.Endpoint, group *portainer.EndpointGroup, userID portainer.UserID, memberships []portainer.TeamMembership) bool {
+	return isEndpointAccessAuthorized(endpoint, group, userID, memberships)
+}
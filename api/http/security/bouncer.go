@@ -2,17 +2,32 @@ package security
 
 import (
 	"github.com/portainer/portainer"
+	"github.com/portainer/portainer/crypto"
 	httperror "github.com/portainer/portainer/http/error"
 
+	"log"
 	"net/http"
 	"strings"
+	"time"
+)
+
+const (
+	// apiKeyHeader is the header used to authenticate a request with an API key.
+	apiKeyHeader = "X-API-Key"
+	// apiKeyUsageResolution is the minimal delay between two updates of the last usage time of an API key.
+	apiKeyUsageResolution = time.Minute
+	// WebSocketTokenProtocolPrefix is the prefix of the websocket subprotocol used to authenticate a websocket connection.
+	WebSocketTokenProtocolPrefix = "bearer."
 )
 
 type (
 	// RequestBouncer represents an entity that manages API request accesses
 	RequestBouncer struct {
 		jwtService            portainer.JWTService
+		userService           portainer.UserService
 		teamMembershipService portainer.TeamMembershipService
+		apiKeyService         portainer.APIKeyService
+		revocationService     portainer.TokenRevocationService
 		authDisabled          bool
 	}
 
@@ -27,10 +42,14 @@ type (
 )
 
 // NewRequestBouncer initializes a new RequestBouncer
-func NewRequestBouncer(jwtService portainer.JWTService, teamMembershipService portainer.TeamMembershipService, authDisabled bool) *RequestBouncer {
+func NewRequestBouncer(jwtService portainer.JWTService, userService portainer.UserService, teamMembershipService portainer.TeamMembershipService,
+	apiKeyService portainer.APIKeyService, revocationService portainer.TokenRevocationService, authDisabled bool) *RequestBouncer {
 	return &RequestBouncer{
 		jwtService:            jwtService,
+		userService:           userService,
 		teamMembershipService: teamMembershipService,
+		apiKeyService:         apiKeyService,
+		revocationService:     revocationService,
 		authDisabled:          authDisabled,
 	}
 }
@@ -68,6 +87,17 @@ func (bouncer *RequestBouncer) AdministratorAccess(h http.Handler) http.Handler
 	return h
 }
 
+// WebSocketAccess defines a security check for websocket endpoints.
+// Authentication is required to access these endpoints. As browsers cannot specify headers when opening
+// a websocket, the JWT can also be specified via a websocket subprotocol prefixed with WebSocketTokenProtocolPrefix.
+// It is not accepted in the query so that it does not end up in the access logs.
+func (bouncer *RequestBouncer) WebSocketAccess(h http.Handler) http.Handler {
+	h = bouncer.mwCheckAuthentication(h)
+	h = mwWebSocketToken(h)
+	h = mwSecureHeaders(h)
+	return h
+}
+
 // mwSecureHeaders provides secure headers middleware for handlers.
 func mwSecureHeaders(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -77,6 +107,24 @@ func mwSecureHeaders(next http.Handler) http.Handler {
 	})
 }
 
+// mwWebSocketToken copies the JWT specified in the websocket subprotocols to the Authorization header
+// when the header is not set.
+func mwWebSocketToken(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") == "" {
+			for _, protocol := range strings.Split(r.Header.Get("Sec-WebSocket-Protocol"), ",") {
+				protocol = strings.TrimSpace(protocol)
+				if strings.HasPrefix(protocol, WebSocketTokenProtocolPrefix) {
+					r.Header.Set("Authorization", "Bearer "+strings.TrimPrefix(protocol, WebSocketTokenProtocolPrefix))
+					break
+				}
+			}
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
 // mwUpgradeToRestrictedRequest will enhance the current request with
 // a new RestrictedRequestContext object.
 func (bouncer *RequestBouncer) mwUpgradeToRestrictedRequest(next http.Handler) http.Handler {
@@ -115,7 +163,14 @@ func mwCheckAdministratorRole(next http.Handler) http.Handler {
 func (bouncer *RequestBouncer) mwCheckAuthentication(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		var tokenData *portainer.TokenData
-		if !bouncer.authDisabled {
+		if !bouncer.authDisabled && r.Header.Get(apiKeyHeader) != "" {
+			var err error
+			tokenData, err = bouncer.authenticateAPIKey(r.Header.Get(apiKeyHeader))
+			if err != nil {
+				httperror.WriteErrorResponse(w, err, http.StatusUnauthorized, nil)
+				return
+			}
+		} else if !bouncer.authDisabled {
 			var token string
 
 			// Get token from the Authorization header
@@ -136,18 +191,86 @@ func (bouncer *RequestBouncer) mwCheckAuthentication(next http.Handler) http.Han
 				httperror.WriteErrorResponse(w, err, http.StatusUnauthorized, nil)
 				return
 			}
+
+			err = bouncer.checkTokenRevocation(tokenData)
+			if err != nil {
+				httperror.WriteErrorResponse(w, err, http.StatusUnauthorized, nil)
+				return
+			}
 		} else {
 			tokenData = &portainer.TokenData{
 				Role: portainer.AdministratorRole,
 			}
 		}
 
+		setAuditLogUser(r, tokenData)
+
 		ctx := storeTokenData(r, tokenData)
 		next.ServeHTTP(w, r.WithContext(ctx))
 		return
 	})
 }
 
+// checkTokenRevocation returns an error if the token has been revoked, either explicitly
+// or because all the tokens of its user issued before a given time have been revoked.
+func (bouncer *RequestBouncer) checkTokenRevocation(tokenData *portainer.TokenData) error {
+	_, err := bouncer.revocationService.TokenRevocation(tokenData.TokenID)
+	if err == nil {
+		return portainer.ErrRevokedJWTToken
+	} else if err != portainer.ErrTokenRevocationNotFound {
+		return err
+	}
+
+	revocation, err := bouncer.revocationService.UserTokenRevocation(tokenData.ID)
+	if err == portainer.ErrTokenRevocationNotFound {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	if tokenData.IssuedAt <= revocation.RevokedAt {
+		return portainer.ErrRevokedJWTToken
+	}
+	return nil
+}
+
+// authenticateAPIKey returns the token data of the owner of an API key.
+// The data is built from the current user record so that role changes are applied immediately.
+func (bouncer *RequestBouncer) authenticateAPIKey(key string) (*portainer.TokenData, error) {
+	apiKey, err := bouncer.apiKeyService.APIKeyByDigest(crypto.HashAPIKey(key))
+	if err == portainer.ErrAPIKeyNotFound {
+		return nil, portainer.ErrUnauthorized
+	} else if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if apiKey.ExpiresAt != 0 && now.Unix() >= apiKey.ExpiresAt {
+		return nil, portainer.ErrAPIKeyExpired
+	}
+
+	user, err := bouncer.userService.User(apiKey.UserID)
+	if err == portainer.ErrUserNotFound {
+		return nil, portainer.ErrUnauthorized
+	} else if err != nil {
+		return nil, err
+	}
+
+	if now.Sub(time.Unix(apiKey.LastUsedAt, 0)) >= apiKeyUsageResolution {
+		apiKey.LastUsedAt = now.Unix()
+		err = bouncer.apiKeyService.UpdateAPIKey(apiKey.ID, apiKey)
+		if err != nil {
+			log.Printf("Unable to update the last usage time of API key %d: %s", apiKey.ID, err)
+		}
+	}
+
+	return &portainer.TokenData{
+		ID:       user.ID,
+		Username: user.Username,
+		Role:     user.Role,
+	}, nil
+}
+
 func (bouncer *RequestBouncer) newRestrictedContextRequest(userID portainer.UserID, userRole portainer.UserRole) (*RestrictedRequestContext, error) {
 	requestContext := &RestrictedRequestContext{
 		IsAdmin: true,
@@ -14,6 +14,7 @@ type (
 const (
 	contextAuthenticationKey contextKey = iota
 	contextRestrictedRequest
+	contextAuditLog
 )
 
 // storeTokenData stores a TokenData object inside the request context and returns the enhanced context.
@@ -79,15 +79,17 @@ func FilterRegistries(registries []portainer.Registry, context *RestrictedReques
 }
 
 // FilterEndpoints filters endpoints based on user role and team memberships.
-// Non administrator users only have access to authorized endpoints.
-func FilterEndpoints(endpoints []portainer.Endpoint, context *RestrictedRequestContext) ([]portainer.Endpoint, error) {
+// Non administrator users only have access to authorized endpoints, either directly
+// or through the group of the endpoint.
+func FilterEndpoints(endpoints []portainer.Endpoint, groups []portainer.EndpointGroup, context *RestrictedRequestContext) ([]portainer.Endpoint, error) {
 	filteredEndpoints := endpoints
 
 	if !context.IsAdmin {
 		filteredEndpoints = make([]portainer.Endpoint, 0)
 
 		for _, endpoint := range endpoints {
-			if isEndpointAccessAuthorized(&endpoint, context.UserID, context.UserMemberships) {
+			group := findEndpointGroup(endpoint.GroupID, groups)
+			if isEndpointAccessAuthorized(&endpoint, group, context.UserID, context.UserMemberships) {
 				filteredEndpoints = append(filteredEndpoints, endpoint)
 			}
 		}
@@ -96,6 +98,15 @@ func FilterEndpoints(endpoints []portainer.Endpoint, context *RestrictedRequestC
 	return filteredEndpoints, nil
 }
 
+func findEndpointGroup(groupID portainer.EndpointGroupID, groups []portainer.EndpointGroup) *portainer.EndpointGroup {
+	for idx := range groups {
+		if groups[idx].ID == groupID {
+			return &groups[idx]
+		}
+	}
+	return nil
+}
+
 func isRegistryAccessAuthorized(registry *portainer.Registry, userID portainer.UserID, memberships []portainer.TeamMembership) bool {
 	for _, authorizedUserID := range registry.AuthorizedUsers {
 		if authorizedUserID == userID {
@@ -112,14 +123,21 @@ func isRegistryAccessAuthorized(registry *portainer.Registry, userID portainer.U
 	return false
 }
 
-func isEndpointAccessAuthorized(endpoint *portainer.Endpoint, userID portainer.UserID, memberships []portainer.TeamMembership) bool {
-	for _, authorizedUserID := range endpoint.AuthorizedUsers {
+func isEndpointAccessAuthorized(endpoint *portainer.Endpoint, group *portainer.EndpointGroup, userID portainer.UserID, memberships []portainer.TeamMembership) bool {
+	if isAccessAuthorized(endpoint.AuthorizedUsers, endpoint.AuthorizedTeams, userID, memberships) {
+		return true
+	}
+	return group != nil && isAccessAuthorized(group.AuthorizedUsers, group.AuthorizedTeams, userID, memberships)
+}
+
+func isAccessAuthorized(authorizedUsers []portainer.UserID, authorizedTeams []portainer.TeamID, userID portainer.UserID, memberships []portainer.TeamMembership) bool {
+	for _, authorizedUserID := range authorizedUsers {
 		if authorizedUserID == userID {
 			return true
 		}
 	}
 	for _, membership := range memberships {
-		for _, authorizedTeamID := range endpoint.AuthorizedTeams {
+		for _, authorizedTeamID := range authorizedTeams {
 			if membership.TeamID == authorizedTeamID {
 				return true
 			}
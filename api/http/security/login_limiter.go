@@ -0,0 +1,205 @@
+package security
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/portainer/portainer"
+)
+
+const (
+	// LoginLockoutUser identifies a lockout applied to a username.
+	LoginLockoutUser = "user"
+	// LoginLockoutIP identifies a lockout applied to a client IP.
+	LoginLockoutIP = "ip"
+
+	loginLimiterCleanupInterval = time.Minute
+)
+
+type (
+	// LoginLimiter tracks the failed authentication attempts per username and per client IP.
+	// Each failure delays the next allowed attempt with an exponential backoff, the username
+	// or IP is locked for the lockout duration once the maximum number of failures is reached.
+	LoginLimiter struct {
+		mu          sync.Mutex
+		users       map[string]*loginAttempts
+		ips         map[string]*loginAttempts
+		lastCleanup time.Time
+	}
+
+	// LoginLockout represents the throttling state of a username or a client IP.
+	LoginLockout struct {
+		Type           string `json:"Type"`
+		Value          string `json:"Value"`
+		FailedAttempts int    `json:"FailedAttempts"`
+		LastFailure    int64  `json:"LastFailure"`
+		BlockedUntil   int64  `json:"BlockedUntil"`
+	}
+
+	loginAttempts struct {
+		failures     int
+		lastFailure  time.Time
+		blockedUntil time.Time
+	}
+)
+
+// NewLoginLimiter initializes a new LoginLimiter.
+func NewLoginLimiter() *LoginLimiter {
+	return &LoginLimiter{
+		users: make(map[string]*loginAttempts),
+		ips:   make(map[string]*loginAttempts),
+	}
+}
+
+// Delay returns the time to wait before an authentication attempt is allowed for the username and IP.
+func (limiter *LoginLimiter) Delay(username, ip string) time.Duration {
+	limiter.mu.Lock()
+	defer limiter.mu.Unlock()
+
+	now := time.Now()
+	delay := time.Duration(0)
+	if attempts, ok := limiter.users[normalizeUsername(username)]; ok && attempts.blockedUntil.After(now) {
+		delay = attempts.blockedUntil.Sub(now)
+	}
+	if attempts, ok := limiter.ips[ip]; ok && attempts.blockedUntil.After(now) && attempts.blockedUntil.Sub(now) > delay {
+		delay = attempts.blockedUntil.Sub(now)
+	}
+	return delay
+}
+
+// RecordFailure records a failed authentication attempt. It returns the time until which
+// the username is locked, or the zero time when the username is only delayed.
+func (limiter *LoginLimiter) RecordFailure(username, ip string, settings *portainer.LoginRateLimitSettings) time.Time {
+	limiter.mu.Lock()
+	defer limiter.mu.Unlock()
+
+	now := time.Now()
+	limiter.cleanup(now, settings)
+
+	userLockedUntil := recordFailure(limiter.users, normalizeUsername(username), now, settings.MaxFailedAttemptsPerUser, settings)
+	recordFailure(limiter.ips, ip, now, settings.MaxFailedAttemptsPerIP, settings)
+	return userLockedUntil
+}
+
+// RecordSuccess resets the failed attempts of the username after a successful authentication.
+// The failed attempts of the IP expire on their own, otherwise logging in with a valid account
+// between attempts would allow to try passwords against the other accounts without limit.
+func (limiter *LoginLimiter) RecordSuccess(username string) {
+	limiter.mu.Lock()
+	defer limiter.mu.Unlock()
+
+	delete(limiter.users, normalizeUsername(username))
+}
+
+// UnlockUser removes the failed attempts and lockout of a username.
+func (limiter *LoginLimiter) UnlockUser(username string) {
+	limiter.mu.Lock()
+	defer limiter.mu.Unlock()
+
+	delete(limiter.users, normalizeUsername(username))
+}
+
+// UnlockIP removes the failed attempts and lockout of a client IP.
+func (limiter *LoginLimiter) UnlockIP(ip string) {
+	limiter.mu.Lock()
+	defer limiter.mu.Unlock()
+
+	delete(limiter.ips, ip)
+}
+
+// Lockouts returns the usernames and IPs that are currently delayed or locked.
+func (limiter *LoginLimiter) Lockouts() []LoginLockout {
+	limiter.mu.Lock()
+	defer limiter.mu.Unlock()
+
+	now := time.Now()
+	lockouts := make([]LoginLockout, 0)
+	for username, attempts := range limiter.users {
+		if attempts.blockedUntil.After(now) {
+			lockouts = append(lockouts, newLoginLockout(LoginLockoutUser, username, attempts))
+		}
+	}
+	for ip, attempts := range limiter.ips {
+		if attempts.blockedUntil.After(now) {
+			lockouts = append(lockouts, newLoginLockout(LoginLockoutIP, ip, attempts))
+		}
+	}
+	return lockouts
+}
+
+// cleanup periodically removes the entries whose failures are older than the lockout duration.
+func (limiter *LoginLimiter) cleanup(now time.Time, settings *portainer.LoginRateLimitSettings) {
+	if now.Sub(limiter.lastCleanup) < loginLimiterCleanupInterval {
+		return
+	}
+	limiter.lastCleanup = now
+
+	for _, entries := range []map[string]*loginAttempts{limiter.users, limiter.ips} {
+		for key, attempts := range entries {
+			if isExpired(attempts, now, settings) {
+				delete(entries, key)
+			}
+		}
+	}
+}
+
+func recordFailure(entries map[string]*loginAttempts, key string, now time.Time, maxFailures int, settings *portainer.LoginRateLimitSettings) time.Time {
+	attempts, ok := entries[key]
+	if !ok || isExpired(attempts, now, settings) {
+		attempts = &loginAttempts{}
+		entries[key] = attempts
+	}
+
+	attempts.failures++
+	attempts.lastFailure = now
+
+	if maxFailures > 0 && attempts.failures >= maxFailures {
+		attempts.blockedUntil = now.Add(time.Duration(settings.LockoutDuration) * time.Second)
+		return attempts.blockedUntil
+	}
+
+	attempts.blockedUntil = now.Add(backoffDelay(attempts.failures, settings))
+	return time.Time{}
+}
+
+// backoffDelay returns the base delay doubled for each consecutive failure, capped to the maximum delay.
+func backoffDelay(failures int, settings *portainer.LoginRateLimitSettings) time.Duration {
+	if settings.BackoffBaseDelay <= 0 {
+		return 0
+	}
+
+	maxDelay := time.Duration(settings.BackoffMaxDelay) * time.Second
+	delay := time.Duration(settings.BackoffBaseDelay) * time.Second
+	for i := 1; i < failures; i++ {
+		delay *= 2
+		if maxDelay > 0 && delay >= maxDelay {
+			return maxDelay
+		}
+	}
+	return delay
+}
+
+// isExpired returns true when the failures of an entry are too old to be taken into account.
+func isExpired(attempts *loginAttempts, now time.Time, settings *portainer.LoginRateLimitSettings) bool {
+	window := time.Duration(settings.LockoutDuration) * time.Second
+	if maxDelay := time.Duration(settings.BackoffMaxDelay) * time.Second; maxDelay > window {
+		window = maxDelay
+	}
+	return !attempts.blockedUntil.After(now) && now.Sub(attempts.lastFailure) > window
+}
+
+func newLoginLockout(lockoutType, value string, attempts *loginAttempts) LoginLockout {
+	return LoginLockout{
+		Type:           lockoutType,
+		Value:          value,
+		FailedAttempts: attempts.failures,
+		LastFailure:    attempts.lastFailure.Unix(),
+		BlockedUntil:   attempts.blockedUntil.Unix(),
+	}
+}
+
+// normalizeUsername ensures that case variations of a username share the same failed attempts.
+func normalizeUsername(username string) string {
+	return strings.ToLower(username)
+}
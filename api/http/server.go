@@ -17,16 +17,29 @@ type Server struct {
 	EndpointManagement     bool
 	Status                 *portainer.Status
 	UserService            portainer.UserService
+	APIKeyService          portainer.APIKeyService
 	TeamService            portainer.TeamService
 	TeamMembershipService  portainer.TeamMembershipService
 	EndpointService        portainer.EndpointService
+	EndpointGroupService   portainer.EndpointGroupService
 	ResourceControlService portainer.ResourceControlService
 	SettingsService        portainer.SettingsService
 	CryptoService          portainer.CryptoService
+	EncryptionService      portainer.EncryptionService
 	JWTService             portainer.JWTService
+	TokenRevocationService portainer.TokenRevocationService
+	AuditLogService        portainer.AuditLogService
+	AuditForwarder         portainer.AuditForwarder
 	FileService            portainer.FileService
 	RegistryService        portainer.RegistryService
+	RegistryBrowserService portainer.RegistryBrowserService
 	DockerHubService       portainer.DockerHubService
+	LDAPService            portainer.LDAPService
+	LDAPSyncService        portainer.LDAPSyncService
+	OAuthService           portainer.OAuthService
+	ProxyManager           *proxy.Manager
+	TunnelService          portainer.TunnelService
+	SSHService             portainer.SSHService
 	Handler                *handler.Handler
 	SSL                    bool
 	SSLCert                string
@@ -35,18 +48,26 @@ type Server struct {
 
 // Start starts the HTTP server
 func (server *Server) Start() error {
-	requestBouncer := security.NewRequestBouncer(server.JWTService, server.TeamMembershipService, server.AuthDisabled)
-	proxyManager := proxy.NewManager(server.ResourceControlService, server.TeamMembershipService, server.SettingsService)
+	requestBouncer := security.NewRequestBouncer(server.JWTService, server.UserService, server.TeamMembershipService, server.APIKeyService, server.TokenRevocationService, server.AuthDisabled)
+	loginLimiter := security.NewLoginLimiter()
 
 	var authHandler = handler.NewAuthHandler(requestBouncer, server.AuthDisabled)
 	authHandler.UserService = server.UserService
 	authHandler.CryptoService = server.CryptoService
 	authHandler.JWTService = server.JWTService
+	authHandler.LDAPService = server.LDAPService
+	authHandler.OAuthService = server.OAuthService
+	authHandler.SettingsService = server.SettingsService
+	authHandler.TeamMembershipService = server.TeamMembershipService
+	authHandler.LoginLimiter = loginLimiter
 	var userHandler = handler.NewUserHandler(requestBouncer)
 	userHandler.UserService = server.UserService
+	userHandler.APIKeyService = server.APIKeyService
 	userHandler.TeamService = server.TeamService
 	userHandler.TeamMembershipService = server.TeamMembershipService
 	userHandler.CryptoService = server.CryptoService
+	userHandler.JWTService = server.JWTService
+	userHandler.LoginLimiter = loginLimiter
 	userHandler.ResourceControlService = server.ResourceControlService
 	var teamHandler = handler.NewTeamHandler(requestBouncer)
 	teamHandler.TeamService = server.TeamService
@@ -56,20 +77,47 @@ func (server *Server) Start() error {
 	var statusHandler = handler.NewStatusHandler(requestBouncer, server.Status)
 	var settingsHandler = handler.NewSettingsHandler(requestBouncer)
 	settingsHandler.SettingsService = server.SettingsService
+	settingsHandler.LDAPService = server.LDAPService
+	settingsHandler.FileService = server.FileService
+	settingsHandler.LDAPSyncService = server.LDAPSyncService
+	settingsHandler.AuditForwarder = server.AuditForwarder
 	var templatesHandler = handler.NewTemplatesHandler(requestBouncer)
 	templatesHandler.SettingsService = server.SettingsService
 	var dockerHandler = handler.NewDockerHandler(requestBouncer)
 	dockerHandler.EndpointService = server.EndpointService
 	dockerHandler.TeamMembershipService = server.TeamMembershipService
-	dockerHandler.ProxyManager = proxyManager
-	var websocketHandler = handler.NewWebSocketHandler()
+	dockerHandler.EndpointGroupService = server.EndpointGroupService
+	dockerHandler.ProxyManager = server.ProxyManager
+	var websocketHandler = handler.NewWebSocketHandler(requestBouncer)
 	websocketHandler.EndpointService = server.EndpointService
+	websocketHandler.TeamMembershipService = server.TeamMembershipService
+	websocketHandler.EndpointGroupService = server.EndpointGroupService
+	websocketHandler.ResourceControlService = server.ResourceControlService
+	websocketHandler.SettingsService = server.SettingsService
+	websocketHandler.FileService = server.FileService
+	websocketHandler.EncryptionService = server.EncryptionService
+	websocketHandler.TunnelService = server.TunnelService
+	websocketHandler.SSHService = server.SSHService
+	var auditLogHandler = handler.NewAuditLogHandler(requestBouncer)
+	auditLogHandler.AuditLogService = server.AuditLogService
+	var execRecordingHandler = handler.NewExecRecordingHandler(requestBouncer)
+	execRecordingHandler.FileService = server.FileService
 	var endpointHandler = handler.NewEndpointHandler(requestBouncer, server.EndpointManagement)
 	endpointHandler.EndpointService = server.EndpointService
+	endpointHandler.EndpointGroupService = server.EndpointGroupService
 	endpointHandler.FileService = server.FileService
-	endpointHandler.ProxyManager = proxyManager
+	endpointHandler.ProxyManager = server.ProxyManager
+	endpointHandler.TunnelService = server.TunnelService
+	endpointHandler.SSHService = server.SSHService
+	var agentHandler = handler.NewAgentHandler(requestBouncer)
+	agentHandler.EndpointService = server.EndpointService
+	agentHandler.TunnelService = server.TunnelService
+	var endpointGroupHandler = handler.NewEndpointGroupHandler(requestBouncer)
+	endpointGroupHandler.EndpointService = server.EndpointService
+	endpointGroupHandler.EndpointGroupService = server.EndpointGroupService
 	var registryHandler = handler.NewRegistryHandler(requestBouncer)
 	registryHandler.RegistryService = server.RegistryService
+	registryHandler.RegistryBrowserService = server.RegistryBrowserService
 	var dockerHubHandler = handler.NewDockerHubHandler(requestBouncer)
 	dockerHubHandler.DockerHubService = server.DockerHubService
 	var resourceHandler = handler.NewResourceHandler(requestBouncer)
@@ -80,10 +128,12 @@ func (server *Server) Start() error {
 
 	server.Handler = &handler.Handler{
 		AuthHandler:           authHandler,
+		AgentHandler:          agentHandler,
 		UserHandler:           userHandler,
 		TeamHandler:           teamHandler,
 		TeamMembershipHandler: teamMembershipHandler,
 		EndpointHandler:       endpointHandler,
+		EndpointGroupHandler:  endpointGroupHandler,
 		RegistryHandler:       registryHandler,
 		DockerHubHandler:      dockerHubHandler,
 		ResourceHandler:       resourceHandler,
@@ -92,12 +142,16 @@ func (server *Server) Start() error {
 		TemplatesHandler:      templatesHandler,
 		DockerHandler:         dockerHandler,
 		WebSocketHandler:      websocketHandler,
+		ExecRecordingHandler:  execRecordingHandler,
+		AuditLogHandler:       auditLogHandler,
 		FileHandler:           fileHandler,
 		UploadHandler:         uploadHandler,
 	}
 
+	auditedHandler := security.NewAuditLogger(server.AuditLogService, server.AuditForwarder).Audit(server.Handler)
+
 	if server.SSL {
-		return http.ListenAndServeTLS(server.BindAddress, server.SSLCert, server.SSLKey, server.Handler)
+		return http.ListenAndServeTLS(server.BindAddress, server.SSLCert, server.SSLKey, auditedHandler)
 	}
-	return http.ListenAndServe(server.BindAddress, server.Handler)
+	return http.ListenAndServe(server.BindAddress, auditedHandler)
 }
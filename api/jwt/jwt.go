@@ -3,7 +3,9 @@ package jwt
 import (
 	"github.com/portainer/portainer"
 
+	"encoding/hex"
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/dgrijalva/jwt-go"
@@ -12,42 +14,108 @@ import (
 
 // Service represents a service for managing JWT tokens.
 type Service struct {
-	secret []byte
+	mu                sync.RWMutex
+	secret            []byte
+	secretService     portainer.JWTSecretService
+	revocationService portainer.TokenRevocationService
 }
 
 type claims struct {
-	UserID   int    `json:"id"`
-	Username string `json:"username"`
-	Role     int    `json:"role"`
+	UserID     int    `json:"id"`
+	Username   string `json:"username"`
+	Role       int    `json:"role"`
+	IssuedAtMs int64  `json:"iat_ms"`
+	Scope      string `json:"scope,omitempty"`
 	jwt.StandardClaims
 }
 
-// NewService initializes a new service. It will generate a random key that will be used to sign JWT tokens.
-func NewService() (*Service, error) {
+const (
+	// twoFactorScope identifies the tokens only allowed to complete a two-factor authentication.
+	twoFactorScope = "2fa"
+)
+
+// NewService initializes a new service. The key used to sign JWT tokens is retrieved from the data store,
+// a random key is generated and persisted when none exists.
+func NewService(secretService portainer.JWTSecretService, revocationService portainer.TokenRevocationService) (*Service, error) {
+	service := &Service{
+		secretService:     secretService,
+		revocationService: revocationService,
+	}
+
+	secret, err := secretService.Secret()
+	if err == portainer.ErrSecretNotFound {
+		return service, service.RotateSecret()
+	} else if err != nil {
+		return nil, err
+	}
+
+	service.secret = secret
+	return service, nil
+}
+
+// RotateSecret generates and persists a new signing key. All the tokens signed with the previous key become invalid.
+func (service *Service) RotateSecret() error {
 	secret := securecookie.GenerateRandomKey(32)
 	if secret == nil {
-		return nil, portainer.ErrSecretGeneration
+		return portainer.ErrSecretGeneration
 	}
-	service := &Service{
-		secret,
+
+	err := service.secretService.StoreSecret(secret)
+	if err != nil {
+		return err
 	}
-	return service, nil
+
+	service.mu.Lock()
+	service.secret = secret
+	service.mu.Unlock()
+	return nil
 }
 
 // GenerateToken generates a new JWT token.
 func (service *Service) GenerateToken(data *portainer.TokenData) (string, error) {
-	expireToken := time.Now().Add(time.Hour * 8).Unix()
+	return service.generateToken(data, "", portainer.JWTTokenLifetime)
+}
+
+// GenerateTwoFactorToken generates a short-lived token that can only be used to complete a two-factor authentication.
+func (service *Service) GenerateTwoFactorToken(data *portainer.TokenData) (string, error) {
+	return service.generateToken(data, twoFactorScope, portainer.TwoFactorTokenLifetime)
+}
+
+// ParseAndVerifyToken parses a JWT token and verify its validity. It returns an error if token is invalid.
+// Revocation is not checked here, see RequestBouncer.
+func (service *Service) ParseAndVerifyToken(token string) (*portainer.TokenData, error) {
+	return service.parseAndVerifyToken(token, "")
+}
+
+// ParseAndVerifyTwoFactorToken parses a token generated by GenerateTwoFactorToken and verify its validity.
+func (service *Service) ParseAndVerifyTwoFactorToken(token string) (*portainer.TokenData, error) {
+	return service.parseAndVerifyToken(token, twoFactorScope)
+}
+
+func (service *Service) generateToken(data *portainer.TokenData, scope string, lifetime time.Duration) (string, error) {
+	tokenID := securecookie.GenerateRandomKey(16)
+	if tokenID == nil {
+		return "", portainer.ErrSecretGeneration
+	}
+
+	now := time.Now()
 	cl := claims{
 		int(data.ID),
 		data.Username,
 		int(data.Role),
+		now.UnixNano() / int64(time.Millisecond),
+		scope,
 		jwt.StandardClaims{
-			ExpiresAt: expireToken,
+			Id:        hex.EncodeToString(tokenID),
+			IssuedAt:  now.Unix(),
+			ExpiresAt: now.Add(lifetime).Unix(),
 		},
 	}
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, cl)
 
+	service.mu.RLock()
 	signedToken, err := token.SignedString(service.secret)
+	service.mu.RUnlock()
 	if err != nil {
 		return "", err
 	}
@@ -55,21 +123,26 @@ func (service *Service) GenerateToken(data *portainer.TokenData) (string, error)
 	return signedToken, nil
 }
 
-// ParseAndVerifyToken parses a JWT token and verify its validity. It returns an error if token is invalid.
-func (service *Service) ParseAndVerifyToken(token string) (*portainer.TokenData, error) {
+// parseAndVerifyToken parses a token and checks that it has been generated for the specified scope.
+func (service *Service) parseAndVerifyToken(token, scope string) (*portainer.TokenData, error) {
 	parsedToken, err := jwt.ParseWithClaims(token, &claims{}, func(token *jwt.Token) (interface{}, error) {
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 			msg := fmt.Errorf("Unexpected signing method: %v", token.Header["alg"])
 			return nil, msg
 		}
+		service.mu.RLock()
+		defer service.mu.RUnlock()
 		return service.secret, nil
 	})
 	if err == nil && parsedToken != nil {
-		if cl, ok := parsedToken.Claims.(*claims); ok && parsedToken.Valid {
+		if cl, ok := parsedToken.Claims.(*claims); ok && parsedToken.Valid && cl.Scope == scope {
 			tokenData := &portainer.TokenData{
-				ID:       portainer.UserID(cl.UserID),
-				Username: cl.Username,
-				Role:     portainer.UserRole(cl.Role),
+				ID:        portainer.UserID(cl.UserID),
+				Username:  cl.Username,
+				Role:      portainer.UserRole(cl.Role),
+				TokenID:   cl.Id,
+				IssuedAt:  cl.IssuedAtMs,
+				ExpiresAt: cl.ExpiresAt,
 			}
 			return tokenData, nil
 		}
@@ -77,3 +150,37 @@ func (service *Service) ParseAndVerifyToken(token string) (*portainer.TokenData,
 
 	return nil, portainer.ErrInvalidJWTToken
 }
+
+// RevokeToken adds a token to the revocation list until it expires.
+func (service *Service) RevokeToken(data *portainer.TokenData) error {
+	if data.TokenID == "" {
+		return nil
+	}
+
+	revocation := &portainer.TokenRevocation{
+		TokenID:   data.TokenID,
+		ExpiresAt: data.ExpiresAt,
+	}
+	err := service.revocationService.RevokeToken(revocation)
+	if err != nil {
+		return err
+	}
+
+	return service.revocationService.DeleteExpiredRevocations(time.Now().Unix())
+}
+
+// RevokeUserTokens revokes all the tokens issued to a user up to now.
+func (service *Service) RevokeUserTokens(userID portainer.UserID) error {
+	now := time.Now()
+	revocation := &portainer.UserTokenRevocation{
+		UserID:    userID,
+		RevokedAt: now.UnixNano() / int64(time.Millisecond),
+		ExpiresAt: now.Add(portainer.JWTTokenLifetime).Unix(),
+	}
+	err := service.revocationService.RevokeUserTokens(revocation)
+	if err != nil {
+		return err
+	}
+
+	return service.revocationService.DeleteExpiredRevocations(now.Unix())
+}
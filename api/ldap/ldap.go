@@ -0,0 +1,157 @@
+package ldap
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/portainer/portainer"
+	"github.com/portainer/portainer/crypto"
+
+	"gopkg.in/ldap.v2"
+)
+
+// Service represents a service used to authenticate users against a LDAP/AD.
+type Service struct{}
+
+// searchUser looks for a unique entry matching the username in each of the search settings
+// and returns its DN. The first matching search settings is used.
+func searchUser(username string, conn *ldap.Conn, settings []portainer.LDAPSearchSettings) (string, error) {
+	for _, searchSettings := range settings {
+		searchRequest := ldap.NewSearchRequest(
+			searchSettings.BaseDN,
+			ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 0, 0, false,
+			fmt.Sprintf("(&%s(%s=%s))", searchSettings.Filter, searchSettings.UserNameAttribute, ldap.EscapeFilter(username)),
+			[]string{"dn"},
+			nil,
+		)
+
+		// Deliberately ignore errors on the search request as the user might not be found with these settings.
+		result, err := conn.Search(searchRequest)
+		if err != nil {
+			continue
+		}
+
+		if len(result.Entries) == 1 {
+			return result.Entries[0].DN, nil
+		}
+	}
+
+	return "", portainer.ErrLDAPUserNotFound
+}
+
+// createConnection opens a connection to the LDAP server, using LDAPS or StartTLS when enabled.
+func createConnection(settings *portainer.LDAPSettings) (*ldap.Conn, error) {
+	if !settings.TLSConfig.TLS && !settings.StartTLS {
+		return ldap.Dial("tcp", settings.URL)
+	}
+
+	config, err := crypto.CreateTLSConfigurationWithCA(settings.TLSConfig.TLSCACertPath, settings.TLSConfig.TLSSkipVerify)
+	if err != nil {
+		return nil, err
+	}
+	config.ServerName = strings.Split(settings.URL, ":")[0]
+
+	if settings.TLSConfig.TLS {
+		return ldap.DialTLS("tcp", settings.URL, config)
+	}
+
+	conn, err := ldap.Dial("tcp", settings.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	err = conn.StartTLS(config)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return conn, nil
+}
+
+// AuthenticateUser is used to authenticate a user against a LDAP/AD.
+// The reader account is used to search for the user DN, the user is then authenticated
+// by binding with its own DN and password.
+func (*Service) AuthenticateUser(username, password string, settings *portainer.LDAPSettings) error {
+	// An empty password would result in an unauthenticated bind which most servers accept.
+	if password == "" {
+		return portainer.ErrUnauthorized
+	}
+
+	connection, err := createConnection(settings)
+	if err != nil {
+		return err
+	}
+	defer connection.Close()
+
+	err = connection.Bind(settings.ReaderDN, settings.Password)
+	if err != nil {
+		return err
+	}
+
+	userDN, err := searchUser(username, connection, settings.SearchSettings)
+	if err != nil {
+		return err
+	}
+
+	err = connection.Bind(userDN, password)
+	if err != nil {
+		return portainer.ErrUnauthorized
+	}
+
+	return nil
+}
+
+// TestConnectivity is used to test a connection against the LDAP server using the credentials
+// specified in the LDAPSettings.
+func (*Service) TestConnectivity(settings *portainer.LDAPSettings) error {
+	connection, err := createConnection(settings)
+	if err != nil {
+		return err
+	}
+	defer connection.Close()
+
+	return connection.Bind(settings.ReaderDN, settings.Password)
+}
+
+// GetUserGroups returns the DN of all the groups the user is a member of, based on the group search settings.
+func (*Service) GetUserGroups(username string, settings *portainer.LDAPSettings) ([]string, error) {
+	connection, err := createConnection(settings)
+	if err != nil {
+		return nil, err
+	}
+	defer connection.Close()
+
+	err = connection.Bind(settings.ReaderDN, settings.Password)
+	if err != nil {
+		return nil, err
+	}
+
+	userDN, err := searchUser(username, connection, settings.SearchSettings)
+	if err != nil {
+		return nil, err
+	}
+
+	groups := make([]string, 0)
+	for _, searchSettings := range settings.GroupSearchSettings {
+		searchRequest := ldap.NewSearchRequest(
+			searchSettings.GroupBaseDN,
+			ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 0, 0, false,
+			fmt.Sprintf("(&%s(%s=%s))", searchSettings.GroupFilter, searchSettings.GroupAttribute, ldap.EscapeFilter(userDN)),
+			[]string{"dn"},
+			nil,
+		)
+
+		// Errors are not ignored here, a partial group list would remove the user from some of its teams.
+		result, err := connection.Search(searchRequest)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, entry := range result.Entries {
+			groups = append(groups, entry.DN)
+		}
+	}
+
+	return groups, nil
+}
@@ -0,0 +1,329 @@
+package oauth
+
+import (
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/portainer/portainer"
+)
+
+// Service represents a service used to authenticate users against an OAuth2/OpenID Connect provider.
+type Service struct {
+	client *http.Client
+}
+
+type tokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	IDToken     string `json:"id_token"`
+}
+
+type jsonWebKeySet struct {
+	Keys []jsonWebKey `json:"keys"`
+}
+
+type jsonWebKey struct {
+	KeyType string `json:"kty"`
+	KeyID   string `json:"kid"`
+	N       string `json:"n"`
+	E       string `json:"e"`
+}
+
+// NewService initializes a new service.
+func NewService() *Service {
+	return &Service{
+		client: &http.Client{
+			Timeout: 10 * time.Second,
+		},
+	}
+}
+
+// GetAuthorizationURL returns the URL of the provider authorization endpoint the user must be redirected to.
+func (service *Service) GetAuthorizationURL(state, nonce string, settings *portainer.OAuthSettings) (string, error) {
+	authorizationURL, err := url.Parse(settings.AuthorizationURI)
+	if err != nil {
+		return "", err
+	}
+
+	query := authorizationURL.Query()
+	query.Set("response_type", "code")
+	query.Set("client_id", settings.ClientID)
+	query.Set("redirect_uri", settings.RedirectURI)
+	query.Set("scope", settings.Scopes)
+	query.Set("state", state)
+	query.Set("nonce", nonce)
+	authorizationURL.RawQuery = query.Encode()
+
+	return authorizationURL.String(), nil
+}
+
+// Authenticate exchanges the authorization code against the provider tokens and returns the identity
+// of the user. When an ID token is returned it is verified and its claims are used, the claims returned
+// by the resource (userinfo) endpoint are used to complete them when it is configured.
+func (service *Service) Authenticate(code, nonce string, settings *portainer.OAuthSettings) (*portainer.OAuthIdentity, error) {
+	token, err := service.exchangeCode(code, settings)
+	if err != nil {
+		return nil, err
+	}
+
+	claims := make(map[string]interface{})
+	if token.IDToken != "" {
+		idTokenClaims, err := service.verifyIDToken(token.IDToken, nonce, settings)
+		if err != nil {
+			return nil, err
+		}
+		for key, value := range idTokenClaims {
+			claims[key] = value
+		}
+	} else if settings.ResourceURI == "" {
+		return nil, portainer.ErrOAuthInvalidIDToken
+	}
+
+	if settings.ResourceURI != "" {
+		userInfo, err := service.getUserInfo(token.AccessToken, settings.ResourceURI)
+		if err != nil {
+			return nil, err
+		}
+
+		// The userinfo response must describe the same subject as the ID token.
+		if subject, ok := claims["sub"]; ok && userInfo["sub"] != subject {
+			return nil, portainer.ErrOAuthInvalidIDToken
+		}
+
+		for key, value := range userInfo {
+			if _, ok := claims[key]; !ok {
+				claims[key] = value
+			}
+		}
+	}
+
+	username := claimAsString(claims[settings.UserIdentifier])
+	if username == "" {
+		return nil, portainer.ErrOAuthUserIdentifierNotFound
+	}
+
+	identity := &portainer.OAuthIdentity{
+		Username: username,
+		Groups:   make([]string, 0),
+	}
+	if settings.GroupsClaim != "" {
+		identity.Groups = claimAsStrings(claims[settings.GroupsClaim])
+	}
+
+	return identity, nil
+}
+
+// exchangeCode retrieves the provider tokens associated to an authorization code.
+// The client authenticates using HTTP basic authentication as described in RFC 6749 section 2.3.1.
+func (service *Service) exchangeCode(code string, settings *portainer.OAuthSettings) (*tokenResponse, error) {
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", settings.RedirectURI)
+
+	req, err := http.NewRequest(http.MethodPost, settings.AccessTokenURI, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.SetBasicAuth(url.QueryEscape(settings.ClientID), url.QueryEscape(settings.ClientSecret))
+
+	resp, err := service.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Unable to exchange the authorization code (status: %d)", resp.StatusCode)
+	}
+
+	var token tokenResponse
+	err = json.NewDecoder(resp.Body).Decode(&token)
+	if err != nil {
+		return nil, err
+	}
+
+	if token.AccessToken == "" {
+		return nil, fmt.Errorf("No access token found in the token response")
+	}
+
+	return &token, nil
+}
+
+// verifyIDToken checks the signature, issuer, audience, expiry and nonce of an ID token and returns its claims.
+// RSA signed tokens are verified with the keys published at the JWKS endpoint, HMAC signed tokens with the client secret.
+func (service *Service) verifyIDToken(idToken, nonce string, settings *portainer.OAuthSettings) (jwt.MapClaims, error) {
+	claims := jwt.MapClaims{}
+	parsedToken, err := jwt.ParseWithClaims(idToken, claims, func(token *jwt.Token) (interface{}, error) {
+		switch token.Method.(type) {
+		case *jwt.SigningMethodRSA:
+			keyID, _ := token.Header["kid"].(string)
+			return service.getSigningKey(keyID, settings.JWKSURI)
+		case *jwt.SigningMethodHMAC:
+			if settings.ClientSecret == "" {
+				return nil, fmt.Errorf("No client secret available to verify the token")
+			}
+			return []byte(settings.ClientSecret), nil
+		}
+		return nil, fmt.Errorf("Unexpected signing method: %v", token.Header["alg"])
+	})
+	if err != nil || !parsedToken.Valid {
+		return nil, portainer.ErrOAuthInvalidIDToken
+	}
+
+	if !claims.VerifyExpiresAt(time.Now().Unix(), true) {
+		return nil, portainer.ErrOAuthInvalidIDToken
+	}
+
+	if settings.Issuer != "" && !claims.VerifyIssuer(settings.Issuer, true) {
+		return nil, portainer.ErrOAuthInvalidIDToken
+	}
+
+	if !containsAudience(claims["aud"], settings.ClientID) {
+		return nil, portainer.ErrOAuthInvalidIDToken
+	}
+
+	if tokenNonce, _ := claims["nonce"].(string); tokenNonce != nonce {
+		return nil, portainer.ErrOAuthInvalidIDToken
+	}
+
+	return claims, nil
+}
+
+// getSigningKey retrieves the RSA public key identified by keyID from the JWKS endpoint.
+// When the token does not specify a key ID, the key set must contain a single RSA key.
+func (service *Service) getSigningKey(keyID, jwksURI string) (*rsa.PublicKey, error) {
+	if jwksURI == "" {
+		return nil, fmt.Errorf("No JWKS endpoint configured")
+	}
+
+	resp, err := service.client.Get(jwksURI)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Unable to retrieve the JWKS (status: %d)", resp.StatusCode)
+	}
+
+	var keySet jsonWebKeySet
+	err = json.NewDecoder(resp.Body).Decode(&keySet)
+	if err != nil {
+		return nil, err
+	}
+
+	rsaKeys := make([]jsonWebKey, 0)
+	for _, key := range keySet.Keys {
+		if key.KeyType == "RSA" {
+			rsaKeys = append(rsaKeys, key)
+		}
+	}
+
+	for _, key := range rsaKeys {
+		if key.KeyID == keyID || (keyID == "" && len(rsaKeys) == 1) {
+			return parseRSAPublicKey(key)
+		}
+	}
+
+	return nil, fmt.Errorf("Unable to find the signing key %s", keyID)
+}
+
+// getUserInfo retrieves the claims returned by the resource endpoint of the provider.
+func (service *Service) getUserInfo(accessToken, resourceURI string) (map[string]interface{}, error) {
+	req, err := http.NewRequest(http.MethodGet, resourceURI, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := service.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Unable to retrieve the user information (status: %d)", resp.StatusCode)
+	}
+
+	userInfo := make(map[string]interface{})
+	err = json.NewDecoder(resp.Body).Decode(&userInfo)
+	if err != nil {
+		return nil, err
+	}
+
+	return userInfo, nil
+}
+
+func parseRSAPublicKey(key jsonWebKey) (*rsa.PublicKey, error) {
+	modulus, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(key.N, "="))
+	if err != nil {
+		return nil, err
+	}
+
+	exponent, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(key.E, "="))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(exponent) == 0 || len(exponent) > 4 {
+		return nil, fmt.Errorf("Invalid RSA exponent for key %s", key.KeyID)
+	}
+
+	e := 0
+	for _, b := range exponent {
+		e = e<<8 | int(b)
+	}
+
+	return &rsa.PublicKey{
+		N: new(big.Int).SetBytes(modulus),
+		E: e,
+	}, nil
+}
+
+// containsAudience checks the aud claim, which can either be a string or an array of strings.
+func containsAudience(audience interface{}, clientID string) bool {
+	for _, value := range claimAsStrings(audience) {
+		if value == clientID {
+			return true
+		}
+	}
+	return false
+}
+
+func claimAsString(claim interface{}) string {
+	switch value := claim.(type) {
+	case string:
+		return value
+	case float64:
+		return fmt.Sprintf("%.0f", value)
+	}
+	return ""
+}
+
+func claimAsStrings(claim interface{}) []string {
+	values := make([]string, 0)
+	switch claimValue := claim.(type) {
+	case string:
+		values = append(values, claimValue)
+	case []interface{}:
+		for _, item := range claimValue {
+			if value, ok := item.(string); ok {
+				values = append(values, value)
+			}
+		}
+	}
+	return values
+}
@@ -0,0 +1,226 @@
+package oauth
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/portainer/portainer"
+)
+
+const (
+	stubClientID     = "portainer"
+	stubClientSecret = "secret"
+	stubCode         = "authorization-code"
+	stubAccessToken  = "access-token"
+	stubKeyID        = "stub-key"
+	stubNonce        = "nonce"
+)
+
+// stubProvider is an OAuth2/OpenID Connect provider serving the token, JWKS and userinfo endpoints.
+type stubProvider struct {
+	*httptest.Server
+	key     *rsa.PrivateKey
+	claims  jwt.MapClaims
+	subject string
+}
+
+func newStubProvider(t *testing.T) *stubProvider {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Unable to generate the provider key: %s", err)
+	}
+
+	provider := &stubProvider{key: key, subject: "user-id"}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/token", provider.handleToken)
+	mux.HandleFunc("/jwks", provider.handleJWKS)
+	mux.HandleFunc("/userinfo", provider.handleUserInfo)
+	provider.Server = httptest.NewServer(mux)
+
+	provider.claims = jwt.MapClaims{
+		"iss":   provider.URL,
+		"aud":   stubClientID,
+		"sub":   provider.subject,
+		"exp":   time.Now().Add(time.Hour).Unix(),
+		"nonce": stubNonce,
+		"email": "user@example.com",
+	}
+	return provider
+}
+
+func (provider *stubProvider) settings() *portainer.OAuthSettings {
+	return &portainer.OAuthSettings{
+		ClientID:       stubClientID,
+		ClientSecret:   stubClientSecret,
+		AccessTokenURI: provider.URL + "/token",
+		ResourceURI:    provider.URL + "/userinfo",
+		JWKSURI:        provider.URL + "/jwks",
+		Issuer:         provider.URL,
+		RedirectURI:    "https://portainer.example.com/api/auth/oauth/callback",
+		UserIdentifier: "email",
+		GroupsClaim:    "groups",
+	}
+}
+
+func (provider *stubProvider) handleToken(w http.ResponseWriter, r *http.Request) {
+	clientID, clientSecret, ok := r.BasicAuth()
+	if !ok || clientID != stubClientID || clientSecret != stubClientSecret {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	if r.PostFormValue("grant_type") != "authorization_code" || r.PostFormValue("code") != stubCode {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, provider.claims)
+	token.Header["kid"] = stubKeyID
+	idToken, err := token.SignedString(provider.key)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(&tokenResponse{
+		AccessToken: stubAccessToken,
+		TokenType:   "Bearer",
+		IDToken:     idToken,
+	})
+}
+
+func (provider *stubProvider) handleJWKS(w http.ResponseWriter, r *http.Request) {
+	json.NewEncoder(w).Encode(&jsonWebKeySet{
+		Keys: []jsonWebKey{
+			{
+				KeyType: "RSA",
+				KeyID:   stubKeyID,
+				N:       base64.RawURLEncoding.EncodeToString(provider.key.N.Bytes()),
+				E:       base64.RawURLEncoding.EncodeToString(big.NewInt(int64(provider.key.E)).Bytes()),
+			},
+		},
+	})
+}
+
+func (provider *stubProvider) handleUserInfo(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Authorization") != "Bearer "+stubAccessToken {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"sub":    provider.subject,
+		"groups": []string{"developers", "operators"},
+	})
+}
+
+func TestAuthenticate(t *testing.T) {
+	provider := newStubProvider(t)
+	defer provider.Close()
+
+	identity, err := NewService().Authenticate(stubCode, stubNonce, provider.settings())
+	if err != nil {
+		t.Fatalf("Unexpected authentication error: %s", err)
+	}
+	if identity.Username != "user@example.com" {
+		t.Errorf("Unexpected username: %s", identity.Username)
+	}
+	if len(identity.Groups) != 2 || identity.Groups[0] != "developers" || identity.Groups[1] != "operators" {
+		t.Errorf("Unexpected groups: %v", identity.Groups)
+	}
+}
+
+func TestAuthenticateRejectsInvalidTokens(t *testing.T) {
+	provider := newStubProvider(t)
+	defer provider.Close()
+
+	cases := []struct {
+		name   string
+		code   string
+		nonce  string
+		mutate func(provider *stubProvider, settings *portainer.OAuthSettings)
+	}{
+		{name: "invalid code", code: "invalid", nonce: stubNonce},
+		{name: "invalid nonce", code: stubCode, nonce: "invalid"},
+		{
+			name: "invalid client secret", code: stubCode, nonce: stubNonce,
+			mutate: func(provider *stubProvider, settings *portainer.OAuthSettings) { settings.ClientSecret = "invalid" },
+		},
+		{
+			name: "invalid issuer", code: stubCode, nonce: stubNonce,
+			mutate: func(provider *stubProvider, settings *portainer.OAuthSettings) {
+				settings.Issuer = "https://issuer.example.com"
+			},
+		},
+		{
+			name: "invalid audience", code: stubCode, nonce: stubNonce,
+			mutate: func(provider *stubProvider, settings *portainer.OAuthSettings) { settings.ClientID = "other" },
+		},
+		{
+			name: "expired token", code: stubCode, nonce: stubNonce,
+			mutate: func(provider *stubProvider, settings *portainer.OAuthSettings) {
+				provider.claims["exp"] = time.Now().Add(-time.Hour).Unix()
+			},
+		},
+		{
+			name: "userinfo subject mismatch", code: stubCode, nonce: stubNonce,
+			mutate: func(provider *stubProvider, settings *portainer.OAuthSettings) { provider.subject = "other-user" },
+		},
+	}
+
+	for _, c := range cases {
+		provider.claims["exp"] = time.Now().Add(time.Hour).Unix()
+		provider.subject = "user-id"
+		provider.claims["sub"] = provider.subject
+
+		settings := provider.settings()
+		if c.mutate != nil {
+			c.mutate(provider, settings)
+		}
+
+		_, err := NewService().Authenticate(c.code, c.nonce, settings)
+		if err == nil {
+			t.Errorf("%s: expected an authentication error", c.name)
+		}
+	}
+}
+
+func TestGetAuthorizationURL(t *testing.T) {
+	settings := &portainer.OAuthSettings{
+		ClientID:         stubClientID,
+		AuthorizationURI: "https://provider.example.com/authorize",
+		RedirectURI:      "https://portainer.example.com/api/auth/oauth/callback",
+		Scopes:           "openid email",
+	}
+
+	authorizationURL, err := NewService().GetAuthorizationURL("state", stubNonce, settings)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	u, err := url.Parse(authorizationURL)
+	if err != nil {
+		t.Fatalf("Invalid authorization URL: %s", err)
+	}
+	query := u.Query()
+	expected := map[string]string{
+		"response_type": "code",
+		"client_id":     stubClientID,
+		"redirect_uri":  settings.RedirectURI,
+		"scope":         settings.Scopes,
+		"state":         "state",
+		"nonce":         stubNonce,
+	}
+	for key, value := range expected {
+		if query.Get(key) != value {
+			t.Errorf("Unexpected %s parameter: %s", key, query.Get(key))
+		}
+	}
+}
@@ -1,6 +1,10 @@
 package portainer
 
-import "io"
+import (
+	"io"
+	"net"
+	"time"
+)
 
 type (
 	// Pair defines a key/value string pair
@@ -11,22 +15,27 @@ type (
 
 	// CLIFlags represents the available flags on the CLI.
 	CLIFlags struct {
-		Addr              *string
-		Assets            *string
-		Data              *string
-		ExternalEndpoints *string
-		SyncInterval      *string
-		Endpoint          *string
-		NoAuth            *bool
-		NoAnalytics       *bool
-		TLSVerify         *bool
-		TLSCacert         *string
-		TLSCert           *string
-		TLSKey            *string
-		SSL               *bool
-		SSLCert           *string
-		SSLKey            *string
-		AdminPassword     *string
+		Addr                *string
+		Assets              *string
+		Data                *string
+		ExternalEndpoints   *string
+		SyncInterval        *string
+		Endpoint            *string
+		NoAuth              *bool
+		NoAnalytics         *bool
+		TLSVerify           *bool
+		TLSCacert           *string
+		TLSCert             *string
+		TLSKey              *string
+		SSL                 *bool
+		SSLCert             *string
+		SSLKey              *string
+		AdminPassword       *string
+		LDAPSyncInterval    *string
+		HealthCheckInterval *string
+		EncryptionKey       *string
+		EncryptionKeyFile   *string
+		RotateKeyFile       *string
 		// Deprecated fields
 		Logo      *string
 		Templates *string
@@ -43,18 +52,161 @@ type (
 
 	// Settings represents the application settings.
 	Settings struct {
-		TemplatesURL                string `json:"TemplatesURL"`
-		LogoURL                     string `json:"LogoURL"`
-		BlackListedLabels           []Pair `json:"BlackListedLabels"`
-		DisplayExternalContributors bool   `json:"DisplayExternalContributors"`
+		TemplatesURL                string                 `json:"TemplatesURL"`
+		LogoURL                     string                 `json:"LogoURL"`
+		BlackListedLabels           []Pair                 `json:"BlackListedLabels"`
+		DisplayExternalContributors bool                   `json:"DisplayExternalContributors"`
+		AuthenticationMethod        AuthenticationMethod   `json:"AuthenticationMethod"`
+		LDAPSettings                LDAPSettings           `json:"LDAPSettings"`
+		OAuthSettings               OAuthSettings          `json:"OAuthSettings"`
+		LoginRateLimitSettings      LoginRateLimitSettings `json:"LoginRateLimitSettings"`
+		EnforceTwoFactorForAdmins   bool                   `json:"EnforceTwoFactorForAdmins"`
+		ExecRecordingSettings       ExecRecordingSettings  `json:"ExecRecordingSettings"`
+		AuditSinks                  []AuditSink            `json:"AuditSinks"`
+	}
+
+	// AuditSink represents a destination the audit logs are forwarded to.
+	// Syslog sinks use an URL with the udp, tcp or tls scheme, webhook sinks use an http or https URL
+	// and the Token is sent as a bearer token. File sinks write to the file named Path in the audit folder
+	// of the file store and rotate the file when it exceeds MaxSize megabytes, keeping MaxBackups rotated files.
+	AuditSink struct {
+		Type          AuditSinkType `json:"Type"`
+		URL           string        `json:"URL"`
+		TLSSkipVerify bool          `json:"TLSSkipVerify"`
+		Token         string        `json:"Token,omitempty"`
+		Path          string        `json:"Path"`
+		MaxSize       int           `json:"MaxSize"`
+		MaxBackups    int           `json:"MaxBackups"`
+	}
+
+	// AuditSinkType represents the type of an audit sink.
+	AuditSinkType int
+
+	// ExecRecordingSettings represents the settings used to record the exec sessions opened through the console.
+	// Recordings are kept forever when RetentionDays is set to 0.
+	ExecRecordingSettings struct {
+		Enabled       bool `json:"Enabled"`
+		RetentionDays int  `json:"RetentionDays"`
+	}
+
+	// ExecRecording represents the recording of an exec session, stored in the asciicast format.
+	ExecRecording struct {
+		ID          string     `json:"Id"`
+		EndpointID  EndpointID `json:"EndpointId"`
+		ContainerID string     `json:"ContainerId"`
+		UserID      UserID     `json:"UserId"`
+		Username    string     `json:"Username"`
+		StartedAt   int64      `json:"StartedAt"`
+		Size        int64      `json:"Size"`
+	}
+
+	// LoginRateLimitSettings represents the settings used to protect the authentication against brute-force attacks.
+	// Durations are expressed in seconds, a value of 0 disables the associated limit.
+	LoginRateLimitSettings struct {
+		MaxFailedAttemptsPerUser int `json:"MaxFailedAttemptsPerUser"`
+		MaxFailedAttemptsPerIP   int `json:"MaxFailedAttemptsPerIP"`
+		LockoutDuration          int `json:"LockoutDuration"`
+		BackoffBaseDelay         int `json:"BackoffBaseDelay"`
+		BackoffMaxDelay          int `json:"BackoffMaxDelay"`
+	}
+
+	// AuthenticationMethod represents the authentication method used to authenticate a user.
+	AuthenticationMethod int
+
+	// LDAPSettings represents the settings used to connect to a LDAP server.
+	LDAPSettings struct {
+		ReaderDN            string                    `json:"ReaderDN"`
+		Password            string                    `json:"Password,omitempty"`
+		URL                 string                    `json:"URL"`
+		TLSConfig           LDAPTLSConfiguration      `json:"TLSConfig"`
+		StartTLS            bool                      `json:"StartTLS"`
+		SearchSettings      []LDAPSearchSettings      `json:"SearchSettings"`
+		AutoCreateUsers     bool                      `json:"AutoCreateUsers"`
+		GroupSearchSettings []LDAPGroupSearchSettings `json:"GroupSearchSettings"`
+		GroupTeamMappings   []LDAPGroupTeamMapping    `json:"GroupTeamMappings"`
+	}
+
+	// LDAPTLSConfiguration represents the TLS configuration used to connect to a LDAP server.
+	LDAPTLSConfiguration struct {
+		TLS           bool   `json:"TLS"`
+		TLSSkipVerify bool   `json:"TLSSkipVerify"`
+		TLSCACertPath string `json:"TLSCACert,omitempty"`
+	}
+
+	// LDAPSearchSettings represents settings used to search for users in a LDAP server.
+	LDAPSearchSettings struct {
+		BaseDN            string `json:"BaseDN"`
+		Filter            string `json:"Filter"`
+		UserNameAttribute string `json:"UserNameAttribute"`
+	}
+
+	// LDAPGroupSearchSettings represents settings used to search for the groups of a user in a LDAP server.
+	LDAPGroupSearchSettings struct {
+		GroupBaseDN    string `json:"GroupBaseDN"`
+		GroupFilter    string `json:"GroupFilter"`
+		GroupAttribute string `json:"GroupAttribute"`
+	}
+
+	// LDAPGroupTeamMapping represents an association between a LDAP group and a team.
+	// Members of the LDAP group are automatically added to the team.
+	LDAPGroupTeamMapping struct {
+		GroupDN string `json:"GroupDN"`
+		TeamID  TeamID `json:"TeamId"`
+	}
+
+	// LDAPSyncReport represents the result of a synchronization between the LDAP groups
+	// and the team memberships.
+	LDAPSyncReport struct {
+		StartedAt          int64            `json:"StartedAt"`
+		EndedAt            int64            `json:"EndedAt"`
+		CreatedMemberships []TeamMembership `json:"CreatedMemberships"`
+		DeletedMemberships []TeamMembership `json:"DeletedMemberships"`
+		Errors             []string         `json:"Errors"`
+	}
+
+	// OAuthSettings represents the settings used to authenticate users against an OAuth2/OpenID Connect provider.
+	OAuthSettings struct {
+		ClientID          string                  `json:"ClientID"`
+		ClientSecret      string                  `json:"ClientSecret,omitempty"`
+		AuthorizationURI  string                  `json:"AuthorizationURI"`
+		AccessTokenURI    string                  `json:"AccessTokenURI"`
+		ResourceURI       string                  `json:"ResourceURI"`
+		JWKSURI           string                  `json:"JWKSURI"`
+		Issuer            string                  `json:"Issuer"`
+		RedirectURI       string                  `json:"RedirectURI"`
+		Scopes            string                  `json:"Scopes"`
+		UserIdentifier    string                  `json:"UserIdentifier"`
+		GroupsClaim       string                  `json:"GroupsClaim"`
+		GroupTeamMappings []OAuthGroupTeamMapping `json:"GroupTeamMappings"`
+		AutoCreateUsers   bool                    `json:"AutoCreateUsers"`
+	}
+
+	// OAuthGroupTeamMapping represents an association between a group claim value and a team.
+	// Users carrying the group in their claims are automatically added to the team when they log in.
+	OAuthGroupTeamMapping struct {
+		Group  string `json:"Group"`
+		TeamID TeamID `json:"TeamId"`
+	}
+
+	// OAuthIdentity represents the identity of a user authenticated by an OAuth provider.
+	OAuthIdentity struct {
+		Username string
+		Groups   []string
 	}
 
 	// User represents a user account.
 	User struct {
-		ID       UserID   `json:"Id"`
-		Username string   `json:"Username"`
-		Password string   `json:"Password,omitempty"`
-		Role     UserRole `json:"Role"`
+		ID                UserID   `json:"Id"`
+		Username          string   `json:"Username"`
+		Password          string   `json:"Password,omitempty"`
+		Role              UserRole `json:"Role"`
+		LockedUntil       int64    `json:"LockedUntil"`
+		TOTPEnabled       bool     `json:"TOTPEnabled"`
+		TOTPSecret        string   `json:"TOTPSecret,omitempty"`
+		TOTPRecoveryCodes []string `json:"TOTPRecoveryCodes,omitempty"`
+		TOTPLastCounter   int64    `json:"TOTPLastCounter,omitempty"`
+		// External is set on the accounts managed by the OAuth provider, the provider can only sign into these accounts.
+		External bool `json:"External"`
 	}
 
 	// UserID represents a user identifier
@@ -64,6 +216,53 @@ type (
 	// or a regular user
 	UserRole int
 
+	// APIKey represents a long-lived personal access key used to authenticate against the API.
+	// Only a digest of the key is stored, the key itself is returned once when it is created.
+	APIKey struct {
+		ID         APIKeyID `json:"Id"`
+		UserID     UserID   `json:"UserId"`
+		Name       string   `json:"Name"`
+		Prefix     string   `json:"Prefix"`
+		Digest     string   `json:"Digest,omitempty"`
+		CreatedAt  int64    `json:"CreatedAt"`
+		LastUsedAt int64    `json:"LastUsedAt"`
+		ExpiresAt  int64    `json:"ExpiresAt"`
+	}
+
+	// APIKeyID represents an API key identifier
+	APIKeyID int
+
+	// AuditLog represents the record of a mutating request sent to the API or to the Docker API of an endpoint.
+	AuditLog struct {
+		ID         AuditLogID `json:"Id"`
+		Timestamp  int64      `json:"Timestamp"`
+		UserID     UserID     `json:"UserId"`
+		Username   string     `json:"Username"`
+		EndpointID EndpointID `json:"EndpointId"`
+		Method     string     `json:"Method"`
+		Path       string     `json:"Path"`
+		ResourceID string     `json:"ResourceId"`
+		Status     int        `json:"Status"`
+		RemoteAddr string     `json:"RemoteAddr"`
+	}
+
+	// AuditLogID represents an audit log identifier
+	AuditLogID int
+
+	// AuditLogQuery represents the filters and the pagination used to retrieve audit logs.
+	// Zero values are ignored, a Limit of 0 returns every matching audit log.
+	AuditLogQuery struct {
+		UserID     UserID
+		EndpointID EndpointID
+		Method     string
+		ResourceID string
+		Status     int
+		Since      int64
+		Until      int64
+		Start      int
+		Limit      int
+	}
+
 	// Team represents a list of user accounts.
 	Team struct {
 		ID   TeamID `json:"Id"`
@@ -89,9 +288,26 @@ type (
 
 	// TokenData represents the data embedded in a JWT token.
 	TokenData struct {
-		ID       UserID
-		Username string
-		Role     UserRole
+		ID        UserID
+		Username  string
+		Role      UserRole
+		TokenID   string
+		IssuedAt  int64
+		ExpiresAt int64
+	}
+
+	// TokenRevocation represents a JWT token that has been revoked before its expiry.
+	TokenRevocation struct {
+		TokenID   string `json:"TokenId"`
+		ExpiresAt int64  `json:"ExpiresAt"`
+	}
+
+	// UserTokenRevocation represents the revocation of all the JWT tokens issued to a user
+	// before RevokedAt (unix time in milliseconds).
+	UserTokenRevocation struct {
+		UserID    UserID `json:"UserId"`
+		RevokedAt int64  `json:"RevokedAt"`
+		ExpiresAt int64  `json:"ExpiresAt"`
 	}
 
 	// RegistryID represents a registry identifier.
@@ -110,6 +326,47 @@ type (
 		AuthorizedTeams []TeamID   `json:"AuthorizedTeams"`
 	}
 
+	// RegistryManifest represents an image manifest or an image index (manifest list) stored in a registry.
+	// Size is the size of the image configuration and layers, it is not defined for an image index.
+	RegistryManifest struct {
+		Repository    string               `json:"Repository"`
+		Reference     string               `json:"Reference"`
+		Digest        string               `json:"Digest"`
+		MediaType     string               `json:"MediaType"`
+		SchemaVersion int                  `json:"SchemaVersion"`
+		ManifestSize  int64                `json:"ManifestSize"`
+		Size          int64                `json:"Size"`
+		Config        *RegistryDescriptor  `json:"Config,omitempty"`
+		Layers        []RegistryDescriptor `json:"Layers,omitempty"`
+		Manifests     []RegistryDescriptor `json:"Manifests,omitempty"`
+	}
+
+	// RegistryDescriptor represents a content referenced by a registry manifest.
+	RegistryDescriptor struct {
+		MediaType string            `json:"MediaType"`
+		Digest    string            `json:"Digest"`
+		Size      int64             `json:"Size"`
+		Platform  *RegistryPlatform `json:"Platform,omitempty"`
+	}
+
+	// RegistryPlatform represents the platform of an image referenced by an image index.
+	RegistryPlatform struct {
+		Architecture string `json:"Architecture"`
+		OS           string `json:"OS"`
+		Variant      string `json:"Variant,omitempty"`
+	}
+
+	// RegistryPingResult represents the diagnostics of a connection to a registry.
+	RegistryPingResult struct {
+		Reachable            bool   `json:"Reachable"`
+		TLS                  bool   `json:"TLS"`
+		TLSError             string `json:"TLSError,omitempty"`
+		APIVersion           string `json:"APIVersion,omitempty"`
+		AuthenticationScheme string `json:"AuthenticationScheme,omitempty"`
+		AuthenticationOK     bool   `json:"AuthenticationOK"`
+		Error                string `json:"Error,omitempty"`
+	}
+
 	// DockerHub represents all the required information to connect and use the
 	// Docker Hub.
 	DockerHub struct {
@@ -121,21 +378,83 @@ type (
 	// EndpointID represents an endpoint identifier.
 	EndpointID int
 
+	// EndpointGroupID represents an endpoint group identifier.
+	EndpointGroupID int
+
+	// EndpointGroup represents a group of endpoints. The users and teams authorized on a group
+	// are authorized on all the endpoints of the group.
+	EndpointGroup struct {
+		ID              EndpointGroupID `json:"Id"`
+		Name            string          `json:"Name"`
+		Description     string          `json:"Description"`
+		AuthorizedUsers []UserID        `json:"AuthorizedUsers"`
+		AuthorizedTeams []TeamID        `json:"AuthorizedTeams"`
+	}
+
 	// Endpoint represents a Docker endpoint with all the info required
 	// to connect to it.
 	Endpoint struct {
-		ID              EndpointID `json:"Id"`
-		Name            string     `json:"Name"`
-		URL             string     `json:"URL"`
-		PublicURL       string     `json:"PublicURL"`
-		TLS             bool       `json:"TLS"`
-		TLSCACertPath   string     `json:"TLSCACert,omitempty"`
-		TLSCertPath     string     `json:"TLSCert,omitempty"`
-		TLSKeyPath      string     `json:"TLSKey,omitempty"`
-		AuthorizedUsers []UserID   `json:"AuthorizedUsers"`
-		AuthorizedTeams []TeamID   `json:"AuthorizedTeams"`
+		ID              EndpointID      `json:"Id"`
+		Name            string          `json:"Name"`
+		GroupID         EndpointGroupID `json:"GroupId"`
+		Tags            []string        `json:"Tags"`
+		URL             string          `json:"URL"`
+		PublicURL       string          `json:"PublicURL"`
+		TLS             bool            `json:"TLS"`
+		TLSCACertPath   string          `json:"TLSCACert,omitempty"`
+		TLSCertPath     string          `json:"TLSCert,omitempty"`
+		TLSKeyPath      string          `json:"TLSKey,omitempty"`
+		AuthorizedUsers []UserID        `json:"AuthorizedUsers"`
+		AuthorizedTeams []TeamID        `json:"AuthorizedTeams"`
+		// ResourceOwnershipPolicy defines the resource control automatically associated to the resources
+		// created by standard users on this endpoint.
+		ResourceOwnershipPolicy ResourceOwnershipPolicy `json:"ResourceOwnershipPolicy"`
+		// Status, StatusMessage, LastCheck, Latency and Snapshot are updated by the endpoint health check.
+		Status        EndpointStatus    `json:"Status"`
+		StatusMessage string            `json:"StatusMessage,omitempty"`
+		LastCheck     int64             `json:"LastCheck"`
+		Latency       int64             `json:"Latency"`
+		Snapshot      *EndpointSnapshot `json:"Snapshot,omitempty"`
+		// AgentKeyDigest and Tunnel are only used by the agent endpoints, reached through the reverse
+		// tunnel opened by an agent running on the Docker host.
+		AgentKeyDigest string          `json:"AgentKeyDigest,omitempty"`
+		Tunnel         *EndpointTunnel `json:"Tunnel,omitempty"`
+		// SSHKeyPath and SSHKnownHostsPath are only used by the SSH endpoints, the Docker API is reached
+		// through the unix socket of the remote host over an SSH connection.
+		SSHKeyPath        string `json:"SSHKey,omitempty"`
+		SSHKnownHostsPath string `json:"SSHKnownHosts,omitempty"`
 	}
 
+	// EndpointTunnel represents the state of the reverse tunnel of an agent endpoint.
+	EndpointTunnel struct {
+		Status         TunnelStatus `json:"Status"`
+		RemoteAddr     string       `json:"RemoteAddr"`
+		AgentVersion   string       `json:"AgentVersion"`
+		ConnectedAt    int64        `json:"ConnectedAt"`
+		DisconnectedAt int64        `json:"DisconnectedAt"`
+	}
+
+	// TunnelStatus represents the status of the reverse tunnel of an agent endpoint.
+	TunnelStatus int
+
+	// EndpointStatus represents the status of an endpoint as reported by the last health check.
+	EndpointStatus int
+
+	// EndpointSnapshot represents a summary of the Docker environment of an endpoint,
+	// taken during the last successful health check.
+	EndpointSnapshot struct {
+		Time                  int64  `json:"Time"`
+		DockerVersion         string `json:"DockerVersion"`
+		Swarm                 bool   `json:"Swarm"`
+		RunningContainerCount int    `json:"RunningContainerCount"`
+		StoppedContainerCount int    `json:"StoppedContainerCount"`
+		ImageCount            int    `json:"ImageCount"`
+		VolumeCount           int    `json:"VolumeCount"`
+	}
+
+	// ResourceOwnershipPolicy represents the default ownership of the resources created on an endpoint.
+	ResourceOwnershipPolicy int
+
 	// ResourceControlID represents a resource control identifier.
 	ResourceControlID int
 
@@ -157,7 +476,8 @@ type (
 		AccessLevel ResourceAccessLevel `json:"AccessLevel"`
 	}
 
-	// ResourceControlType represents the type of resource associated to the resource control (volume, container, service).
+	// ResourceControlType represents the type of resource associated to the resource control (volume, container, service,
+	// network, secret, config, image).
 	ResourceControlType int
 
 	// UserResourceAccess represents the level of control on a resource for a specific user.
@@ -179,6 +499,10 @@ type (
 	// It can be either a TLS CA file, a TLS certificate file or a TLS key file.
 	TLSFileType int
 
+	// SSHFileType represents a type of SSH file required to connect to an SSH endpoint.
+	// It can be either a private key file or a known hosts file.
+	SSHFileType int
+
 	// CLIService represents a service for managing CLI.
 	CLIService interface {
 		ParseFlags(version string) (*CLIFlags, error)
@@ -231,6 +555,17 @@ type (
 		DeleteTeamMembershipByTeamID(teamID TeamID) error
 	}
 
+	// APIKeyService represents a service for managing API key data.
+	APIKeyService interface {
+		APIKey(ID APIKeyID) (*APIKey, error)
+		APIKeyByDigest(digest string) (*APIKey, error)
+		APIKeysByUserID(userID UserID) ([]APIKey, error)
+		CreateAPIKey(apiKey *APIKey) error
+		UpdateAPIKey(ID APIKeyID, apiKey *APIKey) error
+		DeleteAPIKey(ID APIKeyID) error
+		DeleteAPIKeysByUserID(userID UserID) error
+	}
+
 	// EndpointService represents a service for managing endpoint data.
 	EndpointService interface {
 		Endpoint(ID EndpointID) (*Endpoint, error)
@@ -241,6 +576,28 @@ type (
 		Synchronize(toCreate, toUpdate, toDelete []*Endpoint) error
 	}
 
+	// EndpointGroupService represents a service for managing endpoint group data.
+	EndpointGroupService interface {
+		EndpointGroup(ID EndpointGroupID) (*EndpointGroup, error)
+		EndpointGroups() ([]EndpointGroup, error)
+		CreateEndpointGroup(group *EndpointGroup) error
+		UpdateEndpointGroup(ID EndpointGroupID, group *EndpointGroup) error
+		DeleteEndpointGroup(ID EndpointGroupID) error
+	}
+
+	// TunnelService represents a service for managing the reverse tunnels opened by the agents.
+	TunnelService interface {
+		Serve(endpoint *Endpoint, conn net.Conn, remoteAddr, agentVersion string) error
+		Dial(endpointID EndpointID) (net.Conn, error)
+		CloseTunnel(endpointID EndpointID)
+	}
+
+	// SSHService represents a service for managing the SSH connections to the SSH endpoints.
+	SSHService interface {
+		Dial(endpoint *Endpoint) (net.Conn, error)
+		CloseConnection(endpointID EndpointID)
+	}
+
 	// RegistryService represents a service for managing registry data.
 	RegistryService interface {
 		Registry(ID RegistryID) (*Registry, error)
@@ -284,17 +641,100 @@ type (
 		CompareHashAndData(hash string, data string) error
 	}
 
+	// EncryptionService represents a service used to encrypt the secrets stored by Portainer.
+	EncryptionService interface {
+		Encrypt(data []byte) ([]byte, error)
+		Decrypt(data []byte) ([]byte, error)
+	}
+
 	// JWTService represents a service for managing JWT tokens.
 	JWTService interface {
 		GenerateToken(data *TokenData) (string, error)
 		ParseAndVerifyToken(token string) (*TokenData, error)
+		GenerateTwoFactorToken(data *TokenData) (string, error)
+		ParseAndVerifyTwoFactorToken(token string) (*TokenData, error)
+		RotateSecret() error
+		RevokeToken(data *TokenData) error
+		RevokeUserTokens(userID UserID) error
+	}
+
+	// JWTSecretService represents a service for managing the secret used to sign JWT tokens.
+	JWTSecretService interface {
+		Secret() ([]byte, error)
+		StoreSecret(secret []byte) error
+	}
+
+	// TokenRevocationService represents a service for managing revoked JWT tokens.
+	TokenRevocationService interface {
+		TokenRevocation(tokenID string) (*TokenRevocation, error)
+		UserTokenRevocation(userID UserID) (*UserTokenRevocation, error)
+		RevokeToken(revocation *TokenRevocation) error
+		RevokeUserTokens(revocation *UserTokenRevocation) error
+		DeleteExpiredRevocations(now int64) error
+	}
+
+	// AuditLogService represents a service for managing audit logs.
+	AuditLogService interface {
+		AuditLogs(query *AuditLogQuery) ([]AuditLog, int, error)
+		CreateAuditLog(auditLog *AuditLog) error
+	}
+
+	// AuditForwarder represents a service used to forward the audit logs to external sinks.
+	AuditForwarder interface {
+		Configure(sinks []AuditSink) error
+		Forward(auditLog *AuditLog)
 	}
 
 	// FileService represents a service for managing files.
 	FileService interface {
-		StoreTLSFile(endpointID EndpointID, fileType TLSFileType, r io.Reader) error
-		GetPathForTLSFile(endpointID EndpointID, fileType TLSFileType) (string, error)
-		DeleteTLSFiles(endpointID EndpointID) error
+		StoreTLSFile(folder string, fileType TLSFileType, r io.Reader) error
+		GetPathForTLSFile(folder string, fileType TLSFileType) (string, error)
+		DeleteTLSFiles(folder string) error
+		StoreSSHFile(folder string, fileType SSHFileType, r io.Reader) error
+		GetPathForSSHFile(folder string, fileType SSHFileType) (string, error)
+		DeleteSSHFiles(folder string) error
+		EncryptKeyFiles(encryptionService EncryptionService) error
+		CreateExecRecording(recording *ExecRecording) (ExecRecorder, error)
+		ExecRecordings() ([]ExecRecording, error)
+		GetPathForExecRecording(ID string) (string, error)
+		DeleteExecRecording(ID string) error
+		GetAuditLogStorePath() string
+	}
+
+	// ExecRecorder represents a service used to record the events of an exec session.
+	ExecRecorder interface {
+		RecordInput(data []byte) error
+		RecordOutput(data []byte) error
+		RecordResize(width, height int) error
+		Close() error
+	}
+
+	// LDAPService represents a service used to authenticate users against a LDAP/AD.
+	LDAPService interface {
+		AuthenticateUser(username, password string, settings *LDAPSettings) error
+		TestConnectivity(settings *LDAPSettings) error
+		GetUserGroups(username string, settings *LDAPSettings) ([]string, error)
+	}
+
+	// LDAPSyncService represents a service used to synchronize team memberships with LDAP groups.
+	LDAPSyncService interface {
+		Synchronize() (*LDAPSyncReport, error)
+		Reports() []LDAPSyncReport
+	}
+
+	// OAuthService represents a service used to authenticate users against an OAuth2/OpenID Connect provider.
+	OAuthService interface {
+		GetAuthorizationURL(state, nonce string, settings *OAuthSettings) (string, error)
+		Authenticate(code, nonce string, settings *OAuthSettings) (*OAuthIdentity, error)
+	}
+
+	// RegistryBrowserService represents a service used to browse a registry using the Docker Registry HTTP API v2.
+	RegistryBrowserService interface {
+		Repositories(registry *Registry) ([]string, error)
+		Tags(registry *Registry, repository string) ([]string, error)
+		Manifest(registry *Registry, repository, reference string) (*RegistryManifest, error)
+		DeleteManifest(registry *Registry, repository, reference string) error
+		Ping(registry *Registry) *RegistryPingResult
 	}
 
 	// EndpointWatcher represents a service to synchronize the endpoints via an external source.
@@ -307,9 +747,25 @@ const (
 	// APIVersion is the version number of the Portainer API.
 	APIVersion = "1.13.6"
 	// DBVersion is the version number of the Portainer database.
-	DBVersion = 2
+	DBVersion = 7
+	// JWTTokenLifetime represents the validity period of a JWT token.
+	JWTTokenLifetime = 8 * time.Hour
+	// TwoFactorTokenLifetime represents the time allowed to a user to complete the two-factor authentication.
+	TwoFactorTokenLifetime = 5 * time.Minute
 	// DefaultTemplatesURL represents the default URL for the templates definitions.
 	DefaultTemplatesURL = "https://raw.githubusercontent.com/portainer/templates/master/templates.json"
+	// DefaultMaxFailedAttemptsPerUser represents the default number of failed authentications before a user is locked.
+	DefaultMaxFailedAttemptsPerUser = 5
+	// DefaultMaxFailedAttemptsPerIP represents the default number of failed authentications before a client IP is blocked.
+	DefaultMaxFailedAttemptsPerIP = 20
+	// DefaultLoginLockoutDuration represents the default lockout duration in seconds.
+	DefaultLoginLockoutDuration = 900
+	// DefaultLoginBackoffBaseDelay represents the default delay in seconds after a first failed authentication.
+	DefaultLoginBackoffBaseDelay = 1
+	// DefaultLoginBackoffMaxDelay represents the default maximum delay in seconds between two authentication attempts.
+	DefaultLoginBackoffMaxDelay = 60
+	// DefaultEndpointGroupID represents the identifier of the group containing the endpoints not assigned to another group.
+	DefaultEndpointGroupID = 1
 )
 
 const (
@@ -321,6 +777,13 @@ const (
 	TLSFileKey
 )
 
+const (
+	// SSHFileKey represents an SSH private key file.
+	SSHFileKey SSHFileType = iota
+	// SSHFileKnownHosts represents an SSH known hosts file.
+	SSHFileKnownHosts
+)
+
 const (
 	_ MembershipRole = iota
 	// TeamLeader represents a leader role inside a team
@@ -337,10 +800,58 @@ const (
 	StandardUserRole
 )
 
+const (
+	_ AuthenticationMethod = iota
+	// AuthenticationInternal represents the internal authentication method (authentication against Portainer API)
+	AuthenticationInternal
+	// AuthenticationLDAP represents the LDAP authentication method (authentication against a LDAP server)
+	AuthenticationLDAP
+	// AuthenticationOAuth represents the OAuth authentication method (authentication against an OAuth2/OpenID Connect provider)
+	AuthenticationOAuth
+)
+
+const (
+	_ AuditSinkType = iota
+	// SyslogAuditSink represents a sink sending the audit logs to a syslog server (RFC 5424)
+	SyslogAuditSink
+	// WebhookAuditSink represents a sink posting the audit logs as newline-delimited JSON to an HTTP endpoint
+	WebhookAuditSink
+	// FileAuditSink represents a sink writing the audit logs to a local file with rotation
+	FileAuditSink
+)
+
 const (
 	_ ResourceAccessLevel = iota
 	// ReadWriteAccessLevel represents an access level with read-write permissions on a resource
 	ReadWriteAccessLevel
+	// ReadOnlyAccessLevel represents an access level with read-only permissions on a resource
+	ReadOnlyAccessLevel
+)
+
+const (
+	_ TunnelStatus = iota
+	// TunnelStatusConnected represents a reverse tunnel currently opened by the agent of an endpoint
+	TunnelStatusConnected
+	// TunnelStatusDisconnected represents a reverse tunnel closed or lost, waiting for the agent to reconnect
+	TunnelStatusDisconnected
+)
+
+const (
+	_ EndpointStatus = iota
+	// EndpointStatusUp represents an endpoint whose Docker API was reachable during the last health check
+	EndpointStatusUp
+	// EndpointStatusDown represents an endpoint whose Docker API was unreachable during the last health check
+	EndpointStatusDown
+)
+
+const (
+	_ ResourceOwnershipPolicy = iota
+	// PublicResourceOwnership represents a policy where created resources are accessible to every user
+	PublicResourceOwnership
+	// PrivateResourceOwnership represents a policy where created resources are only accessible to their creator
+	PrivateResourceOwnership
+	// TeamResourceOwnership represents a policy where created resources are accessible to the teams of their creator
+	TeamResourceOwnership
 )
 
 const (
@@ -351,4 +862,12 @@ const (
 	ServiceResourceControl
 	// VolumeResourceControl represents a resource control associated to a Docker volume
 	VolumeResourceControl
+	// NetworkResourceControl represents a resource control associated to a Docker network
+	NetworkResourceControl
+	// SecretResourceControl represents a resource control associated to a Docker secret
+	SecretResourceControl
+	// ConfigResourceControl represents a resource control associated to a Docker config
+	ConfigResourceControl
+	// ImageResourceControl represents a resource control associated to a Docker image
+	ImageResourceControl
 )
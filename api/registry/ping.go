@@ -0,0 +1,90 @@
+package registry
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/portainer/portainer"
+)
+
+// Ping checks that a registry implements the Docker Registry HTTP API v2 by requesting the /v2/ endpoint.
+// When the registry answers with an authentication challenge, the challenge is answered using the
+// credentials of the registry. Connection, TLS and authentication failures are reported in the result.
+func (service *Service) Ping(registry *portainer.Registry) *portainer.RegistryPingResult {
+	result := &portainer.RegistryPingResult{}
+
+	requestURL, err := registryURL(registry.URL, "/v2/")
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	result.TLS = strings.HasPrefix(requestURL, "https://")
+
+	resp, err := service.send(http.MethodGet, requestURL, "")
+	if err != nil {
+		if detail := tlsErrorDetail(err); detail != "" {
+			result.Reachable = true
+			result.TLSError = detail
+		}
+		result.Error = err.Error()
+		return result
+	}
+	resp.Body.Close()
+
+	result.Reachable = true
+	result.APIVersion = resp.Header.Get("Docker-Distribution-Api-Version")
+
+	if resp.StatusCode == http.StatusUnauthorized {
+		challenge := resp.Header.Get("WWW-Authenticate")
+		if fields := strings.Fields(challenge); len(fields) > 0 {
+			result.AuthenticationScheme = strings.ToLower(fields[0])
+		}
+
+		authorization, err := service.authorization(registry, challenge, "")
+		if err != nil {
+			result.Error = err.Error()
+			return result
+		}
+
+		resp, err = service.send(http.MethodGet, requestURL, authorization)
+		if err != nil {
+			result.Error = err.Error()
+			return result
+		}
+		resp.Body.Close()
+	}
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		result.AuthenticationOK = true
+	case http.StatusUnauthorized, http.StatusForbidden:
+		result.Error = portainer.ErrRegistryAuthenticationFailed.Error()
+	default:
+		result.Error = portainer.ErrRegistryAPINotSupported.Error()
+	}
+	return result
+}
+
+// tlsErrorDetail returns the description of a certificate verification or TLS handshake error.
+// It returns an empty string when the error is not related to TLS.
+func tlsErrorDetail(err error) string {
+	var unknownAuthorityError x509.UnknownAuthorityError
+	var hostnameError x509.HostnameError
+	var certificateInvalidError x509.CertificateInvalidError
+	var recordHeaderError tls.RecordHeaderError
+
+	switch {
+	case errors.As(err, &unknownAuthorityError):
+		return unknownAuthorityError.Error()
+	case errors.As(err, &hostnameError):
+		return hostnameError.Error()
+	case errors.As(err, &certificateInvalidError):
+		return certificateInvalidError.Error()
+	case errors.As(err, &recordHeaderError), strings.Contains(err.Error(), "server gave HTTP response to HTTPS client"):
+		return "The server did not answer with a TLS handshake, it may only support HTTP"
+	}
+	return ""
+}
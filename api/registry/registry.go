@@ -0,0 +1,403 @@
+package registry
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/portainer/portainer"
+)
+
+const (
+	manifestV2MediaType     = "application/vnd.docker.distribution.manifest.v2+json"
+	manifestListMediaType   = "application/vnd.docker.distribution.manifest.list.v2+json"
+	ociManifestMediaType    = "application/vnd.oci.image.manifest.v1+json"
+	ociImageIndexMediaType  = "application/vnd.oci.image.index.v1+json"
+	contentDigestHeader     = "Docker-Content-Digest"
+	maxManifestSize         = 4 * 1024 * 1024
+	maxPaginatedRequests    = 100
+	paginatedRequestEntries = 1000
+)
+
+var (
+	// manifestMediaTypes are the manifest formats accepted when retrieving a manifest.
+	manifestMediaTypes = []string{manifestV2MediaType, manifestListMediaType, ociManifestMediaType, ociImageIndexMediaType}
+	challengeParameter = regexp.MustCompile(`(\w+)="([^"]*)"`)
+	nextLink           = regexp.MustCompile(`<([^>]+)>\s*;\s*rel="?next"?`)
+	digestReference    = regexp.MustCompile(`^[a-z0-9]+(?:[.+_-][a-z0-9]+)*:[a-zA-Z0-9=_-]+$`)
+)
+
+// Service represents a service used to browse registries using the Docker Registry HTTP API v2.
+// Registries requiring authentication are supported through the basic and the token authentication schemes.
+type Service struct {
+	client *http.Client
+}
+
+type tokenResponse struct {
+	Token       string `json:"token"`
+	AccessToken string `json:"access_token"`
+}
+
+type descriptor struct {
+	MediaType string `json:"mediaType"`
+	Digest    string `json:"digest"`
+	Size      int64  `json:"size"`
+	Platform  *struct {
+		Architecture string `json:"architecture"`
+		OS           string `json:"os"`
+		Variant      string `json:"variant"`
+	} `json:"platform"`
+}
+
+type manifestResponse struct {
+	SchemaVersion int          `json:"schemaVersion"`
+	MediaType     string       `json:"mediaType"`
+	Config        *descriptor  `json:"config"`
+	Layers        []descriptor `json:"layers"`
+	Manifests     []descriptor `json:"manifests"`
+}
+
+// NewService initializes a new service.
+func NewService() *Service {
+	return &Service{
+		client: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+	}
+}
+
+// Repositories returns the name of the repositories stored in a registry.
+func (service *Service) Repositories(registry *portainer.Registry) ([]string, error) {
+	repositories := make([]string, 0)
+	err := service.paginate(registry, "/v2/_catalog", "registry:catalog:*", func(body []byte) error {
+		var catalog struct {
+			Repositories []string `json:"repositories"`
+		}
+		err := json.Unmarshal(body, &catalog)
+		if err != nil {
+			return err
+		}
+		repositories = append(repositories, catalog.Repositories...)
+		return nil
+	})
+	return repositories, err
+}
+
+// Tags returns the tags of a repository.
+func (service *Service) Tags(registry *portainer.Registry, repository string) ([]string, error) {
+	tags := make([]string, 0)
+	err := service.paginate(registry, "/v2/"+repository+"/tags/list", repositoryScope(repository, "pull"), func(body []byte) error {
+		var tagList struct {
+			Tags []string `json:"tags"`
+		}
+		err := json.Unmarshal(body, &tagList)
+		if err != nil {
+			return err
+		}
+		tags = append(tags, tagList.Tags...)
+		return nil
+	})
+	return tags, err
+}
+
+// Manifest returns the manifest of a repository identified by a tag or a digest. The manifest is retrieved in
+// the Docker image manifest V2 schema 2 or OCI format, image indexes and manifest lists are also supported.
+func (service *Service) Manifest(registry *portainer.Registry, repository, reference string) (*portainer.RegistryManifest, error) {
+	resp, err := service.do(registry, http.MethodGet, "/v2/"+repository+"/manifests/"+reference, repositoryScope(repository, "pull"))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	err = checkResponseStatus(resp, http.StatusOK)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxManifestSize))
+	if err != nil {
+		return nil, err
+	}
+
+	var content manifestResponse
+	err = json.Unmarshal(body, &content)
+	if err != nil {
+		return nil, portainer.ErrRegistryUnexpectedResponse
+	}
+
+	manifest := &portainer.RegistryManifest{
+		Repository:    repository,
+		Reference:     reference,
+		Digest:        resp.Header.Get(contentDigestHeader),
+		MediaType:     content.MediaType,
+		SchemaVersion: content.SchemaVersion,
+		ManifestSize:  int64(len(body)),
+	}
+	if manifest.Digest == "" {
+		manifest.Digest = fmt.Sprintf("sha256:%x", sha256.Sum256(body))
+	}
+	if mediaType, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type")); err == nil && mediaType != "application/json" {
+		manifest.MediaType = mediaType
+	}
+
+	if content.Config != nil {
+		manifest.Config = convertDescriptor(content.Config)
+		manifest.Size += content.Config.Size
+	}
+	for idx := range content.Layers {
+		manifest.Layers = append(manifest.Layers, *convertDescriptor(&content.Layers[idx]))
+		manifest.Size += content.Layers[idx].Size
+	}
+	for idx := range content.Manifests {
+		manifest.Manifests = append(manifest.Manifests, *convertDescriptor(&content.Manifests[idx]))
+	}
+
+	return manifest, nil
+}
+
+// DeleteManifest deletes a manifest identified by a tag or a digest. The registry only allows the deletion
+// of a manifest by digest, a tag is resolved first. Every tag referencing the same manifest is removed.
+func (service *Service) DeleteManifest(registry *portainer.Registry, repository, reference string) error {
+	scope := repositoryScope(repository, "pull,delete")
+
+	digest := reference
+	if !digestReference.MatchString(reference) {
+		resp, err := service.do(registry, http.MethodHead, "/v2/"+repository+"/manifests/"+reference, scope)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+
+		err = checkResponseStatus(resp, http.StatusOK)
+		if err != nil {
+			return err
+		}
+
+		digest = resp.Header.Get(contentDigestHeader)
+		if digest == "" {
+			return portainer.ErrRegistryUnexpectedResponse
+		}
+	}
+
+	resp, err := service.do(registry, http.MethodDelete, "/v2/"+repository+"/manifests/"+digest, scope)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode == http.StatusMethodNotAllowed {
+		return portainer.ErrRegistryDeletionNotSupported
+	}
+	return checkResponseStatus(resp, http.StatusAccepted)
+}
+
+// paginate retrieves every page of a paginated list, the next page is identified by the Link header of the response.
+func (service *Service) paginate(registry *portainer.Registry, path, scope string, handlePage func(body []byte) error) error {
+	requestPath := fmt.Sprintf("%s?n=%d", path, paginatedRequestEntries)
+	for page := 0; page < maxPaginatedRequests && requestPath != ""; page++ {
+		resp, err := service.do(registry, http.MethodGet, requestPath, scope)
+		if err != nil {
+			return err
+		}
+
+		err = checkResponseStatus(resp, http.StatusOK)
+		if err != nil {
+			resp.Body.Close()
+			return err
+		}
+
+		body, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return err
+		}
+
+		err = handlePage(body)
+		if err != nil {
+			return portainer.ErrRegistryUnexpectedResponse
+		}
+
+		requestPath = ""
+		if match := nextLink.FindStringSubmatch(resp.Header.Get("Link")); match != nil {
+			requestPath = match[1]
+		}
+	}
+	return nil
+}
+
+// do executes a request against the registry API. When the registry answers with an authentication challenge,
+// the request is executed again with the credentials of the registry or with a token retrieved from the
+// authorization server specified in the challenge.
+func (service *Service) do(registry *portainer.Registry, method, path, scope string) (*http.Response, error) {
+	requestURL, err := registryURL(registry.URL, path)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := service.send(method, requestURL, "")
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		return resp, err
+	}
+	resp.Body.Close()
+
+	authorization, err := service.authorization(registry, resp.Header.Get("WWW-Authenticate"), scope)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err = service.send(method, requestURL, authorization)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusUnauthorized {
+		resp.Body.Close()
+		return nil, portainer.ErrRegistryAuthenticationFailed
+	}
+	return resp, nil
+}
+
+// authorization returns the value of the Authorization header answering an authentication challenge.
+func (service *Service) authorization(registry *portainer.Registry, challenge, scope string) (string, error) {
+	switch {
+	case strings.HasPrefix(strings.ToLower(challenge), "bearer"):
+		token, err := service.requestToken(registry, challenge, scope)
+		if err != nil {
+			return "", err
+		}
+		return "Bearer " + token, nil
+	case strings.HasPrefix(strings.ToLower(challenge), "basic") && registry.Authentication:
+		req := &http.Request{Header: make(http.Header)}
+		req.SetBasicAuth(registry.Username, registry.Password)
+		return req.Header.Get("Authorization"), nil
+	}
+	return "", portainer.ErrRegistryAuthenticationFailed
+}
+
+func (service *Service) send(method, requestURL, authorization string) (*http.Response, error) {
+	req, err := http.NewRequest(method, requestURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", strings.Join(manifestMediaTypes, ", "))
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+	return service.client.Do(req)
+}
+
+// requestToken retrieves a token from the authorization server specified in a bearer challenge.
+// The credentials of the registry are used when the registry requires authentication, an anonymous
+// token is requested otherwise. No scope is requested when the scope is empty.
+func (service *Service) requestToken(registry *portainer.Registry, challenge, scope string) (string, error) {
+	parameters := make(map[string]string)
+	for _, match := range challengeParameter.FindAllStringSubmatch(challenge, -1) {
+		parameters[strings.ToLower(match[1])] = match[2]
+	}
+
+	realm, err := url.Parse(parameters["realm"])
+	if err != nil || realm.Host == "" {
+		return "", portainer.ErrRegistryAuthenticationFailed
+	}
+
+	query := realm.Query()
+	if parameters["service"] != "" {
+		query.Set("service", parameters["service"])
+	}
+	if scope != "" {
+		query.Set("scope", scope)
+	}
+	realm.RawQuery = query.Encode()
+
+	req, err := http.NewRequest(http.MethodGet, realm.String(), nil)
+	if err != nil {
+		return "", err
+	}
+	if registry.Authentication {
+		req.SetBasicAuth(registry.Username, registry.Password)
+	}
+
+	resp, err := service.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", portainer.ErrRegistryAuthenticationFailed
+	}
+
+	var token tokenResponse
+	err = json.NewDecoder(resp.Body).Decode(&token)
+	if err != nil {
+		return "", portainer.ErrRegistryAuthenticationFailed
+	}
+
+	if token.Token != "" {
+		return token.Token, nil
+	}
+	if token.AccessToken != "" {
+		return token.AccessToken, nil
+	}
+	return "", portainer.ErrRegistryAuthenticationFailed
+}
+
+// registryURL builds the URL of a registry API path. The HTTPS scheme is used when the registry URL
+// does not specify a scheme.
+func registryURL(registryURL, path string) (string, error) {
+	if !strings.Contains(registryURL, "://") {
+		registryURL = "https://" + registryURL
+	}
+
+	base, err := url.Parse(strings.TrimRight(registryURL, "/"))
+	if err != nil || base.Host == "" {
+		return "", portainer.ErrRegistryInvalidURL
+	}
+
+	reference, err := url.Parse(path)
+	if err != nil {
+		return "", err
+	}
+
+	// Pagination links are relative to the registry host.
+	return base.Scheme + "://" + base.Host + reference.RequestURI(), nil
+}
+
+func repositoryScope(repository, actions string) string {
+	return "repository:" + repository + ":" + actions
+}
+
+func checkResponseStatus(resp *http.Response, expectedStatus int) error {
+	switch resp.StatusCode {
+	case expectedStatus:
+		return nil
+	case http.StatusNotFound:
+		return portainer.ErrRegistryResourceNotFound
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return portainer.ErrRegistryAuthenticationFailed
+	}
+	return portainer.ErrRegistryUnexpectedResponse
+}
+
+func convertDescriptor(content *descriptor) *portainer.RegistryDescriptor {
+	converted := &portainer.RegistryDescriptor{
+		MediaType: content.MediaType,
+		Digest:    content.Digest,
+		Size:      content.Size,
+	}
+	if content.Platform != nil {
+		converted.Platform = &portainer.RegistryPlatform{
+			Architecture: content.Platform.Architecture,
+			OS:           content.Platform.OS,
+			Variant:      content.Platform.Variant,
+		}
+	}
+	return converted
+}
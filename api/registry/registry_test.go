@@ -0,0 +1,165 @@
+package registry
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/portainer/portainer"
+)
+
+const (
+	stubUsername = "user"
+	stubPassword = "password"
+	stubToken    = "registry-token"
+	stubDigest   = "sha256:0123456789abcdef"
+	stubService  = "registry.example.com"
+)
+
+// stubRegistry is a registry requiring a token issued by its authorization server for every request.
+type stubRegistry struct {
+	*httptest.Server
+	scopes []string
+	tags   []string
+}
+
+func newStubRegistry() *stubRegistry {
+	registry := &stubRegistry{tags: []string{"latest", "1.0", "2.0"}}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/token", registry.handleToken)
+	mux.HandleFunc("/v2/", registry.handleAPI)
+	registry.Server = httptest.NewServer(mux)
+	return registry
+}
+
+func (registry *stubRegistry) handleToken(w http.ResponseWriter, r *http.Request) {
+	username, password, ok := r.BasicAuth()
+	if !ok || username != stubUsername || password != stubPassword || r.URL.Query().Get("service") != stubService {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	registry.scopes = append(registry.scopes, r.URL.Query().Get("scope"))
+	json.NewEncoder(w).Encode(&tokenResponse{Token: stubToken})
+}
+
+func (registry *stubRegistry) handleAPI(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Authorization") != "Bearer "+stubToken {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="`+registry.URL+`/token",service="`+stubService+`"`)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	switch {
+	case r.URL.Path == "/v2/_catalog" && r.URL.Query().Get("last") == "":
+		w.Header().Set("Link", `</v2/_catalog?last=library%2Falpine&n=1000>; rel="next"`)
+		json.NewEncoder(w).Encode(map[string][]string{"repositories": {"library/alpine"}})
+	case r.URL.Path == "/v2/_catalog":
+		json.NewEncoder(w).Encode(map[string][]string{"repositories": {"library/nginx"}})
+	case r.URL.Path == "/v2/library/alpine/tags/list":
+		json.NewEncoder(w).Encode(map[string][]string{"tags": registry.tags})
+	case r.URL.Path == "/v2/library/alpine/manifests/latest" && r.Method != http.MethodDelete:
+		w.Header().Set("Content-Type", manifestV2MediaType)
+		w.Header().Set(contentDigestHeader, stubDigest)
+		if r.Method == http.MethodGet {
+			w.Write([]byte(`{"schemaVersion":2,"mediaType":"` + manifestV2MediaType + `",` +
+				`"config":{"mediaType":"application/vnd.docker.container.image.v1+json","digest":"sha256:config","size":100},` +
+				`"layers":[{"mediaType":"application/vnd.docker.image.rootfs.diff.tar.gzip","digest":"sha256:layer","size":1000}]}`))
+		}
+	case r.URL.Path == "/v2/library/alpine/manifests/"+stubDigest && r.Method == http.MethodDelete:
+		registry.tags = []string{"1.0", "2.0"}
+		w.WriteHeader(http.StatusAccepted)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func (registry *stubRegistry) registry() *portainer.Registry {
+	return &portainer.Registry{
+		URL:            registry.URL,
+		Authentication: true,
+		Username:       stubUsername,
+		Password:       stubPassword,
+	}
+}
+
+func TestRepositoriesWithTokenChallenge(t *testing.T) {
+	registry := newStubRegistry()
+	defer registry.Close()
+
+	repositories, err := NewService().Repositories(registry.registry())
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if strings.Join(repositories, ",") != "library/alpine,library/nginx" {
+		t.Errorf("Unexpected repositories: %v", repositories)
+	}
+	if len(registry.scopes) != 2 || registry.scopes[0] != "registry:catalog:*" {
+		t.Errorf("Unexpected token scopes: %v", registry.scopes)
+	}
+}
+
+func TestTagsAndManifest(t *testing.T) {
+	registry := newStubRegistry()
+	defer registry.Close()
+	service := NewService()
+
+	tags, err := service.Tags(registry.registry(), "library/alpine")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if strings.Join(tags, ",") != "latest,1.0,2.0" {
+		t.Errorf("Unexpected tags: %v", tags)
+	}
+	if registry.scopes[0] != "repository:library/alpine:pull" {
+		t.Errorf("Unexpected token scope: %s", registry.scopes[0])
+	}
+
+	manifest, err := service.Manifest(registry.registry(), "library/alpine", "latest")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if manifest.Digest != stubDigest || manifest.MediaType != manifestV2MediaType || manifest.Size != 1100 || len(manifest.Layers) != 1 {
+		t.Errorf("Unexpected manifest: %+v", manifest)
+	}
+
+	_, err = service.Manifest(registry.registry(), "library/alpine", "unknown")
+	if err != portainer.ErrRegistryResourceNotFound {
+		t.Errorf("Unexpected error for an unknown manifest: %v", err)
+	}
+}
+
+func TestDeleteManifestResolvesTheTag(t *testing.T) {
+	registry := newStubRegistry()
+	defer registry.Close()
+
+	err := NewService().DeleteManifest(registry.registry(), "library/alpine", "latest")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if strings.Join(registry.tags, ",") != "1.0,2.0" {
+		t.Errorf("The manifest has not been deleted: %v", registry.tags)
+	}
+	if registry.scopes[0] != "repository:library/alpine:pull,delete" {
+		t.Errorf("Unexpected token scope: %s", registry.scopes[0])
+	}
+}
+
+func TestInvalidCredentials(t *testing.T) {
+	registry := newStubRegistry()
+	defer registry.Close()
+
+	credentials := registry.registry()
+	credentials.Password = "invalid"
+	_, err := NewService().Repositories(credentials)
+	if err != portainer.ErrRegistryAuthenticationFailed {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	credentials.Authentication = false
+	_, err = NewService().Repositories(credentials)
+	if err != portainer.ErrRegistryAuthenticationFailed {
+		t.Errorf("Unexpected error for an anonymous request: %v", err)
+	}
+}
@@ -0,0 +1,217 @@
+package ssh
+
+import (
+	"log"
+	"net"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/portainer/portainer"
+	"github.com/portainer/portainer/crypto"
+	cryptossh "golang.org/x/crypto/ssh"
+)
+
+const (
+	// ErrInvalidSSHEndpointURL defines an error raised when the URL of an SSH endpoint does not specify a user and a host
+	ErrInvalidSSHEndpointURL = portainer.Error("Invalid SSH endpoint URL: the URL must be ssh://user@host[:port][/socket/path]")
+)
+
+const (
+	defaultSSHPort          = "22"
+	defaultDockerSocketPath = "/var/run/docker.sock"
+	// keepAliveInterval is the interval between two keep-alive requests sent on an SSH connection.
+	// The connection is closed when a keep-alive request fails.
+	keepAliveInterval = 30 * time.Second
+)
+
+// Service implements the SSHService interface. A single SSH connection is opened per endpoint and reused
+// by all the requests: each connection to the Docker API is a channel forwarded to the Docker unix socket
+// of the remote host.
+type Service struct {
+	encryptionService portainer.EncryptionService
+	logger            *log.Logger
+	mu                sync.Mutex
+	connections       map[portainer.EndpointID]*connection
+}
+
+// connection is the SSH connection of an endpoint. The client is established by the first dial and
+// reset when the connection is lost.
+type connection struct {
+	mu     sync.Mutex
+	url    string
+	client *cryptossh.Client
+}
+
+// NewService initializes a new SSH service. The encryption service is used to decrypt the private keys.
+func NewService(encryptionService portainer.EncryptionService) *Service {
+	return &Service{
+		encryptionService: encryptionService,
+		logger:            log.New(os.Stderr, "", log.LstdFlags),
+		connections:       make(map[portainer.EndpointID]*connection),
+	}
+}
+
+// ParseEndpointURL returns the user, the address and the Docker socket path of an SSH endpoint URL.
+func ParseEndpointURL(endpointURL string) (user, address, socketPath string, err error) {
+	u, err := url.Parse(endpointURL)
+	if err != nil || u.Scheme != "ssh" || u.User == nil || u.User.Username() == "" || u.Hostname() == "" {
+		return "", "", "", ErrInvalidSSHEndpointURL
+	}
+
+	port := u.Port()
+	if port == "" {
+		port = defaultSSHPort
+	}
+
+	socketPath = u.Path
+	if socketPath == "" || socketPath == "/" {
+		socketPath = defaultDockerSocketPath
+	}
+	return u.User.Username(), net.JoinHostPort(u.Hostname(), port), socketPath, nil
+}
+
+// Dial opens a connection to the Docker API of an endpoint. The SSH connection of the endpoint is
+// established when needed, it is established again once when a dial fails on a broken connection.
+func (service *Service) Dial(endpoint *portainer.Endpoint) (net.Conn, error) {
+	_, _, socketPath, err := ParseEndpointURL(endpoint.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := service.client(endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := client.Dial("unix", socketPath)
+	if err == nil {
+		return conn, nil
+	}
+
+	// The connection may have been lost without being detected yet, the error returned when the
+	// remote host rejects the channel means that the connection itself is healthy.
+	if _, ok := err.(*cryptossh.OpenChannelError); ok {
+		return nil, err
+	}
+
+	service.resetClient(endpoint.ID, client)
+	client, err = service.client(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	return client.Dial("unix", socketPath)
+}
+
+// CloseConnection closes the SSH connection of an endpoint, if any.
+func (service *Service) CloseConnection(endpointID portainer.EndpointID) {
+	service.mu.Lock()
+	conn := service.connections[endpointID]
+	delete(service.connections, endpointID)
+	service.mu.Unlock()
+
+	if conn != nil {
+		conn.mu.Lock()
+		if conn.client != nil {
+			conn.client.Close()
+			conn.client = nil
+		}
+		conn.mu.Unlock()
+	}
+}
+
+// client returns the SSH client of an endpoint, the connection is established when it does not exist
+// or when the URL of the endpoint changed. Connections to different endpoints are established concurrently.
+func (service *Service) client(endpoint *portainer.Endpoint) (*cryptossh.Client, error) {
+	service.mu.Lock()
+	conn := service.connections[endpoint.ID]
+	if conn == nil {
+		conn = &connection{}
+		service.connections[endpoint.ID] = conn
+	}
+	service.mu.Unlock()
+
+	conn.mu.Lock()
+	defer conn.mu.Unlock()
+
+	if conn.client != nil && conn.url == endpoint.URL {
+		return conn.client, nil
+	}
+
+	if conn.client != nil {
+		conn.client.Close()
+		conn.client = nil
+	}
+
+	client, err := service.connect(endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	conn.client = client
+	conn.url = endpoint.URL
+	go service.keepAlive(endpoint.ID, client)
+	return client, nil
+}
+
+// connect opens an SSH connection to the host of an endpoint.
+func (service *Service) connect(endpoint *portainer.Endpoint) (*cryptossh.Client, error) {
+	user, address, _, err := ParseEndpointURL(endpoint.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	config, err := crypto.CreateSSHClientConfiguration(user, endpoint.SSHKeyPath, endpoint.SSHKnownHostsPath, service.encryptionService)
+	if err != nil {
+		return nil, err
+	}
+
+	return cryptossh.Dial("tcp", address, config)
+}
+
+// keepAlive sends keep-alive requests on the SSH connection of an endpoint until it is closed, so that
+// idle connections are not dropped by the network and lost connections are detected.
+func (service *Service) keepAlive(endpointID portainer.EndpointID, client *cryptossh.Client) {
+	closed := make(chan struct{})
+	go func() {
+		client.Wait()
+		close(closed)
+	}()
+
+	ticker := time.NewTicker(keepAliveInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-closed:
+			service.resetClient(endpointID, client)
+			return
+		case <-ticker.C:
+			_, _, err := client.SendRequest("keepalive@openssh.com", true, nil)
+			if err != nil {
+				service.logger.Printf("SSH connection of endpoint %d lost: %s", endpointID, err)
+				client.Close()
+			}
+		}
+	}
+}
+
+// resetClient closes the SSH client of an endpoint and removes it if it is still the current client.
+func (service *Service) resetClient(endpointID portainer.EndpointID, client *cryptossh.Client) {
+	client.Close()
+
+	service.mu.Lock()
+	conn := service.connections[endpointID]
+	service.mu.Unlock()
+
+	if conn == nil {
+		return
+	}
+
+	conn.mu.Lock()
+	if conn.client == client {
+		conn.client = nil
+	}
+	conn.mu.Unlock()
+}
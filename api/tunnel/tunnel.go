@@ -0,0 +1,291 @@
+package tunnel
+
+import (
+	"context"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/portainer/portainer"
+	"golang.org/x/net/http2"
+)
+
+const (
+	// tunnelPingInterval is the duration without any frame received from an agent after which the tunnel is checked.
+	tunnelPingInterval = 30 * time.Second
+	// tunnelPingTimeout is the duration after which a tunnel is closed when an agent does not answer a ping.
+	tunnelPingTimeout = 15 * time.Second
+	// streamOpenTimeout is the maximum duration allowed to an agent to open a connection to the Docker API.
+	streamOpenTimeout = 10 * time.Second
+	// streamHost is the host used in the requests opening a stream, agents always connect to their local Docker API.
+	streamHost = "docker"
+)
+
+// Service implements the TunnelService interface. Agents open a connection to Portainer which is then used
+// as an HTTP/2 connection where Portainer is the client: each connection to the Docker API of an endpoint
+// is a CONNECT stream that the agent forwards to its local Docker API.
+type Service struct {
+	endpointService portainer.EndpointService
+	transport       *http2.Transport
+	logger          *log.Logger
+	mu              sync.Mutex
+	tunnels         map[portainer.EndpointID]*tunnel
+}
+
+type (
+	tunnel struct {
+		clientConn *http2.ClientConn
+		closed     chan struct{}
+	}
+
+	// notifyConn is a connection signaling when it is closed.
+	notifyConn struct {
+		net.Conn
+		once   sync.Once
+		closed chan struct{}
+	}
+
+	// streamConn is a connection to the Docker API of an endpoint established through a tunnel stream.
+	streamConn struct {
+		writer *io.PipeWriter
+		body   io.ReadCloser
+		cancel context.CancelFunc
+	}
+)
+
+// NewService initializes a new tunnel service. No tunnel is opened when the service is created,
+// the tunnels recorded as connected by a previous run are marked as disconnected.
+func NewService(endpointService portainer.EndpointService) (*Service, error) {
+	service := &Service{
+		endpointService: endpointService,
+		transport: &http2.Transport{
+			ReadIdleTimeout: tunnelPingInterval,
+			PingTimeout:     tunnelPingTimeout,
+		},
+		logger:  log.New(os.Stderr, "", log.LstdFlags),
+		tunnels: make(map[portainer.EndpointID]*tunnel),
+	}
+
+	err := service.resetTunnelStatuses()
+	if err != nil {
+		return nil, err
+	}
+	return service, nil
+}
+
+// Serve registers the tunnel opened by the agent of an endpoint on a connection and blocks until
+// the tunnel is closed. A tunnel previously opened for the same endpoint is closed, which lets an
+// agent reconnect before the loss of its previous connection is detected.
+func (service *Service) Serve(endpoint *portainer.Endpoint, conn net.Conn, remoteAddr, agentVersion string) error {
+	nc := &notifyConn{Conn: conn, closed: make(chan struct{})}
+	clientConn, err := service.transport.NewClientConn(nc)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+
+	t := &tunnel{clientConn: clientConn, closed: nc.closed}
+
+	service.mu.Lock()
+	previous := service.tunnels[endpoint.ID]
+	service.tunnels[endpoint.ID] = t
+	service.mu.Unlock()
+
+	if previous != nil {
+		previous.clientConn.Close()
+	}
+
+	service.logger.Printf("Agent tunnel connected. [endpoint: %s] [remote: %s] [version: %s]", endpoint.Name, remoteAddr, agentVersion)
+	service.updateTunnelStatus(endpoint.ID, func(endpointTunnel *portainer.EndpointTunnel) {
+		endpointTunnel.Status = portainer.TunnelStatusConnected
+		endpointTunnel.RemoteAddr = remoteAddr
+		endpointTunnel.AgentVersion = agentVersion
+		endpointTunnel.ConnectedAt = time.Now().Unix()
+	})
+
+	<-t.closed
+
+	service.mu.Lock()
+	current := service.tunnels[endpoint.ID] == t
+	if current {
+		delete(service.tunnels, endpoint.ID)
+	}
+	service.mu.Unlock()
+
+	service.logger.Printf("Agent tunnel disconnected. [endpoint: %s] [remote: %s]", endpoint.Name, remoteAddr)
+	if current {
+		service.updateTunnelStatus(endpoint.ID, func(endpointTunnel *portainer.EndpointTunnel) {
+			endpointTunnel.Status = portainer.TunnelStatusDisconnected
+			endpointTunnel.DisconnectedAt = time.Now().Unix()
+		})
+	}
+	return nil
+}
+
+// Dial opens a connection to the Docker API of an endpoint through its tunnel.
+func (service *Service) Dial(endpointID portainer.EndpointID) (net.Conn, error) {
+	service.mu.Lock()
+	t := service.tunnels[endpointID]
+	service.mu.Unlock()
+
+	if t == nil {
+		return nil, portainer.ErrAgentTunnelNotConnected
+	}
+
+	reader, writer := io.Pipe()
+	ctx, cancel := context.WithCancel(context.Background())
+	req := &http.Request{
+		Method:        http.MethodConnect,
+		URL:           &url.URL{Host: streamHost},
+		Host:          streamHost,
+		Header:        make(http.Header),
+		Body:          reader,
+		ContentLength: -1,
+	}
+
+	// The context of the request is bound to the whole stream, it is only canceled
+	// by the timeout until the agent answers.
+	timer := time.AfterFunc(streamOpenTimeout, cancel)
+	resp, err := t.clientConn.RoundTrip(req.WithContext(ctx))
+	timer.Stop()
+	if err != nil {
+		cancel()
+		writer.Close()
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		cancel()
+		writer.Close()
+		return nil, portainer.ErrAgentTunnelNotConnected
+	}
+
+	return &streamConn{
+		writer: writer,
+		body:   resp.Body,
+		cancel: cancel,
+	}, nil
+}
+
+// CloseTunnel closes the tunnel of an endpoint, if any. The agent is expected to reconnect.
+func (service *Service) CloseTunnel(endpointID portainer.EndpointID) {
+	service.mu.Lock()
+	t := service.tunnels[endpointID]
+	service.mu.Unlock()
+
+	if t != nil {
+		t.clientConn.Close()
+	}
+}
+
+// resetTunnelStatuses marks the tunnels recorded as connected as disconnected.
+func (service *Service) resetTunnelStatuses() error {
+	endpoints, err := service.endpointService.Endpoints()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().Unix()
+	for _, endpoint := range endpoints {
+		if endpoint.Tunnel == nil || endpoint.Tunnel.Status != portainer.TunnelStatusConnected {
+			continue
+		}
+
+		endpoint.Tunnel.Status = portainer.TunnelStatusDisconnected
+		endpoint.Tunnel.DisconnectedAt = now
+		err = service.endpointService.UpdateEndpoint(endpoint.ID, &endpoint)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// updateTunnelStatus updates the tunnel status recorded on an endpoint. The endpoint is retrieved
+// again so that the changes made while the tunnel was opened are preserved.
+func (service *Service) updateTunnelStatus(endpointID portainer.EndpointID, update func(*portainer.EndpointTunnel)) {
+	endpoint, err := service.endpointService.Endpoint(endpointID)
+	if err == portainer.ErrEndpointNotFound {
+		return
+	} else if err != nil {
+		service.logger.Printf("Unable to retrieve endpoint %d: %s", endpointID, err)
+		return
+	}
+
+	if endpoint.Tunnel == nil {
+		endpoint.Tunnel = &portainer.EndpointTunnel{}
+	}
+	update(endpoint.Tunnel)
+
+	err = service.endpointService.UpdateEndpoint(endpoint.ID, endpoint)
+	if err != nil {
+		service.logger.Printf("Unable to update the tunnel status of endpoint %d: %s", endpointID, err)
+	}
+}
+
+func (conn *notifyConn) Close() error {
+	err := conn.Conn.Close()
+	conn.once.Do(func() {
+		close(conn.closed)
+	})
+	return err
+}
+
+func (conn *streamConn) Read(p []byte) (int, error) {
+	return conn.body.Read(p)
+}
+
+func (conn *streamConn) Write(p []byte) (int, error) {
+	return conn.writer.Write(p)
+}
+
+// CloseWrite ends the request stream so that the agent closes the write side of its Docker API connection.
+func (conn *streamConn) CloseWrite() error {
+	return conn.writer.Close()
+}
+
+func (conn *streamConn) Close() error {
+	conn.writer.Close()
+	err := conn.body.Close()
+	conn.cancel()
+	return err
+}
+
+func (conn *streamConn) LocalAddr() net.Addr {
+	return streamAddr{}
+}
+
+func (conn *streamConn) RemoteAddr() net.Addr {
+	return streamAddr{}
+}
+
+// Deadlines are not supported on tunnel streams, the Docker API clients rely on timeouts defined
+// on their requests instead.
+func (conn *streamConn) SetDeadline(t time.Time) error {
+	return nil
+}
+
+func (conn *streamConn) SetReadDeadline(t time.Time) error {
+	return nil
+}
+
+func (conn *streamConn) SetWriteDeadline(t time.Time) error {
+	return nil
+}
+
+// streamAddr is the address of both ends of a tunnel stream.
+type streamAddr struct{}
+
+func (streamAddr) Network() string {
+	return "tunnel"
+}
+
+func (streamAddr) String() string {
+	return streamHost
+}
@@ -125,6 +125,19 @@ angular.module('portainer', [
         requiresLogin: false
       }
     })
+    .state('oauth', {
+      parent: 'root',
+      url: '/auth/oauth?token&error&twoFactorToken&enrollmentRequired',
+      views: {
+        'content@': {
+          templateUrl: 'app/components/auth/oauth.html',
+          controller: 'OAuthController'
+        }
+      },
+      data: {
+        requiresLogin: false
+      }
+    })
     .state('containers', {
       parent: 'root',
       url: '/containers/',
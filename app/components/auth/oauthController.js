@@ -0,0 +1,51 @@
+angular.module('auth')
+.controller('OAuthController', ['$scope', '$state', '$stateParams', 'Authentication', 'EndpointService', 'StateManager', 'EndpointProvider', 'Notifications',
+function ($scope, $state, $stateParams, Authentication, EndpointService, StateManager, EndpointProvider, Notifications) {
+
+  $scope.logo = StateManager.getState().application.logo;
+
+  function redirectToLogin(error) {
+    $state.go('auth', {error: error});
+  }
+
+  function initialize() {
+    if ($stateParams.twoFactorToken) {
+      redirectToLogin('Two-factor authentication is required for this account, use the API to complete the authentication.');
+      return;
+    }
+
+    if ($stateParams.error || !$stateParams.token) {
+      redirectToLogin($stateParams.error || 'Authentication error');
+      return;
+    }
+
+    Authentication.loginWithToken($stateParams.token);
+    EndpointService.endpoints()
+    .then(function success(data) {
+      var userDetails = Authentication.getUserDetails();
+      if (data.length > 0)  {
+        endpointID = EndpointProvider.endpointID();
+        if (!endpointID) {
+          endpointID = data[0].Id;
+          EndpointProvider.setEndpointID(endpointID);
+        }
+        StateManager.updateEndpointState(true)
+        .then(function success() {
+          $state.go('dashboard');
+        }, function error(err) {
+          Notifications.error('Failure', err, 'Unable to connect to the Docker endpoint');
+        });
+      }
+      else if (userDetails.role === 1) {
+        $state.go('endpointInit');
+      } else {
+        redirectToLogin('User not allowed. Please contact your administrator.');
+      }
+    })
+    .catch(function error(err) {
+      redirectToLogin('Authentication error');
+    });
+  }
+
+  initialize();
+}]);
@@ -1,6 +1,6 @@
 angular.module('containerConsole', [])
-.controller('ContainerConsoleController', ['$scope', '$stateParams', 'Container', 'Image', 'EndpointProvider', 'Notifications', 'ContainerHelper', 'ContainerService', 'ExecService',
-function ($scope, $stateParams, Container, Image, EndpointProvider, Notifications, ContainerHelper, ContainerService, ExecService) {
+.controller('ContainerConsoleController', ['$scope', '$stateParams', 'Container', 'Image', 'EndpointProvider', 'Notifications', 'ContainerHelper', 'ContainerService', 'ExecService', 'LocalStorage',
+function ($scope, $stateParams, Container, Image, EndpointProvider, Notifications, ContainerHelper, ContainerService, ExecService, LocalStorage) {
   $scope.state = {};
   $scope.state.loaded = false;
   $scope.state.connected = false;
@@ -83,8 +83,20 @@ function ($scope, $stateParams, Container, Image, EndpointProvider, Notification
     }
   };
 
+  // Control messages are sent in binary frames, text frames carry the terminal input.
+  function sendControlMessage(message) {
+    var data = JSON.stringify(message);
+    var buffer = new Uint8Array(data.length);
+    for (var i = 0; i < data.length; i++) {
+      buffer[i] = data.charCodeAt(i);
+    }
+    socket.send(buffer.buffer);
+  }
+
   function initTerm(url, height, width) {
-    socket = new WebSocket(url);
+    var jwt = LocalStorage.getJWT();
+    socket = jwt ? new WebSocket(url, ['bearer.' + jwt]) : new WebSocket(url);
+    socket.binaryType = 'arraybuffer';
 
     $scope.state.connected = true;
     socket.onopen = function(evt) {
@@ -94,12 +106,23 @@ function ($scope, $stateParams, Container, Image, EndpointProvider, Notification
       term.on('data', function (data) {
         socket.send(data);
       });
+      term.on('resize', function (size) {
+        sendControlMessage({ Type: 'resize', Height: size.rows, Width: size.cols });
+      });
       term.open(document.getElementById('terminal-container'), true);
       term.resize(width, height);
       term.setOption('cursorBlink', true);
 
       socket.onmessage = function (e) {
-        term.write(e.data);
+        if (typeof e.data === 'string') {
+          term.write(e.data);
+          return;
+        }
+        var message = JSON.parse(String.fromCharCode.apply(null, new Uint8Array(e.data)));
+        if (message.Type === 'exit') {
+          term.writeln('');
+          term.writeln('Process exited with code ' + message.ExitCode);
+        }
       };
       socket.onerror = function (error) {
         $scope.state.connected = false;
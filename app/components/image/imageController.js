@@ -40,11 +40,7 @@ function ($scope, $stateParams, $state, $timeout, ImageService, RegistryService,
 
 	$scope.pushTag = function(repository) {
 		$('#loadingViewSpinner').show();
-		RegistryService.retrieveRegistryFromRepository(repository)
-		.then(function success(data) {
-			var registry = data;
-			return ImageService.pushImage(repository, registry);
-		})
+		ImageService.pushImage(repository)
 		.then(function success(data) {
 			Notifications.success('Image successfully pushed', repository);
 		})
@@ -4,3 +4,10 @@ function SettingsViewModel(data) {
   this.BlackListedLabels = data.BlackListedLabels;
   this.DisplayExternalContributors = data.DisplayExternalContributors;
 }
+
+function PublicSettingsViewModel(data) {
+  this.LogoURL = data.LogoURL;
+  this.DisplayExternalContributors = data.DisplayExternalContributors;
+  this.AuthenticationMethod = data.AuthenticationMethod;
+  this.OAuthLoginURI = data.OAuthLoginURI;
+}
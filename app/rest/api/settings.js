@@ -1,8 +1,9 @@
 angular.module('portainer.rest')
 .factory('Settings', ['$resource', 'API_ENDPOINT_SETTINGS', function SettingsFactory($resource, API_ENDPOINT_SETTINGS) {
   'use strict';
-  return $resource(API_ENDPOINT_SETTINGS, {}, {
+  return $resource(API_ENDPOINT_SETTINGS + '/:subResource', {}, {
     get: { method: 'GET' },
-    update: { method: 'PUT' }
+    update: { method: 'PUT' },
+    publicSettings: { method: 'GET', params: { subResource: 'public' } }
   });
 }]);
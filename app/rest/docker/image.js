@@ -1,5 +1,5 @@
 angular.module('portainer.rest')
-.factory('Image', ['$resource', 'API_ENDPOINT_ENDPOINTS', 'EndpointProvider', 'HttpRequestHelper', function ImageFactory($resource, API_ENDPOINT_ENDPOINTS, EndpointProvider, HttpRequestHelper) {
+.factory('Image', ['$resource', 'API_ENDPOINT_ENDPOINTS', 'EndpointProvider', function ImageFactory($resource, API_ENDPOINT_ENDPOINTS, EndpointProvider) {
   'use strict';
 
   return $resource(API_ENDPOINT_ENDPOINTS + '/:endpointId/docker/images/:id/:action', {
@@ -15,13 +15,11 @@ angular.module('portainer.rest')
     inspect: {method: 'GET', params: {id: '@id', action: 'json'}},
     push: {
       method: 'POST', params: {action: 'push', id: '@tag'},
-      isArray: true, transformResponse: jsonObjectsToArrayHandler,
-      headers: { 'X-Registry-Auth': HttpRequestHelper.registryAuthenticationHeader }
+      isArray: true, transformResponse: jsonObjectsToArrayHandler
     },
     create: {
       method: 'POST', params: {action: 'create', fromImage: '@fromImage', tag: '@tag'},
-      isArray: true, transformResponse: jsonObjectsToArrayHandler,
-      headers: { 'X-Registry-Auth': HttpRequestHelper.registryAuthenticationHeader }
+      isArray: true, transformResponse: jsonObjectsToArrayHandler
     },
     remove: {
       method: 'DELETE', params: {id: '@id', force: '@force'},
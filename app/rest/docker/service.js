@@ -1,5 +1,5 @@
 angular.module('portainer.rest')
-.factory('Service', ['$resource', 'API_ENDPOINT_ENDPOINTS', 'EndpointProvider', 'HttpRequestHelper' ,function ServiceFactory($resource, API_ENDPOINT_ENDPOINTS, EndpointProvider, HttpRequestHelper) {
+.factory('Service', ['$resource', 'API_ENDPOINT_ENDPOINTS', 'EndpointProvider', function ServiceFactory($resource, API_ENDPOINT_ENDPOINTS, EndpointProvider) {
   'use strict';
   return $resource(API_ENDPOINT_ENDPOINTS + '/:endpointId/docker/services/:id/:action', {
     endpointId: EndpointProvider.endpointID
@@ -7,10 +7,7 @@ angular.module('portainer.rest')
   {
     get: { method: 'GET', params: {id: '@id'} },
     query: { method: 'GET', isArray: true },
-    create: {
-      method: 'POST', params: {action: 'create'},
-      headers: { 'X-Registry-Auth': HttpRequestHelper.registryAuthenticationHeader }
-    },
+    create: { method: 'POST', params: {action: 'create'} },
     update: { method: 'POST', params: {id: '@id', action: 'update', version: '@version'} },
     remove: { method: 'DELETE', params: {id: '@id'} }
   });
@@ -35,15 +35,6 @@ angular.module('portainer.services')
     return deferred.promise;
   };
 
-  service.encodedCredentials = function(registry) {
-    var credentials = {
-      username: registry.Username,
-      password: registry.Password,
-      serveraddress: registry.URL
-    };
-    return btoa(JSON.stringify(credentials));
-  };
-
   service.updateAccess = function(id, authorizedUserIDs, authorizedTeamIDs) {
     return Registries.updateAccess({id: id}, {authorizedUsers: authorizedUserIDs, authorizedTeams: authorizedTeamIDs}).$promise;
   };
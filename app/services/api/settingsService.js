@@ -18,6 +18,21 @@ angular.module('portainer.services')
     return deferred.promise;
   };
 
+  service.publicSettings = function() {
+    var deferred = $q.defer();
+
+    Settings.publicSettings().$promise
+    .then(function success(data) {
+      var settings = new PublicSettingsViewModel(data);
+      deferred.resolve(settings);
+    })
+    .catch(function error(err) {
+      deferred.reject({ msg: 'Unable to retrieve application settings', err: err });
+    });
+
+    return deferred.promise;
+  };
+
   service.update = function(settings) {
     return Settings.update({}, settings).$promise;
   };
@@ -28,6 +28,13 @@ angular.module('portainer.services')
         });
       });
     },
+    loginWithToken: function(jwt) {
+      LocalStorage.storeJWT(jwt);
+      var tokenPayload = jwtHelper.decodeToken(jwt);
+      user.username = tokenPayload.username;
+      user.ID = tokenPayload.id;
+      user.role = tokenPayload.role;
+    },
     logout: function() {
       StateManager.clean();
       EndpointProvider.clean();
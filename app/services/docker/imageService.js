@@ -1,5 +1,5 @@
 angular.module('portainer.services')
-.factory('ImageService', ['$q', 'Image', 'ImageHelper', 'RegistryService', 'HttpRequestHelper', 'SystemService', function ImageServiceFactory($q, Image, ImageHelper, RegistryService, HttpRequestHelper, SystemService) {
+.factory('ImageService', ['$q', 'Image', 'ImageHelper', 'SystemService', function ImageServiceFactory($q, Image, ImageHelper, SystemService) {
   'use strict';
   var service = {};
 
@@ -63,11 +63,9 @@ angular.module('portainer.services')
     return deferred.promise;
   };
 
-  service.pushImage = function(tag, registry) {
+  service.pushImage = function(tag) {
     var deferred = $q.defer();
 
-    var authenticationDetails = registry.Authentication ? RegistryService.encodedCredentials(registry) : '';
-    HttpRequestHelper.setRegistryAuthenticationHeader(authenticationDetails);
     Image.push({tag: tag}).$promise
     .then(function success(data) {
       if (data[data.length - 1].error) {
@@ -116,9 +114,6 @@ angular.module('portainer.services')
   service.pullImage = function(image, registry, ignoreErrors) {
     var imageDetails = ImageHelper.extractImageAndRegistryFromRepository(image);
     var imageConfiguration = ImageHelper.createImageConfigForContainer(imageDetails.image, registry.URL);
-    var authenticationDetails = registry.Authentication ? RegistryService.encodedCredentials(registry) : '';
-    HttpRequestHelper.setRegistryAuthenticationHeader(authenticationDetails);
-
     if (ignoreErrors) {
       return pullImageAndIgnoreErrors(imageConfiguration);
     }
@@ -44,7 +44,7 @@ angular.module('portainer.services')
       deferred.resolve(state);
     } else {
       $q.all({
-        settings: SettingsService.settings(),
+        settings: SettingsService.publicSettings(),
         status: StatusService.status()
       })
       .then(function success(data) {
@@ -56,6 +56,8 @@ angular.module('portainer.services')
         state.application.version = status.Version;
         state.application.logo = settings.LogoURL;
         state.application.displayExternalContributors = settings.DisplayExternalContributors;
+        state.application.authenticationMethod = settings.AuthenticationMethod;
+        state.application.oauthLoginURI = settings.OAuthLoginURI;
         LocalStorage.storeApplicationState(state.application);
         deferred.resolve(state);
       })